@@ -1,18 +1,28 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/fatih/color"
 	"github.com/lance0/hookshot/internal/client"
 	"github.com/lance0/hookshot/internal/config"
+	"github.com/lance0/hookshot/internal/protocol"
 	"github.com/lance0/hookshot/internal/server"
 	"github.com/lance0/hookshot/internal/tui"
 	"github.com/spf13/cobra"
@@ -34,6 +44,10 @@ var rootCmd = &cobra.Command{
 Run 'hookshot server' on your VPS, then 'hookshot client' locally
 to receive webhooks at localhost.`,
 	Version: version,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		noColor, _ := cmd.Flags().GetBool("no-color")
+		client.SetColor(noColor)
+	},
 }
 
 // Server command
@@ -43,6 +57,7 @@ var serverCmd = &cobra.Command{
 	Long:  `Run the hookshot relay server that receives webhooks and forwards them to connected clients.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configFile, _ := cmd.Flags().GetString("config")
+		profile := configProfile(cmd)
 
 		// Load config file if specified or found
 		var fileCfg *config.Config
@@ -51,10 +66,12 @@ var serverCmd = &cobra.Command{
 		}
 		if configFile != "" {
 			var err error
-			fileCfg, err = config.Load(configFile)
+			fileCfg, err = config.Load(configFile, profile)
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
+		} else if profile != "" {
+			return fmt.Errorf("profile %q selected but no config file found", profile)
 		}
 
 		// Validate config file if loaded
@@ -69,9 +86,59 @@ var serverCmd = &cobra.Command{
 		host, _ := cmd.Flags().GetString("host")
 		publicURL, _ := cmd.Flags().GetString("public-url")
 		maxRequests, _ := cmd.Flags().GetInt("max-requests")
+		storePath, _ := cmd.Flags().GetString("store-path")
 		token, _ := cmd.Flags().GetString("token")
 		tlsCert, _ := cmd.Flags().GetString("tls-cert")
 		tlsKey, _ := cmd.Flags().GetString("tls-key")
+		acmeDomains, _ := cmd.Flags().GetStringSlice("acme-domain")
+		acmeEmail, _ := cmd.Flags().GetString("acme-email")
+		acmeCacheDir, _ := cmd.Flags().GetString("acme-cache-dir")
+		registerRateLimit, _ := cmd.Flags().GetInt("register-rate-limit")
+		maxTunnels, _ := cmd.Flags().GetInt("max-tunnels")
+		rateLimit, _ := cmd.Flags().GetFloat64("rate-limit")
+		rateLimitBurst, _ := cmd.Flags().GetInt("rate-limit-burst")
+		noTunnelStatus, _ := cmd.Flags().GetInt("no-tunnel-status")
+		noTunnelRetryAfter, _ := cmd.Flags().GetInt("no-tunnel-retry-after")
+		noTunnelBody, _ := cmd.Flags().GetString("no-tunnel-body")
+		noTunnelBodyFile, _ := cmd.Flags().GetString("no-tunnel-body-file")
+		bufferOffline, _ := cmd.Flags().GetBool("buffer-offline")
+		offlineBufferSize, _ := cmd.Flags().GetInt("offline-buffer-size")
+		offlineBufferAge, _ := cmd.Flags().GetDuration("offline-buffer-age")
+		lockTunnels, _ := cmd.Flags().GetBool("lock-tunnels")
+		tunnelsFile, _ := cmd.Flags().GetString("tunnels-file")
+		allowMultiClient, _ := cmd.Flags().GetBool("allow-multi-client")
+		balance, _ := cmd.Flags().GetString("balance")
+		debug, _ := cmd.Flags().GetBool("debug")
+		dashboard, _ := cmd.Flags().GetBool("dashboard")
+		transformScript, _ := cmd.Flags().GetString("transform-script")
+		transformTimeout, _ := cmd.Flags().GetDuration("transform-timeout")
+		sink, _ := cmd.Flags().GetString("sink")
+		sinkPayload, _ := cmd.Flags().GetString("sink-payload")
+		sinkQueueSize, _ := cmd.Flags().GetInt("sink-queue-size")
+		maxDecompressed, _ := cmd.Flags().GetInt64("max-decompressed-bytes")
+		maxBodySize, _ := cmd.Flags().GetInt64("max-body-size")
+		chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+		sendBuffer, _ := cmd.Flags().GetInt("send-buffer")
+		noWSCompression, _ := cmd.Flags().GetBool("no-ws-compression")
+		requireUpgradeAuth, _ := cmd.Flags().GetBool("require-upgrade-auth")
+		drainTimeout, _ := cmd.Flags().GetDuration("drain-timeout")
+		responseWait, _ := cmd.Flags().GetDuration("response-wait")
+		pingInterval, _ := cmd.Flags().GetDuration("ping-interval")
+		pongTimeout, _ := cmd.Flags().GetDuration("pong-timeout")
+		tunnelIdleTimeout, _ := cmd.Flags().GetDuration("tunnel-idle-timeout")
+		urlTTL, _ := cmd.Flags().GetDuration("url-ttl")
+		dedupWindow, _ := cmd.Flags().GetDuration("dedup-window")
+		dedupHeader, _ := cmd.Flags().GetString("dedup-header")
+		aliases, _ := cmd.Flags().GetStringToString("alias")
+		aliasPrefix, _ := cmd.Flags().GetString("alias-prefix")
+		allowIPs, _ := cmd.Flags().GetStringSlice("allow-ips")
+		denyIPs, _ := cmd.Flags().GetStringSlice("deny-ips")
+		trustedProxies, _ := cmd.Flags().GetStringSlice("trusted-proxies")
+		corsOrigins, _ := cmd.Flags().GetStringSlice("cors-origins")
+		corsMethods, _ := cmd.Flags().GetStringSlice("cors-methods")
+		corsHeaders, _ := cmd.Flags().GetStringSlice("cors-headers")
+
+		var tokens []server.AuthToken
 
 		// Apply config file values if flags weren't set
 		if fileCfg != nil {
@@ -87,25 +154,252 @@ var serverCmd = &cobra.Command{
 			if !cmd.Flags().Changed("max-requests") && fileCfg.Server.MaxRequests != 0 {
 				maxRequests = fileCfg.Server.MaxRequests
 			}
+			if !cmd.Flags().Changed("store-path") && fileCfg.Server.StorePath != "" {
+				storePath = fileCfg.Server.StorePath
+			}
 			if !cmd.Flags().Changed("token") && fileCfg.Server.Token != "" {
 				token = fileCfg.Server.Token
 			}
+			for _, t := range fileCfg.Server.Tokens {
+				tokens = append(tokens, server.AuthToken{
+					Name:         t.Name,
+					Token:        t.Token,
+					TunnelPrefix: t.TunnelPrefix,
+					Label:        t.Label,
+				})
+			}
 			if !cmd.Flags().Changed("tls-cert") && fileCfg.Server.TLSCert != "" {
 				tlsCert = fileCfg.Server.TLSCert
 			}
 			if !cmd.Flags().Changed("tls-key") && fileCfg.Server.TLSKey != "" {
 				tlsKey = fileCfg.Server.TLSKey
 			}
+			if !cmd.Flags().Changed("acme-domain") && len(fileCfg.Server.ACMEDomains) > 0 {
+				acmeDomains = fileCfg.Server.ACMEDomains
+			}
+			if !cmd.Flags().Changed("acme-email") && fileCfg.Server.ACMEEmail != "" {
+				acmeEmail = fileCfg.Server.ACMEEmail
+			}
+			if !cmd.Flags().Changed("acme-cache-dir") && fileCfg.Server.ACMECacheDir != "" {
+				acmeCacheDir = fileCfg.Server.ACMECacheDir
+			}
+			if !cmd.Flags().Changed("register-rate-limit") && fileCfg.Server.RegisterRateLimit != 0 {
+				registerRateLimit = fileCfg.Server.RegisterRateLimit
+			}
+			if !cmd.Flags().Changed("max-tunnels") && fileCfg.Server.MaxTunnels != 0 {
+				maxTunnels = fileCfg.Server.MaxTunnels
+			}
+			if !cmd.Flags().Changed("rate-limit") && fileCfg.Server.RateLimit != 0 {
+				rateLimit = fileCfg.Server.RateLimit
+			}
+			if !cmd.Flags().Changed("rate-limit-burst") && fileCfg.Server.RateLimitBurst != 0 {
+				rateLimitBurst = fileCfg.Server.RateLimitBurst
+			}
+			if !cmd.Flags().Changed("no-tunnel-status") && fileCfg.Server.NoTunnelStatus != 0 {
+				noTunnelStatus = fileCfg.Server.NoTunnelStatus
+			}
+			if !cmd.Flags().Changed("no-tunnel-retry-after") && fileCfg.Server.NoTunnelRetryAfter != 0 {
+				noTunnelRetryAfter = fileCfg.Server.NoTunnelRetryAfter
+			}
+			// Either flag overrides both config keys
+			if !cmd.Flags().Changed("no-tunnel-body") && !cmd.Flags().Changed("no-tunnel-body-file") {
+				if fileCfg.Server.NoTunnelBody != "" {
+					noTunnelBody = fileCfg.Server.NoTunnelBody
+				}
+				if fileCfg.Server.NoTunnelBodyFile != "" {
+					noTunnelBodyFile = fileCfg.Server.NoTunnelBodyFile
+				}
+			}
+			if !cmd.Flags().Changed("buffer-offline") && fileCfg.Server.BufferOffline {
+				bufferOffline = fileCfg.Server.BufferOffline
+			}
+			if !cmd.Flags().Changed("offline-buffer-size") && fileCfg.Server.OfflineBufferSize != 0 {
+				offlineBufferSize = fileCfg.Server.OfflineBufferSize
+			}
+			if !cmd.Flags().Changed("offline-buffer-age") && fileCfg.Server.OfflineBufferAge != 0 {
+				offlineBufferAge = fileCfg.Server.OfflineBufferAge
+			}
+			if !cmd.Flags().Changed("lock-tunnels") && fileCfg.Server.LockTunnels {
+				lockTunnels = fileCfg.Server.LockTunnels
+			}
+			if !cmd.Flags().Changed("tunnels-file") && fileCfg.Server.TunnelsFile != "" {
+				tunnelsFile = fileCfg.Server.TunnelsFile
+			}
+			if !cmd.Flags().Changed("allow-multi-client") && fileCfg.Server.AllowMultiClient {
+				allowMultiClient = fileCfg.Server.AllowMultiClient
+			}
+			if !cmd.Flags().Changed("balance") && fileCfg.Server.Balance != "" {
+				balance = fileCfg.Server.Balance
+			}
+			if !cmd.Flags().Changed("debug") && fileCfg.Server.Debug {
+				debug = fileCfg.Server.Debug
+			}
+			if !cmd.Flags().Changed("dashboard") && fileCfg.Server.Dashboard {
+				dashboard = fileCfg.Server.Dashboard
+			}
+			if !cmd.Flags().Changed("transform-script") && fileCfg.Server.TransformScript != "" {
+				transformScript = fileCfg.Server.TransformScript
+			}
+			if !cmd.Flags().Changed("transform-timeout") && fileCfg.Server.TransformTimeout != 0 {
+				transformTimeout = fileCfg.Server.TransformTimeout
+			}
+			if !cmd.Flags().Changed("sink") && fileCfg.Server.Sink != "" {
+				sink = fileCfg.Server.Sink
+			}
+			if !cmd.Flags().Changed("sink-payload") && fileCfg.Server.SinkPayload != "" {
+				sinkPayload = fileCfg.Server.SinkPayload
+			}
+			if !cmd.Flags().Changed("sink-queue-size") && fileCfg.Server.SinkQueueSize != 0 {
+				sinkQueueSize = fileCfg.Server.SinkQueueSize
+			}
+			if !cmd.Flags().Changed("max-decompressed-bytes") && fileCfg.Server.MaxDecompressedBytes != 0 {
+				maxDecompressed = fileCfg.Server.MaxDecompressedBytes
+			}
+			if !cmd.Flags().Changed("max-body-size") && fileCfg.Server.MaxBodySize != 0 {
+				maxBodySize = fileCfg.Server.MaxBodySize
+			}
+			if !cmd.Flags().Changed("chunk-size") && fileCfg.Server.ChunkSize != 0 {
+				chunkSize = fileCfg.Server.ChunkSize
+			}
+			if !cmd.Flags().Changed("send-buffer") && fileCfg.Server.SendBuffer != 0 {
+				sendBuffer = fileCfg.Server.SendBuffer
+			}
+			if !cmd.Flags().Changed("no-ws-compression") && fileCfg.Server.NoWSCompression {
+				noWSCompression = true
+			}
+			if !cmd.Flags().Changed("require-upgrade-auth") && fileCfg.Server.RequireUpgradeAuth {
+				requireUpgradeAuth = true
+			}
+			if !cmd.Flags().Changed("drain-timeout") && fileCfg.Server.DrainTimeout != 0 {
+				drainTimeout = fileCfg.Server.DrainTimeout
+			}
+			if !cmd.Flags().Changed("response-wait") && fileCfg.Server.ResponseWait != 0 {
+				responseWait = fileCfg.Server.ResponseWait
+			}
+			if !cmd.Flags().Changed("ping-interval") && fileCfg.Server.PingInterval != 0 {
+				pingInterval = fileCfg.Server.PingInterval
+			}
+			if !cmd.Flags().Changed("pong-timeout") && fileCfg.Server.PongTimeout != 0 {
+				pongTimeout = fileCfg.Server.PongTimeout
+			}
+			if !cmd.Flags().Changed("tunnel-idle-timeout") && fileCfg.Server.TunnelIdleTimeout != 0 {
+				tunnelIdleTimeout = fileCfg.Server.TunnelIdleTimeout
+			}
+			if !cmd.Flags().Changed("url-ttl") && fileCfg.Server.URLTTL != 0 {
+				urlTTL = fileCfg.Server.URLTTL
+			}
+			if !cmd.Flags().Changed("dedup-window") && fileCfg.Server.DedupWindow != 0 {
+				dedupWindow = fileCfg.Server.DedupWindow
+			}
+			if !cmd.Flags().Changed("dedup-header") && fileCfg.Server.DedupHeader != "" {
+				dedupHeader = fileCfg.Server.DedupHeader
+			}
+			if !cmd.Flags().Changed("alias") && len(fileCfg.Server.Aliases) > 0 {
+				aliases = fileCfg.Server.Aliases
+			}
+			if !cmd.Flags().Changed("alias-prefix") && fileCfg.Server.AliasPrefix != "" {
+				aliasPrefix = fileCfg.Server.AliasPrefix
+			}
+			if !cmd.Flags().Changed("allow-ips") && len(fileCfg.Server.IPFilter.Allow) > 0 {
+				allowIPs = fileCfg.Server.IPFilter.Allow
+			}
+			if !cmd.Flags().Changed("deny-ips") && len(fileCfg.Server.IPFilter.Deny) > 0 {
+				denyIPs = fileCfg.Server.IPFilter.Deny
+			}
+			if !cmd.Flags().Changed("trusted-proxies") && len(fileCfg.Server.TrustedProxies) > 0 {
+				trustedProxies = fileCfg.Server.TrustedProxies
+			}
+			if !cmd.Flags().Changed("cors-origins") && len(fileCfg.Server.CORS.Origins) > 0 {
+				corsOrigins = fileCfg.Server.CORS.Origins
+			}
+			if !cmd.Flags().Changed("cors-methods") && len(fileCfg.Server.CORS.Methods) > 0 {
+				corsMethods = fileCfg.Server.CORS.Methods
+			}
+			if !cmd.Flags().Changed("cors-headers") && len(fileCfg.Server.CORS.Headers) > 0 {
+				corsHeaders = fileCfg.Server.CORS.Headers
+			}
+		}
+
+		if noTunnelBodyFile != "" {
+			if noTunnelBody != "" {
+				return fmt.Errorf("--no-tunnel-body and --no-tunnel-body-file are mutually exclusive")
+			}
+			data, err := os.ReadFile(noTunnelBodyFile)
+			if err != nil {
+				return fmt.Errorf("failed to read no-tunnel body: %w", err)
+			}
+			noTunnelBody = string(data)
+		}
+
+		// ACME serves HTTPS on the standard port unless one was chosen
+		if len(acmeDomains) > 0 && !cmd.Flags().Changed("port") && (fileCfg == nil || fileCfg.Server.Port == 0) {
+			port = 443
+		}
+
+		if err := validKeepalive(pingInterval, pongTimeout); err != nil {
+			return err
+		}
+		if balance != server.BalanceRoundRobin && balance != server.BalanceLeastInFlight && balance != server.BalanceFailover {
+			return fmt.Errorf("invalid --balance: %s (must be round-robin, least-in-flight or failover)", balance)
+		}
+		if sinkPayload != server.SinkPayloadSummary && sinkPayload != server.SinkPayloadFull {
+			return fmt.Errorf("invalid --sink-payload: %s (must be summary or full)", sinkPayload)
 		}
 
 		cfg := server.Config{
-			Port:        port,
-			Host:        host,
-			PublicURL:   publicURL,
-			MaxRequests: maxRequests,
-			Token:       token,
-			TLSCert:     tlsCert,
-			TLSKey:      tlsKey,
+			Port:                 port,
+			Host:                 host,
+			PublicURL:            publicURL,
+			MaxRequests:          maxRequests,
+			StorePath:            storePath,
+			Token:                token,
+			Tokens:               tokens,
+			TLSCert:              tlsCert,
+			TLSKey:               tlsKey,
+			ACMEDomains:          acmeDomains,
+			ACMEEmail:            acmeEmail,
+			ACMECacheDir:         acmeCacheDir,
+			WebhookIPs:           server.IPFilter{Allow: allowIPs, Deny: denyIPs},
+			CORS:                 server.CORS{Origins: corsOrigins, Methods: corsMethods, Headers: corsHeaders},
+			TrustedProxies:       trustedProxies,
+			RegisterRateLimit:    registerRateLimit,
+			MaxTunnels:           maxTunnels,
+			RateLimit:            rateLimit,
+			RateLimitBurst:       rateLimitBurst,
+			NoTunnelStatus:       noTunnelStatus,
+			NoTunnelRetryAfter:   noTunnelRetryAfter,
+			NoTunnelBody:         noTunnelBody,
+			BufferOffline:        bufferOffline,
+			OfflineBufferSize:    offlineBufferSize,
+			OfflineBufferAge:     offlineBufferAge,
+			LockTunnels:          lockTunnels,
+			TunnelsFile:          tunnelsFile,
+			MaxDecompressedBytes: maxDecompressed,
+			MaxBodySize:          maxBodySize,
+			ChunkSize:            chunkSize,
+			SendBuffer:           sendBuffer,
+			DisableWSCompression: noWSCompression,
+			RequireUpgradeAuth:   requireUpgradeAuth,
+			DrainTimeout:         drainTimeout,
+			ResponseWait:         responseWait,
+			PingInterval:         pingInterval,
+			PongTimeout:          pongTimeout,
+			TunnelIdleTimeout:    tunnelIdleTimeout,
+			URLTTL:               urlTTL,
+			DedupWindow:          dedupWindow,
+			DedupHeader:          dedupHeader,
+			Aliases:              aliases,
+			AliasPrefix:          aliasPrefix,
+			AllowMultiClient:     allowMultiClient,
+			Balance:              balance,
+			Debug:                debug,
+			Dashboard:            dashboard,
+			TransformScript:      transformScript,
+			TransformTimeout:     transformTimeout,
+			SinkURL:              sink,
+			SinkPayload:          sinkPayload,
+			SinkQueueSize:        sinkQueueSize,
+			Version:              version,
 		}
 
 		srv := server.New(cfg)
@@ -122,6 +416,17 @@ var serverCmd = &cobra.Command{
 			cancel()
 		}()
 
+		// Reload the tunnels file on SIGHUP
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
+		go func() {
+			for range hupCh {
+				if err := srv.ReloadTunnels(); err != nil {
+					log.Printf("failed to reload tunnels file: %v", err)
+				}
+			}
+		}()
+
 		return srv.Run(ctx)
 	},
 }
@@ -133,6 +438,7 @@ var clientCmd = &cobra.Command{
 	Long:  `Connect to a hookshot relay server and forward webhooks to a local target.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configFile, _ := cmd.Flags().GetString("config")
+		profile := configProfile(cmd)
 
 		// Load config file if specified or found
 		var fileCfg *config.Config
@@ -141,10 +447,12 @@ var clientCmd = &cobra.Command{
 		}
 		if configFile != "" {
 			var err error
-			fileCfg, err = config.Load(configFile)
+			fileCfg, err = config.Load(configFile, profile)
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
+		} else if profile != "" {
+			return fmt.Errorf("profile %q selected but no config file found", profile)
 		}
 
 		// Validate config file if loaded
@@ -159,9 +467,64 @@ var clientCmd = &cobra.Command{
 		tunnelID, _ := cmd.Flags().GetString("id")
 		token, _ := cmd.Flags().GetString("token")
 		verbose, _ := cmd.Flags().GetBool("verbose")
+		echo, _ := cmd.Flags().GetBool("echo")
 		tuiMode, _ := cmd.Flags().GetBool("tui")
+		heartbeatInterval, _ := cmd.Flags().GetDuration("heartbeat-interval")
+		summaryInterval, _ := cmd.Flags().GetDuration("summary-interval")
+		heartbeatTimeout, _ := cmd.Flags().GetDuration("heartbeat-timeout")
+		pingInterval, _ := cmd.Flags().GetDuration("ping-interval")
+		pongTimeout, _ := cmd.Flags().GetDuration("pong-timeout")
+		maxReconnects, _ := cmd.Flags().GetInt("max-reconnects")
+		clientIPHeader, _ := cmd.Flags().GetString("client-ip-header")
+		transformExpr, _ := cmd.Flags().GetString("transform")
+		hostHeader, _ := cmd.Flags().GetString("host-header")
+		bodyDisplayLimit, _ := cmd.Flags().GetInt("body-display-limit")
+		expectStatus, _ := cmd.Flags().GetStringSlice("expect-status")
+		asyncAck, _ := cmd.Flags().GetInt("async-ack")
+		tunnelFlags, _ := cmd.Flags().GetStringArray("tunnel")
+		maxDecompressed, _ := cmd.Flags().GetInt64("max-decompressed-bytes")
+		maxResponseSize, _ := cmd.Flags().GetInt64("max-response-size")
+		noWSCompression, _ := cmd.Flags().GetBool("no-ws-compression")
+		retryAttempts, _ := cmd.Flags().GetInt("retry-attempts")
+		retryDelay, _ := cmd.Flags().GetDuration("retry-delay")
+		targetTimeout, _ := cmd.Flags().GetDuration("target-timeout")
+		insecureSkipVerify, _ := cmd.Flags().GetBool("insecure-skip-verify")
+		injectHeaderFlags, _ := cmd.Flags().GetStringArray("inject-header")
+		removeHeaders, _ := cmd.Flags().GetStringSlice("remove-header")
+		headerMap, _ := cmd.Flags().GetStringToString("target-header-map")
+		targetCA, _ := cmd.Flags().GetString("target-ca")
+		insecure, _ := cmd.Flags().GetBool("insecure")
+		relayCA, _ := cmd.Flags().GetString("relay-ca")
+		proxyFlag, _ := cmd.Flags().GetString("proxy")
+		retryStatus, _ := cmd.Flags().GetStringSlice("retry-status")
+		acceptPaths, _ := cmd.Flags().GetStringSlice("accept-path")
+		ignorePaths, _ := cmd.Flags().GetStringSlice("ignore-path")
+		ignoreStatus, _ := cmd.Flags().GetInt("ignore-status")
+		injectDelay, _ := cmd.Flags().GetString("inject-delay")
+		dropRate, _ := cmd.Flags().GetFloat64("drop-rate")
+		ordered, _ := cmd.Flags().GetBool("ordered")
+		maxConcurrency, _ := cmd.Flags().GetInt("max-concurrency")
+		logFile, _ := cmd.Flags().GetString("log-file")
+		historyFile, _ := cmd.Flags().GetString("history-file")
+		logBodies, _ := cmd.Flags().GetBool("log-bodies")
 
 		var routes []client.Route
+		var tunnels []client.TunnelConfig
+		var mocks []client.Mock
+
+		injectHeaders := make(map[string]string, len(injectHeaderFlags))
+		for _, h := range injectHeaderFlags {
+			name, value, err := client.ParseHeader(h)
+			if err != nil {
+				return fmt.Errorf("invalid --inject-header: %w", err)
+			}
+			injectHeaders[name] = value
+		}
+		for from, to := range headerMap {
+			if from == "" || to == "" || strings.ContainsAny(from+to, " \t:") {
+				return fmt.Errorf("invalid --target-header-map entry %q", from+"="+to)
+			}
+		}
 
 		// Apply config file values if flags weren't set
 		if fileCfg != nil {
@@ -180,13 +543,161 @@ var clientCmd = &cobra.Command{
 			if !cmd.Flags().Changed("verbose") && fileCfg.Client.Verbose {
 				verbose = fileCfg.Client.Verbose
 			}
+			if !cmd.Flags().Changed("echo") && fileCfg.Client.Echo {
+				echo = true
+			}
+			if !cmd.Flags().Changed("heartbeat-interval") && fileCfg.Client.HeartbeatInterval != 0 {
+				heartbeatInterval = fileCfg.Client.HeartbeatInterval
+			}
+			if !cmd.Flags().Changed("summary-interval") && fileCfg.Client.SummaryInterval != 0 {
+				summaryInterval = fileCfg.Client.SummaryInterval
+			}
+			if !cmd.Flags().Changed("ping-interval") && fileCfg.Client.PingInterval != 0 {
+				pingInterval = fileCfg.Client.PingInterval
+			}
+			if !cmd.Flags().Changed("pong-timeout") && fileCfg.Client.PongTimeout != 0 {
+				pongTimeout = fileCfg.Client.PongTimeout
+			}
+			if !cmd.Flags().Changed("max-reconnects") && fileCfg.Client.MaxReconnects != 0 {
+				maxReconnects = fileCfg.Client.MaxReconnects
+			}
+			if !cmd.Flags().Changed("heartbeat-timeout") && fileCfg.Client.HeartbeatTimeout != 0 {
+				heartbeatTimeout = fileCfg.Client.HeartbeatTimeout
+			}
+			if !cmd.Flags().Changed("client-ip-header") && fileCfg.Client.ClientIPHeader != "" {
+				clientIPHeader = fileCfg.Client.ClientIPHeader
+			}
+			if !cmd.Flags().Changed("transform") && fileCfg.Client.Transform != "" {
+				transformExpr = fileCfg.Client.Transform
+			}
+			if !cmd.Flags().Changed("host-header") && fileCfg.Client.HostHeader != "" {
+				hostHeader = fileCfg.Client.HostHeader
+			}
+			if !cmd.Flags().Changed("body-display-limit") && fileCfg.Client.BodyDisplayLimit != 0 {
+				bodyDisplayLimit = fileCfg.Client.BodyDisplayLimit
+			}
+			if !cmd.Flags().Changed("expect-status") && len(fileCfg.Client.ExpectStatus) > 0 {
+				expectStatus = fileCfg.Client.ExpectStatus
+			}
+			if !cmd.Flags().Changed("async-ack") && fileCfg.Client.AsyncAck != 0 {
+				asyncAck = fileCfg.Client.AsyncAck
+			}
+			if !cmd.Flags().Changed("max-decompressed-bytes") && fileCfg.Client.MaxDecompressedBytes != 0 {
+				maxDecompressed = fileCfg.Client.MaxDecompressedBytes
+			}
+			if !cmd.Flags().Changed("max-response-size") && fileCfg.Client.MaxResponseSize != 0 {
+				maxResponseSize = fileCfg.Client.MaxResponseSize
+			}
+			if !cmd.Flags().Changed("no-ws-compression") && fileCfg.Client.NoWSCompression {
+				noWSCompression = true
+			}
+			if !cmd.Flags().Changed("retry-attempts") && fileCfg.Client.Retry.MaxAttempts != 0 {
+				retryAttempts = fileCfg.Client.Retry.MaxAttempts
+			}
+			if !cmd.Flags().Changed("retry-delay") && fileCfg.Client.Retry.BaseDelay != 0 {
+				retryDelay = fileCfg.Client.Retry.BaseDelay
+			}
+			if !cmd.Flags().Changed("target-timeout") && fileCfg.Client.TargetTimeout != 0 {
+				targetTimeout = fileCfg.Client.TargetTimeout
+			}
+			if !cmd.Flags().Changed("insecure-skip-verify") && fileCfg.Client.InsecureSkipVerify {
+				insecureSkipVerify = true
+			}
+			if !cmd.Flags().Changed("target-ca") && fileCfg.Client.TargetCA != "" {
+				targetCA = fileCfg.Client.TargetCA
+			}
+			if !cmd.Flags().Changed("insecure") && fileCfg.Client.Insecure {
+				insecure = true
+			}
+			if !cmd.Flags().Changed("relay-ca") && fileCfg.Client.RelayCA != "" {
+				relayCA = fileCfg.Client.RelayCA
+			}
+			if !cmd.Flags().Changed("proxy") && fileCfg.Client.Proxy != "" {
+				proxyFlag = fileCfg.Client.Proxy
+			}
+			if !cmd.Flags().Changed("inject-header") && len(fileCfg.Client.InjectHeaders) > 0 {
+				injectHeaders = fileCfg.Client.InjectHeaders
+			}
+			if !cmd.Flags().Changed("remove-header") && len(fileCfg.Client.RemoveHeaders) > 0 {
+				removeHeaders = fileCfg.Client.RemoveHeaders
+			}
+			if !cmd.Flags().Changed("target-header-map") && len(fileCfg.Client.TargetHeaderMap) > 0 {
+				headerMap = fileCfg.Client.TargetHeaderMap
+			}
+			if !cmd.Flags().Changed("retry-status") && len(fileCfg.Client.Retry.Statuses) > 0 {
+				retryStatus = fileCfg.Client.Retry.Statuses
+			}
+			if !cmd.Flags().Changed("ordered") && fileCfg.Client.Ordered {
+				ordered = true
+			}
+			if !cmd.Flags().Changed("max-concurrency") && fileCfg.Client.MaxConcurrency != 0 {
+				maxConcurrency = fileCfg.Client.MaxConcurrency
+			}
+			if !cmd.Flags().Changed("log-file") && fileCfg.Client.LogFile != "" {
+				logFile = fileCfg.Client.LogFile
+			}
+			if !cmd.Flags().Changed("history-file") && fileCfg.Client.HistoryFile != "" {
+				historyFile = fileCfg.Client.HistoryFile
+			}
+			if !cmd.Flags().Changed("log-bodies") && fileCfg.Client.LogBodies {
+				logBodies = true
+			}
+			if !cmd.Flags().Changed("accept-path") && len(fileCfg.Client.AcceptPaths) > 0 {
+				acceptPaths = fileCfg.Client.AcceptPaths
+			}
+			if !cmd.Flags().Changed("ignore-path") && len(fileCfg.Client.IgnorePaths) > 0 {
+				ignorePaths = fileCfg.Client.IgnorePaths
+			}
+			if !cmd.Flags().Changed("ignore-status") && fileCfg.Client.IgnoreStatus != 0 {
+				ignoreStatus = fileCfg.Client.IgnoreStatus
+			}
+			if !cmd.Flags().Changed("inject-delay") && fileCfg.Client.InjectDelay != "" {
+				injectDelay = fileCfg.Client.InjectDelay
+			}
+			if !cmd.Flags().Changed("drop-rate") && fileCfg.Client.DropRate != 0 {
+				dropRate = fileCfg.Client.DropRate
+			}
 			// Load routes from config
 			for _, r := range fileCfg.Client.Routes {
-				routes = append(routes, client.Route{
-					Path:   r.Path,
-					Target: r.Target,
+				route := client.Route{
+					Path:        r.Path,
+					Methods:     r.Methods,
+					Target:      r.PrimaryTarget(),
+					StripPrefix: r.StripPrefix,
+					Rewrite:     r.Rewrite,
+					Headers:     client.HeaderRules{Set: r.InjectHeaders, Remove: r.RemoveHeaders},
+				}
+				if len(r.Targets) > 1 {
+					route.Mirrors = r.Targets[1:]
+				}
+				routes = append(routes, route)
+			}
+			for _, m := range fileCfg.Client.Mocks {
+				mocks = append(mocks, client.Mock{
+					Method:  m.Method,
+					Path:    m.Path,
+					Status:  m.Status,
+					Headers: m.Headers,
+					Body:    m.Body,
+					Delay:   m.Delay,
 				})
 			}
+			if !cmd.Flags().Changed("tunnel") {
+				for _, t := range fileCfg.Client.Tunnels {
+					tunnels = append(tunnels, client.TunnelConfig{
+						Name:     t.Name,
+						Target:   t.Target,
+						TunnelID: t.ID,
+					})
+				}
+			}
+		}
+
+		if len(tunnelFlags) > 0 {
+			var err error
+			if tunnels, err = parseTunnelFlags(tunnelFlags); err != nil {
+				return err
+			}
 		}
 
 		if serverURL == "" {
@@ -195,19 +706,140 @@ var clientCmd = &cobra.Command{
 		if target == "" && len(routes) == 0 {
 			target = "http://localhost:3000"
 		}
+		if hostHeader != "" {
+			if err := config.ValidateHostname(hostHeader); err != nil {
+				return fmt.Errorf("invalid --host-header: %w", err)
+			}
+		}
+		if strings.ContainsAny(clientIPHeader, " \t:") {
+			return fmt.Errorf("invalid --client-ip-header: %q", clientIPHeader)
+		}
+		retryRanges, err := client.ParseStatusRanges(retryStatus)
+		if err != nil {
+			return fmt.Errorf("invalid --retry-status: %w", err)
+		}
+		expectRanges, err := client.ParseStatusRanges(expectStatus)
+		if err != nil {
+			return fmt.Errorf("invalid expect-status: %w", err)
+		}
+		if asyncAck != 0 && (asyncAck < 200 || asyncAck > 299) {
+			return fmt.Errorf("invalid --async-ack: %d (must be a 2xx status)", asyncAck)
+		}
+		targetTLS, err := client.TargetTLSConfig(targetCA, insecureSkipVerify)
+		if err != nil {
+			return err
+		}
+		relayTLS, err := client.RelayTLSConfig(relayCA, insecure)
+		if err != nil {
+			return err
+		}
+		var proxyURL *url.URL
+		if proxyFlag != "" {
+			if proxyURL, err = client.ParseProxy(proxyFlag); err != nil {
+				return err
+			}
+		}
+		if err := validKeepalive(pingInterval, pongTimeout); err != nil {
+			return err
+		}
+		if maxReconnects < 0 {
+			return fmt.Errorf("invalid --max-reconnects: %d (must be >= 0)", maxReconnects)
+		}
+		if maxResponseSize < 0 {
+			return fmt.Errorf("invalid --max-response-size: %d (must be >= 0)", maxResponseSize)
+		}
+		if maxConcurrency < 0 {
+			return fmt.Errorf("invalid --max-concurrency: %d (must be >= 0)", maxConcurrency)
+		}
+		if summaryInterval != 0 && summaryInterval < time.Second {
+			return fmt.Errorf("invalid --summary-interval: %s (must be at least 1s)", summaryInterval)
+		}
+		var bodyTransform *client.BodyTransform
+		if transformExpr != "" {
+			t, err := client.ParseBodyTransform(transformExpr)
+			if err != nil {
+				return err
+			}
+			bodyTransform = t
+		}
+		paths := client.PathFilter{Accept: acceptPaths, Ignore: ignorePaths, Status: ignoreStatus}
+		if err := paths.Validate(); err != nil {
+			return err
+		}
+		chaos := client.Chaos{DropRate: dropRate}
+		if injectDelay != "" {
+			if chaos.DelayMin, chaos.DelayMax, err = client.ParseDelay(injectDelay); err != nil {
+				return fmt.Errorf("invalid --inject-delay: %w", err)
+			}
+		}
+		if dropRate < 0 || dropRate > 1 {
+			return fmt.Errorf("invalid --drop-rate: %g (must be 0-1)", dropRate)
+		}
+
+		var requestLog *client.RequestLog
+		if logFile != "" {
+			if requestLog, err = client.OpenRequestLog(logFile, logBodies); err != nil {
+				return err
+			}
+			defer requestLog.Close()
+		}
+		if historyFile != "" && !tuiMode {
+			return fmt.Errorf("--history-file requires --tui")
+		}
 
 		cfg := client.Config{
 			ServerURL: serverURL,
 			Target:    target,
 			Routes:    routes,
-			TunnelID:  tunnelID,
-			Token:     token,
-			Verbose:   verbose,
-			TUIMode:   tuiMode,
+			Mocks:     mocks,
+			Echo:      echo,
+			Paths:     paths,
+			Chaos:     chaos,
+			Ordered:   ordered,
+
+			MaxConcurrency: maxConcurrency,
+			TunnelID:       tunnelID,
+			Token:          token,
+			Verbose:        verbose,
+			TUIMode:        tuiMode,
+
+			HeartbeatInterval: heartbeatInterval,
+			SummaryInterval:   summaryInterval,
+			HeartbeatTimeout:  heartbeatTimeout,
+			PingInterval:      pingInterval,
+			PongTimeout:       pongTimeout,
+			MaxReconnects:     maxReconnects,
+
+			HostHeader:       hostHeader,
+			ClientIPHeader:   clientIPHeader,
+			BodyTransform:    bodyTransform,
+			BodyDisplayLimit: bodyDisplayLimit,
+			ExpectStatus:     expectRanges,
+			AsyncAck:         asyncAck,
+			Tunnels:          tunnels,
+
+			MaxDecompressedBytes: maxDecompressed,
+			MaxResponseSize:      maxResponseSize,
+			DisableWSCompression: noWSCompression,
+			TargetTimeout:        targetTimeout,
+			TargetTLS:            targetTLS,
+			RelayTLS:             relayTLS,
+			Proxy:                proxyURL,
+			Headers:              client.HeaderRules{Rename: headerMap, Set: injectHeaders, Remove: removeHeaders},
+			RequestLog:           requestLog,
+
+			Retry: client.RetryPolicy{
+				MaxAttempts: retryAttempts,
+				BaseDelay:   retryDelay,
+				Statuses:    retryRanges,
+			},
 		}
 
 		c := client.New(cfg)
 
+		// Flags are valid; errors from here on aren't usage errors
+		cmd.SilenceUsage = true
+
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -224,37 +856,57 @@ var clientCmd = &cobra.Command{
 
 		if tuiMode {
 			// Run with TUI
-			return runWithTUI(ctx, c, cancel)
+			return runWithTUI(ctx, c, cancel, historyFile)
 		}
 
 		return c.Run(ctx)
 	},
 }
 
-// runWithTUI runs the client with the TUI
-func runWithTUI(ctx context.Context, c *client.Client, cancel context.CancelFunc) error {
+// runWithTUI runs the client with the TUI. With a history file, the list
+// starts with its newest requests and new ones are appended to it.
+func runWithTUI(ctx context.Context, c *client.Client, cancel context.CancelFunc, historyFile string) error {
 	// Create TUI model
 	m := tui.NewModel()
+	if historyFile != "" {
+		history, err := tui.OpenHistory(historyFile)
+		if err != nil {
+			return err
+		}
+		defer history.Close()
+		if err := m.UseHistory(history); err != nil {
+			return err
+		}
+	}
 
 	// Set up TUI channels
 	c.SetTUIChannels(m.RequestChannel(), m.ConnectionChannel())
 
-	// Run client in background
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	// Run client in background. It only stops on its own when the server
+	// refuses it for good (e.g., a revoked token); the TUI then exits too.
+	clientErr := make(chan error, 1)
 	go func() {
 		if err := c.Run(ctx); err != nil && ctx.Err() == nil {
-			// Client error - could send to TUI
+			clientErr <- err
+			p.Quit()
 		}
 	}()
 
 	// Run TUI
-	p := tea.NewProgram(m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		return fmt.Errorf("TUI error: %w", err)
 	}
 
 	// TUI exited, cancel context
 	cancel()
-	return nil
+	select {
+	case err := <-clientErr:
+		return err
+	default:
+		return nil
+	}
 }
 
 // Requests command
@@ -265,6 +917,15 @@ var requestsCmd = &cobra.Command{
 		serverURL, _ := cmd.Flags().GetString("server")
 		tunnelID, _ := cmd.Flags().GetString("tunnel")
 		token, _ := cmd.Flags().GetString("token")
+		search, _ := cmd.Flags().GetString("search")
+		method, _ := cmd.Flags().GetString("method")
+		limit, _ := cmd.Flags().GetInt("limit")
+		before, _ := cmd.Flags().GetString("before")
+		since, _ := cmd.Flags().GetString("since")
+		output, _ := cmd.Flags().GetString("output")
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			output = "json"
+		}
 
 		if serverURL == "" {
 			return fmt.Errorf("--server is required")
@@ -272,9 +933,31 @@ var requestsCmd = &cobra.Command{
 		if tunnelID == "" {
 			return fmt.Errorf("--tunnel is required")
 		}
+		if output != "table" && output != "json" && output != "csv" {
+			return fmt.Errorf("invalid --output: %s (must be table, json or csv)", output)
+		}
 
-		url := fmt.Sprintf("%s/api/tunnels/%s/requests", serverURL, tunnelID)
-		req, _ := http.NewRequest("GET", url, nil)
+		query := url.Values{}
+		if search != "" {
+			query.Set("q", search)
+		}
+		if method != "" {
+			query.Set("method", method)
+		}
+		if limit > 0 {
+			query.Set("limit", strconv.Itoa(limit))
+		}
+		if before != "" {
+			query.Set("before", before)
+		}
+		if since != "" {
+			query.Set("since", since)
+		}
+		endpoint := fmt.Sprintf("%s/api/tunnels/%s/requests", serverURL, tunnelID)
+		if len(query) > 0 {
+			endpoint += "?" + query.Encode()
+		}
+		req, _ := http.NewRequest("GET", endpoint, nil)
 		if token != "" {
 			req.Header.Set("Authorization", "Bearer "+token)
 		}
@@ -288,18 +971,23 @@ var requestsCmd = &cobra.Command{
 			return fmt.Errorf("server returned %d", resp.StatusCode)
 		}
 
-		var requests []struct {
-			ID         string `json:"id"`
-			Method     string `json:"method"`
-			Path       string `json:"path"`
-			Timestamp  string `json:"timestamp"`
-			StatusCode int    `json:"status_code"`
-		}
-
+		var requests []server.RequestSummary
 		if err := json.NewDecoder(resp.Body).Decode(&requests); err != nil {
 			return fmt.Errorf("failed to parse response: %w", err)
 		}
 
+		switch output {
+		case "json":
+			if requests == nil {
+				requests = []server.RequestSummary{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(requests)
+		case "csv":
+			return writeRequestsCSV(os.Stdout, requests)
+		}
+
 		if len(requests) == 0 {
 			fmt.Println("No requests found")
 			return nil
@@ -316,20 +1004,190 @@ var requestsCmd = &cobra.Command{
 
 			status := "-"
 			if r.StatusCode > 0 {
-				status = statusColor("%d", r.StatusCode)
+				status = statusColor(protocol.StatusLine(r.StatusCode, r.StatusText))
+			}
+
+			signed := ""
+			if r.Verified {
+				signed = "  " + color.GreenString("✓ signed")
+			}
+
+			size := formatBytes(int64(r.BodySize))
+			if r.StatusCode > 0 {
+				size += "→" + formatBytes(int64(r.ResBodySize))
+			}
+
+			failed := ""
+			if r.Error != "" {
+				failed = "  " + color.RedString(r.Error)
 			}
 
-			fmt.Printf("  %s  %-7s %s  %s\n",
+			fmt.Printf("  %s  %-7s %s  %s  %s%s%s\n",
 				color.HiBlackString(r.ID),
 				color.YellowString(r.Method),
 				r.Path,
 				status,
+				color.HiBlackString(size),
+				signed,
+				failed,
 			)
 		}
 		return nil
 	},
 }
 
+// writeRequestsCSV writes request summaries as CSV with a header row. The
+// status is empty for requests without a response.
+func writeRequestsCSV(out io.Writer, requests []server.RequestSummary) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"id", "method", "path", "timestamp", "status", "duration_ms", "body_size", "response_body_size", "verified", "error"})
+	for _, r := range requests {
+		status := ""
+		if r.StatusCode > 0 {
+			status = strconv.Itoa(r.StatusCode)
+		}
+		w.Write([]string{
+			r.ID, r.Method, r.Path, r.Timestamp, status,
+			strconv.FormatInt(r.DurationMs, 10),
+			strconv.Itoa(r.BodySize),
+			strconv.Itoa(r.ResBodySize),
+			strconv.FormatBool(r.Verified),
+			r.Error,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Tunnels command
+var tunnelsCmd = &cobra.Command{
+	Use:   "tunnels",
+	Short: "List active tunnels on a server",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverURL, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		output, _ := cmd.Flags().GetString("output")
+
+		if serverURL == "" {
+			return fmt.Errorf("--server is required")
+		}
+		if output != "table" && output != "json" {
+			return fmt.Errorf("invalid --output: %s (must be table or json)", output)
+		}
+
+		url := fmt.Sprintf("%s/api/tunnels", serverURL)
+		req, _ := http.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch tunnels: %w", err)
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusUnauthorized:
+			return fmt.Errorf("unauthorized: pass the server's auth token with --token")
+		case http.StatusForbidden:
+			return fmt.Errorf("server does not allow tunnel listing (start it with --token to enable)")
+		default:
+			return fmt.Errorf("server returned %d", resp.StatusCode)
+		}
+
+		var tunnels []server.TunnelInfo
+		if err := json.NewDecoder(resp.Body).Decode(&tunnels); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		if output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(tunnels)
+		}
+
+		if len(tunnels) == 0 {
+			fmt.Println("No active tunnels")
+			return nil
+		}
+
+		fmt.Printf("Active tunnels on %s:\n\n", color.CyanString(serverURL))
+		for _, t := range tunnels {
+			clients := ""
+			if t.Clients > 1 {
+				clients = color.MagentaString(" ×%d clients", t.Clients)
+			}
+			if t.SendQueue > 0 {
+				clients += color.RedString(" queue %d/%d", t.SendQueue, t.SendQueueSize)
+			}
+			active := "no webhooks yet"
+			if t.LastActivity != nil {
+				active = "last " + formatAgo(time.Since(*t.LastActivity))
+			}
+			fmt.Printf("  %s  %s  %s  %s  %s  %s%s\n",
+				color.HiBlackString(t.ShortID),
+				t.PublicURL,
+				color.HiBlackString("since %s", t.ConnectedAt.Local().Format("2006-01-02 15:04:05")),
+				color.YellowString("%d req", t.RequestCount),
+				formatBytes(t.Bytes),
+				color.HiBlackString(active),
+				clients,
+			)
+		}
+		return nil
+	},
+}
+
+// parseTunnelFlags parses repeated --tunnel name=target values
+func parseTunnelFlags(values []string) ([]client.TunnelConfig, error) {
+	seen := make(map[string]bool, len(values))
+	tunnels := make([]client.TunnelConfig, 0, len(values))
+	for _, v := range values {
+		name, target, ok := strings.Cut(v, "=")
+		if !ok || name == "" || target == "" {
+			return nil, fmt.Errorf("invalid --tunnel %q (expected name=target)", v)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate --tunnel name %q", name)
+		}
+		if _, err := url.Parse(target); err != nil {
+			return nil, fmt.Errorf("invalid --tunnel %q target: %w", name, err)
+		}
+		seen[name] = true
+		tunnels = append(tunnels, client.TunnelConfig{Name: name, Target: target})
+	}
+	return tunnels, nil
+}
+
+// formatBytes formats a byte count for display (e.g., 1.2KB)
+func formatBytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%dB", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1fKB", float64(n)/1024)
+	case n < 1024*1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	default:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1024*1024*1024))
+	}
+}
+
+// formatAgo formats an elapsed time for display (e.g., 5m ago)
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 // Replay command
 var replayCmd = &cobra.Command{
 	Use:   "replay",
@@ -385,33 +1243,530 @@ var replayCmd = &cobra.Command{
 	},
 }
 
+// Clear command
+var clearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete a tunnel's stored requests",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverURL, _ := cmd.Flags().GetString("server")
+		tunnelID, _ := cmd.Flags().GetString("tunnel")
+		token, _ := cmd.Flags().GetString("token")
+
+		url := fmt.Sprintf("%s/api/tunnels/%s/requests", serverURL, tunnelID)
+		req, _ := http.NewRequest("DELETE", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to clear requests: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("server returned %d", resp.StatusCode)
+		}
+
+		var result struct {
+			Cleared int `json:"cleared"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		fmt.Printf("Cleared %s stored requests for tunnel %s\n", color.CyanString("%d", result.Cleared), color.CyanString(tunnelID))
+		return nil
+	},
+}
+
+// Replay-file command
+var replayFileCmd = &cobra.Command{
+	Use:   "replay-file",
+	Short: "Send a request saved as HAR or JSON to a local target",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		target, _ := cmd.Flags().GetString("target")
+		entry, _ := cmd.Flags().GetInt("entry")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		insecureSkipVerify, _ := cmd.Flags().GetBool("insecure-skip-verify")
+		targetCA, _ := cmd.Flags().GetString("target-ca")
+
+		req, err := client.LoadRequestFile(file, entry)
+		if err != nil {
+			return err
+		}
+		targetTLS, err := client.TargetTLSConfig(targetCA, insecureSkipVerify)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		defer cancel()
+		start := time.Now()
+		res, err := client.ReplayRequest(ctx, req, target, targetTLS)
+		if err != nil {
+			return err
+		}
+
+		statusColor := color.GreenString
+		if res.StatusCode >= 400 {
+			statusColor = color.RedString
+		} else if res.StatusCode >= 300 {
+			statusColor = color.YellowString
+		}
+		fmt.Printf("%s %s → %s %s\n", color.YellowString(req.Method), req.Path,
+			statusColor(res.Status()),
+			color.HiBlackString("(%s from %s)", time.Since(start).Round(time.Millisecond), res.Target))
+		fmt.Println()
+		printHeaders(res.Headers)
+		printBody(res.Body, res.Headers.Get("Content-Type"), res.Headers.Get("Content-Encoding"))
+		return nil
+	},
+}
+
+// Curl command
+var curlCmd = &cobra.Command{
+	Use:   "curl",
+	Short: "Print a curl command that reproduces a stored request",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverURL, _ := cmd.Flags().GetString("server")
+		tunnelID, _ := cmd.Flags().GetString("tunnel")
+		requestID, _ := cmd.Flags().GetString("request")
+		token, _ := cmd.Flags().GetString("token")
+		target, _ := cmd.Flags().GetString("target")
+
+		if serverURL == "" {
+			return fmt.Errorf("--server is required")
+		}
+		if tunnelID == "" {
+			return fmt.Errorf("--tunnel is required")
+		}
+		if requestID == "" {
+			return fmt.Errorf("--request is required")
+		}
+
+		url := fmt.Sprintf("%s/api/tunnels/%s/requests/%s", serverURL, tunnelID, requestID)
+		req, _ := http.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("request %s not found for tunnel %s", requestID, tunnelID)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("server returned %d", resp.StatusCode)
+		}
+
+		var detail server.RequestDetail
+		if err := json.NewDecoder(resp.Body).Decode(&detail); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		// Default to the target that originally handled the request
+		if target == "" && detail.Response != nil {
+			target = detail.Response.Target
+		}
+		if target == "" {
+			target = "http://localhost:3000"
+		}
+
+		line, err := client.CurlCommand(detail.Request, target)
+		if err != nil {
+			return err
+		}
+		fmt.Println(line)
+		return nil
+	},
+}
+
+// Inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Show a stored request and its response in full",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverURL, _ := cmd.Flags().GetString("server")
+		tunnelID, _ := cmd.Flags().GetString("tunnel")
+		requestID, _ := cmd.Flags().GetString("request")
+		token, _ := cmd.Flags().GetString("token")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		if serverURL == "" {
+			return fmt.Errorf("--server is required")
+		}
+		if tunnelID == "" {
+			return fmt.Errorf("--tunnel is required")
+		}
+		if requestID == "" {
+			return fmt.Errorf("--request is required")
+		}
+
+		url := fmt.Sprintf("%s/api/tunnels/%s/requests/%s", serverURL, tunnelID, requestID)
+		req, _ := http.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("request %s not found for tunnel %s", requestID, tunnelID)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("server returned %d", resp.StatusCode)
+		}
+
+		var detail server.RequestDetail
+		if err := json.NewDecoder(resp.Body).Decode(&detail); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(detail)
+		}
+
+		r := detail.Request
+		signed := ""
+		if r.Verified {
+			signed = "  " + color.GreenString("✓ signed")
+		}
+		fmt.Printf("%s %s%s\n", color.YellowString(r.Method), r.Path, signed)
+		from := ""
+		if r.RemoteIP != "" {
+			from = color.HiBlackString("  from %s", r.RemoteIP)
+		}
+		fmt.Printf("%s  %s%s\n\n", color.HiBlackString(r.ID), color.HiBlackString(r.Timestamp.Local().Format(time.RFC3339)), from)
+		printHeaders(r.Headers)
+		printBody(r.Body, r.Headers.Get("Content-Type"), r.Headers.Get("Content-Encoding"))
+
+		fmt.Println()
+		if detail.Response == nil {
+			fmt.Println(color.HiBlackString("No response (pending, or the forward failed before one arrived)"))
+			return nil
+		}
+		res := detail.Response
+		statusColor := color.GreenString
+		if res.StatusCode >= 400 {
+			statusColor = color.RedString
+		} else if res.StatusCode >= 300 {
+			statusColor = color.YellowString
+		}
+		target := ""
+		if res.Target != "" {
+			target = color.HiBlackString(" from %s", res.Target)
+		}
+		fmt.Printf("Response: %s%s\n", statusColor(res.Status()), target)
+		if res.Error != "" {
+			fmt.Printf("%s\n", color.RedString("Forward failed: %s", res.Error))
+		}
+		fmt.Println()
+		printHeaders(res.Headers)
+		printBody(res.Body, res.Headers.Get("Content-Type"), res.Headers.Get("Content-Encoding"))
+		return nil
+	},
+}
+
+// validKeepalive checks --ping-interval and --pong-timeout
+func validKeepalive(ping, pong time.Duration) error {
+	if pong <= 0 {
+		return fmt.Errorf("invalid --pong-timeout: %s (must be > 0)", pong)
+	}
+	if ping < 0 || ping >= pong {
+		return fmt.Errorf("invalid --ping-interval: %s (must be shorter than --pong-timeout)", ping)
+	}
+	return nil
+}
+
+// printHeaders prints headers sorted by name
+func printHeaders(headers protocol.Headers) {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, v := range headers[k] {
+			fmt.Printf("  %s %s\n", color.CyanString(k+":"), v)
+		}
+	}
+}
+
+// printBody prints a body, pretty-printing JSON. Binary bodies are summarized.
+func printBody(body []byte, contentType, contentEncoding string) {
+	if len(body) == 0 {
+		return
+	}
+	fmt.Println()
+	if !protocol.IsTextBody(body, contentType, contentEncoding) {
+		fmt.Println(color.HiBlackString("  <binary body, %s (%s)>", formatBytes(int64(len(body))), contentType))
+		return
+	}
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, body, "  ", "  ") == nil {
+		fmt.Println("  " + pretty.String())
+		return
+	}
+	fmt.Println("  " + strings.ReplaceAll(strings.TrimRight(string(body), "\n"), "\n", "\n  "))
+}
+
+// Export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a tunnel's stored requests as a HAR archive",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverURL, _ := cmd.Flags().GetString("server")
+		tunnelID, _ := cmd.Flags().GetString("tunnel")
+		token, _ := cmd.Flags().GetString("token")
+		out, _ := cmd.Flags().GetString("out")
+
+		if serverURL == "" {
+			return fmt.Errorf("--server is required")
+		}
+		if tunnelID == "" {
+			return fmt.Errorf("--tunnel is required")
+		}
+
+		url := fmt.Sprintf("%s/api/tunnels/%s/har", serverURL, tunnelID)
+		req, _ := http.NewRequest("GET", url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to export requests: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("server returned %d", resp.StatusCode)
+		}
+
+		if out == "" {
+			_, err := io.Copy(os.Stdout, resp.Body)
+			return err
+		}
+
+		f, err := os.Create(out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", out, err)
+		}
+		if _, err := io.Copy(f, resp.Body); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write %s: %w", out, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", out, err)
+		}
+		fmt.Printf("Exported tunnel %s to %s\n", color.CyanString(tunnelID), out)
+		return nil
+	},
+}
+
+// configProfile returns the config profile chosen with --profile, or else
+// with HOOKSHOT_PROFILE
+func configProfile(cmd *cobra.Command) string {
+	if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+		return profile
+	}
+	return os.Getenv(config.ProfileEnv)
+}
+
+// completeProfiles offers the profiles defined in the config file
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	configFile, _ := cmd.Flags().GetString("config")
+	if configFile == "" {
+		configFile = config.FindConfigFile()
+	}
+	if configFile == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.Load(configFile, "")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return cfg.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// Completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Print a script that completes hookshot's commands and flags.
+
+Bash (needs the bash-completion package):
+  source <(hookshot completion bash)
+  # or permanently:
+  hookshot completion bash > /etc/bash_completion.d/hookshot
+
+Zsh:
+  hookshot completion zsh > "${fpath[1]}/_hookshot"
+
+Fish:
+  hookshot completion fish > ~/.config/fish/completions/hookshot.fish
+
+PowerShell:
+  hookshot completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The scripts call back into hookshot for candidates, so flags can
+		// gain dynamic completions (e.g., tunnel IDs) without regenerating
+		out := cmd.OutOrStdout()
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(out, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(out)
+		case "fish":
+			return rootCmd.GenFishCompletion(out, true)
+		default:
+			return rootCmd.GenPowerShellCompletionWithDesc(out)
+		}
+	},
+}
+
 func init() {
+	rootCmd.PersistentFlags().Bool("no-color", false, "Disable colored output (also set by NO_COLOR; FORCE_COLOR forces it on)")
+
 	// Server flags
 	serverCmd.Flags().StringP("config", "c", "", "Config file path")
+	serverCmd.Flags().String("profile", "", "Config file profile to apply over the top-level settings (default $HOOKSHOT_PROFILE)")
 	serverCmd.Flags().IntP("port", "p", 8080, "Port to listen on")
 	serverCmd.Flags().String("host", "0.0.0.0", "Host to bind to")
 	serverCmd.Flags().String("public-url", "", "Public URL for the server (for display)")
 	serverCmd.Flags().Int("max-requests", 100, "Maximum requests to store per tunnel")
+	serverCmd.Flags().String("store-path", "", "SQLite file to persist request history across restarts (default in-memory)")
 	serverCmd.Flags().String("token", "", "Auth token (required for client connections if set)")
 	serverCmd.Flags().String("tls-cert", "", "Path to TLS certificate file")
 	serverCmd.Flags().String("tls-key", "", "Path to TLS key file")
+	serverCmd.Flags().StringSlice("acme-domain", nil, "Get a Let's Encrypt certificate for this domain (serves HTTPS on 443, challenges on 80)")
+	serverCmd.Flags().String("acme-email", "", "Contact email for the Let's Encrypt account")
+	serverCmd.Flags().String("acme-cache-dir", "", "Directory for ACME certificates (default in the user cache dir)")
+	serverCmd.Flags().Int("register-rate-limit", 0, "Max new tunnel registrations per minute (0 = unlimited)")
+	serverCmd.Flags().Int("max-tunnels", 0, "Max tunnels connected at once; further registrations are rejected (0 = unlimited)")
+	serverCmd.Flags().StringSlice("allow-ips", nil, "Only accept webhooks from these CIDRs or IPs; others get 403")
+	serverCmd.Flags().StringSlice("deny-ips", nil, "Reject webhooks from these CIDRs or IPs with 403")
+	serverCmd.Flags().StringSlice("trusted-proxies", nil, "Proxies whose X-Forwarded-For header is used as the webhook source")
+	serverCmd.Flags().StringSlice("cors-origins", nil, "Let browsers on these origins send webhooks (\"*\" for any); off by default")
+	serverCmd.Flags().StringSlice("cors-methods", nil, "Methods allowed in CORS preflights (default GET,POST,PUT,PATCH,DELETE)")
+	serverCmd.Flags().StringSlice("cors-headers", nil, "Request headers allowed in CORS preflights (default: whatever the browser asks for)")
+	serverCmd.Flags().Float64("rate-limit", 0, "Max webhooks per second per tunnel; excess gets 429 (0 = unlimited)")
+	serverCmd.Flags().Int("rate-limit-burst", 0, "Webhooks allowed in a burst above --rate-limit (default: the rate)")
+	serverCmd.Flags().Int("no-tunnel-status", 404, "HTTP status returned when a webhook's tunnel is not connected")
+	serverCmd.Flags().Int("no-tunnel-retry-after", 0, "Retry-After seconds sent with the no-tunnel status (0 = omit)")
+	serverCmd.Flags().String("no-tunnel-body", "", "Response body sent with the no-tunnel status, plain text or HTML")
+	serverCmd.Flags().String("no-tunnel-body-file", "", "File holding the no-tunnel response body (e.g., an HTML page)")
+	serverCmd.Flags().Bool("buffer-offline", false, "Buffer webhooks for disconnected tunnels and deliver them when the client reconnects")
+	serverCmd.Flags().Int("offline-buffer-size", 100, "Max webhooks buffered per disconnected tunnel")
+	serverCmd.Flags().Duration("offline-buffer-age", 10*time.Minute, "How long to buffer for a disconnected tunnel")
+	serverCmd.Flags().Bool("lock-tunnels", false, "Only accept tunnel IDs listed in --tunnels-file")
+	serverCmd.Flags().String("tunnels-file", "", "YAML file of bound tunnel IDs and their settings (reloaded on SIGHUP)")
+	serverCmd.Flags().Bool("allow-multi-client", false, "Honor client-requested tunnel IDs and let clients share them")
+	serverCmd.Flags().String("balance", "round-robin", "Balancing across shared clients: round-robin, least-in-flight or failover")
+	serverCmd.Flags().Bool("debug", false, "Log redacted dumps of malformed protocol frames")
+	serverCmd.Flags().Bool("dashboard", false, "Serve a browser dashboard at /dashboard (requires --token)")
+	serverCmd.Flags().String("transform-script", "", "Starlark script to rewrite or reject each webhook")
+	serverCmd.Flags().Duration("transform-timeout", 500*time.Millisecond, "Max execution time for the transform script per webhook")
+	serverCmd.Flags().String("sink", "", "URL to POST an event to for every received webhook")
+	serverCmd.Flags().String("sink-payload", "summary", "Sink event contents: summary or full (with headers and body)")
+	serverCmd.Flags().Int("sink-queue-size", 1000, "Max sink events buffered before dropping")
+	serverCmd.Flags().Int64("max-decompressed-bytes", 10*1024*1024, "Max size of a compressed response body once decompressed")
+	serverCmd.Flags().Int64("max-body-size", 10*1024*1024, "Max webhook or response body size in bytes; larger webhooks get 413")
+	serverCmd.Flags().Int("chunk-size", 1024*1024, "Bodies larger than this cross the tunnel in chunks of this size")
+	serverCmd.Flags().Int("send-buffer", 256, "Messages queued per client connection; webhooks get 503 with Retry-After once it is 90% full")
+	serverCmd.Flags().Bool("no-ws-compression", false, "Don't negotiate permessage-deflate on tunnel connections")
+	serverCmd.Flags().Bool("require-upgrade-auth", false, "Refuse WebSocket upgrades without a valid Authorization header, before accepting the connection (older clients only send the token after it)")
+	serverCmd.Flags().Duration("drain-timeout", 10*time.Second, "On shutdown, how long to wait for in-flight webhooks before closing tunnels")
+	serverCmd.Flags().Duration("tunnel-idle-timeout", 0, "Close tunnels that receive no webhooks for this long; their clients exit (0 = never)")
+	serverCmd.Flags().Duration("url-ttl", 0, "Sign public URLs so they expire after this long; expired URLs get 410 (0 = never expire)")
+	serverCmd.Flags().Duration("dedup-window", 0, "Answer webhooks redelivered within this long with the first delivery's response instead of forwarding (0 = off)")
+	serverCmd.Flags().StringToString("alias", nil, "Memorable name for a tunnel ID, as alias=tunnel-id; webhooks to /t/alias go to that tunnel (repeatable)")
+	serverCmd.Flags().String("alias-prefix", "", "Also serve aliases at this path prefix, e.g. / for /alias/... (default: only /t/alias)")
+	serverCmd.Flags().String("dedup-header", "", "Header identifying a delivery for --dedup-window (e.g., X-GitHub-Delivery; default: a hash of method, path and body)")
+	serverCmd.Flags().Duration("response-wait", 30*time.Second, "How long a webhook waits for the client's response before failing with 502")
+	serverCmd.Flags().Duration("ping-interval", 0, "How often to ping each client over its WebSocket (default 90% of --pong-timeout)")
+	serverCmd.Flags().Duration("pong-timeout", 60*time.Second, "Drop clients that don't answer a ping within this time")
 
 	// Client flags
 	clientCmd.Flags().StringP("config", "c", "", "Config file path")
+	clientCmd.Flags().String("profile", "", "Config file profile to apply over the top-level settings (default $HOOKSHOT_PROFILE)")
 	clientCmd.Flags().StringP("server", "s", "", "Server URL (e.g., https://relay.example.com)")
 	clientCmd.Flags().StringP("target", "t", "http://localhost:3000", "Local target URL")
 	clientCmd.Flags().String("id", "", "Requested tunnel ID (optional)")
 	clientCmd.Flags().String("token", "", "Auth token for server")
 	clientCmd.Flags().BoolP("verbose", "v", false, "Show request/response bodies")
+	clientCmd.Flags().Bool("echo", false, "Answer webhooks with a JSON summary of each instead of forwarding (to test the tunnel)")
 	clientCmd.Flags().Bool("tui", false, "Enable interactive TUI mode")
+	clientCmd.Flags().Duration("heartbeat-interval", 0, "Send app-level pings to the server at this interval (0 = disabled)")
+	clientCmd.Flags().Duration("summary-interval", 0, "Print a one-line summary of forwarded requests at this interval (0 = never)")
+	clientCmd.Flags().Duration("heartbeat-timeout", 10*time.Second, "Reconnect if no heartbeat pong arrives within this time")
+	clientCmd.Flags().Duration("ping-interval", 0, "How often to ping the server over the WebSocket (default 90% of --pong-timeout)")
+	clientCmd.Flags().Duration("pong-timeout", 60*time.Second, "Reconnect if the server doesn't answer a ping within this time")
+	clientCmd.Flags().Int("max-reconnects", 0, "Exit with an error after this many failed reconnect attempts in a row (0 = retry forever)")
+	clientCmd.Flags().Int("body-display-limit", 500, "Max body characters shown with --verbose")
+	clientCmd.Flags().String("log-file", "", "Append every forwarded request to this JSONL file")
+	clientCmd.Flags().Bool("log-bodies", false, "Include request and response bodies in --log-file")
+	clientCmd.Flags().String("history-file", "", "With --tui, keep the request list in this file and reload it on startup")
+	clientCmd.Flags().Bool("ordered", false, "Forward each tunnel's webhooks one at a time, in arrival order")
+	clientCmd.Flags().Int("max-concurrency", 0, "Max webhooks forwarded at once; more wait in a queue (0 = unlimited)")
+	clientCmd.Flags().StringSlice("accept-path", nil, "Only handle these request paths (prefixes, or globs like /hooks/*)")
+	clientCmd.Flags().StringSlice("ignore-path", nil, "Answer these request paths without forwarding (prefixes or globs)")
+	clientCmd.Flags().Int("ignore-status", 404, "Status returned for paths not handled by --accept-path/--ignore-path")
+	clientCmd.Flags().String("inject-delay", "", "Chaos testing: wait this long before handling each webhook, fixed or a random range (e.g., 500ms or 100ms-2s)")
+	clientCmd.Flags().Float64("drop-rate", 0, "Chaos testing: fraction of webhooks answered with 500 without forwarding (e.g., 0.1)")
+	clientCmd.Flags().StringSlice("expect-status", nil, "Expected target statuses, warn otherwise (e.g., 2xx,404,200-204)")
+	clientCmd.Flags().StringArray("tunnel", nil, "Named tunnel as name=target, repeatable (one connection, one URL per tunnel)")
+	clientCmd.Flags().Int("async-ack", 0, "Have the server ack webhooks with this 2xx status and forward in the background")
+	clientCmd.Flags().String("host-header", "", "Override the Host header sent to the target (e.g., app.local)")
+	clientCmd.Flags().String("client-ip-header", "", "Pass the webhook sender's IP to the target in this header (e.g., X-Forwarded-For or X-Real-IP)")
+	clientCmd.Flags().String("transform", "", "jq expression that rewrites JSON request bodies before forwarding (e.g., '{event: .type}')")
+	clientCmd.Flags().Int64("max-decompressed-bytes", 10*1024*1024, "Max size of a compressed or chunked request body once decoded")
+	clientCmd.Flags().Int64("max-response-size", client.DefaultMaxResponseSize, "Max size of a target's response body; larger responses fail with 502")
+	clientCmd.Flags().Bool("no-ws-compression", false, "Don't offer permessage-deflate on the tunnel connection")
+	clientCmd.Flags().Int("retry-attempts", 0, "Attempts per webhook while the target is down or failing (0 = no retries)")
+	clientCmd.Flags().Duration("retry-delay", 500*time.Millisecond, "Wait before the first retry, doubled after each retry")
+	clientCmd.Flags().StringSlice("retry-status", nil, "Target statuses that trigger a retry (default 5xx)")
+	clientCmd.Flags().StringArray("inject-header", nil, "Header added to every forwarded request, as \"Name: value\" (repeatable)")
+	clientCmd.Flags().StringSlice("remove-header", nil, "Headers stripped from forwarded requests (e.g., Cookie,Authorization)")
+	clientCmd.Flags().StringToString("target-header-map", nil, "Rename headers before forwarding, keeping their values (e.g., X-Signature=X-Hub-Signature)")
+	clientCmd.Flags().Bool("insecure-skip-verify", false, "Don't verify certificates of https:// targets (e.g., self-signed dev certs)")
+	clientCmd.Flags().String("target-ca", "", "PEM file of CA certificates to trust for https:// targets")
+	clientCmd.Flags().Bool("insecure", false, "Don't verify the relay server's certificate (testing only)")
+	clientCmd.Flags().String("relay-ca", "", "PEM file of CA certificates to trust for an https:// server")
+	clientCmd.Flags().String("proxy", "", "Proxy for the connection to the server, http:// or socks5:// (default from HTTPS_PROXY/HTTP_PROXY)")
+	clientCmd.Flags().Duration("target-timeout", 29*time.Second, "Max time to forward one webhook, retries included (capped under the server's response wait)")
 
 	// Requests flags
 	requestsCmd.Flags().StringP("server", "s", "", "Server URL")
 	requestsCmd.Flags().String("tunnel", "", "Tunnel ID")
 	requestsCmd.Flags().String("token", "", "Auth token for server")
+	requestsCmd.Flags().StringP("search", "q", "", "Only requests whose path or body contains this (case-insensitive)")
+	requestsCmd.Flags().String("method", "", "Only requests with this method")
+	requestsCmd.Flags().IntP("limit", "n", 0, "Show at most this many requests, newest first (0 = all)")
+	requestsCmd.Flags().String("before", "", "Only requests older than this request ID or RFC 3339 time")
+	requestsCmd.Flags().String("since", "", "Only requests newer than this request ID or RFC 3339 time")
+	requestsCmd.Flags().StringP("output", "o", "table", "Output format: table, json or csv")
+	requestsCmd.Flags().Bool("json", false, "Shorthand for --output json")
 	requestsCmd.MarkFlagRequired("server")
 	requestsCmd.MarkFlagRequired("tunnel")
 
+	// Tunnels flags
+	tunnelsCmd.Flags().StringP("server", "s", "", "Server URL")
+	tunnelsCmd.Flags().String("token", "", "Auth token for server")
+	tunnelsCmd.Flags().StringP("output", "o", "table", "Output format: table or json")
+	tunnelsCmd.MarkFlagRequired("server")
+
 	// Replay flags
 	replayCmd.Flags().StringP("server", "s", "", "Server URL")
 	replayCmd.Flags().String("tunnel", "", "Tunnel ID")
@@ -421,9 +1776,83 @@ func init() {
 	replayCmd.MarkFlagRequired("tunnel")
 	replayCmd.MarkFlagRequired("request")
 
+	// Clear flags
+	clearCmd.Flags().StringP("server", "s", "", "Server URL")
+	clearCmd.Flags().String("tunnel", "", "Tunnel ID")
+	clearCmd.Flags().String("token", "", "Auth token for server")
+	clearCmd.MarkFlagRequired("server")
+	clearCmd.MarkFlagRequired("tunnel")
+
+	// Replay-file flags
+	replayFileCmd.Flags().StringP("file", "f", "", "HAR archive, hookshot inspect --json output, or request JSON")
+	replayFileCmd.Flags().StringP("target", "t", "http://localhost:3000", "Target to send the request to")
+	replayFileCmd.Flags().Int("entry", 0, "Which entry of a HAR archive to send (0 = first)")
+	replayFileCmd.Flags().Duration("timeout", 30*time.Second, "Max time to wait for the target")
+	replayFileCmd.Flags().Bool("insecure-skip-verify", false, "Don't verify the certificate of an https:// target")
+	replayFileCmd.Flags().String("target-ca", "", "PEM file of CA certificates to trust for an https:// target")
+	replayFileCmd.MarkFlagRequired("file")
+
+	// Curl flags
+	inspectCmd.Flags().StringP("server", "s", "", "Server URL")
+	inspectCmd.Flags().String("tunnel", "", "Tunnel ID")
+	inspectCmd.Flags().StringP("request", "r", "", "Request ID")
+	inspectCmd.Flags().String("token", "", "Auth token for server")
+	inspectCmd.Flags().Bool("json", false, "Print the request and response as JSON (bodies base64-encoded)")
+	inspectCmd.MarkFlagRequired("server")
+	inspectCmd.MarkFlagRequired("tunnel")
+	inspectCmd.MarkFlagRequired("request")
+
+	curlCmd.Flags().StringP("server", "s", "", "Server URL")
+	curlCmd.Flags().String("tunnel", "", "Tunnel ID")
+	curlCmd.Flags().StringP("request", "r", "", "Request ID")
+	curlCmd.Flags().String("token", "", "Auth token for server")
+	curlCmd.Flags().StringP("target", "t", "", "Target URL for the generated command (default: the target that handled the request)")
+	curlCmd.MarkFlagRequired("server")
+	curlCmd.MarkFlagRequired("tunnel")
+	curlCmd.MarkFlagRequired("request")
+
+	// Export flags
+	exportCmd.Flags().StringP("server", "s", "", "Server URL")
+	exportCmd.Flags().String("tunnel", "", "Tunnel ID")
+	exportCmd.Flags().String("token", "", "Auth token for server")
+	exportCmd.Flags().StringP("out", "o", "", "Output file (default: stdout)")
+	exportCmd.MarkFlagRequired("server")
+	exportCmd.MarkFlagRequired("tunnel")
+
 	// Add commands
 	rootCmd.AddCommand(serverCmd)
 	rootCmd.AddCommand(clientCmd)
 	rootCmd.AddCommand(requestsCmd)
+	rootCmd.AddCommand(tunnelsCmd)
 	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(replayFileCmd)
+	rootCmd.AddCommand(clearCmd)
+	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(curlCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(completionCmd)
+
+	// Completion of flag values with a fixed set of choices or a file
+	for _, c := range []*cobra.Command{serverCmd, clientCmd} {
+		c.MarkFlagFilename("config", "yaml", "yml")
+		c.RegisterFlagCompletionFunc("profile", completeProfiles)
+	}
+	serverCmd.MarkFlagFilename("store-path")
+	serverCmd.MarkFlagFilename("tls-cert")
+	serverCmd.MarkFlagFilename("tls-key")
+	serverCmd.MarkFlagDirname("acme-cache-dir")
+	serverCmd.MarkFlagFilename("no-tunnel-body-file")
+	serverCmd.MarkFlagFilename("tunnels-file", "yaml", "yml")
+	serverCmd.MarkFlagFilename("transform-script", "star")
+	serverCmd.RegisterFlagCompletionFunc("balance", cobra.FixedCompletions([]string{"round-robin", "least-in-flight", "failover"}, cobra.ShellCompDirectiveNoFileComp))
+	serverCmd.RegisterFlagCompletionFunc("sink-payload", cobra.FixedCompletions([]string{"summary", "full"}, cobra.ShellCompDirectiveNoFileComp))
+	clientCmd.MarkFlagFilename("log-file")
+	clientCmd.MarkFlagFilename("history-file")
+	clientCmd.MarkFlagFilename("target-ca")
+	clientCmd.MarkFlagFilename("relay-ca")
+	requestsCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"table", "json", "csv"}, cobra.ShellCompDirectiveNoFileComp))
+	tunnelsCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"table", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	replayFileCmd.MarkFlagFilename("file", "har", "json")
+	replayFileCmd.MarkFlagFilename("target-ca")
+	exportCmd.MarkFlagFilename("out", "har")
 }
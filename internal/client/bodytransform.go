@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/itchyny/gojq"
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// bodyTransformTimeout bounds one jq run, so a runaway expression (e.g.,
+// `repeat(.)`) can't hold up a forward
+const bodyTransformTimeout = 500 * time.Millisecond
+
+// BodyTransform rewrites JSON request bodies with a jq expression before
+// they are forwarded, such as `{event: .type, id: .data.object.id}`.
+// Bodies that aren't JSON are forwarded unchanged.
+type BodyTransform struct {
+	expr string
+	code *gojq.Code
+}
+
+// TransformErrorFunc is called when a body transform fails; the original
+// body is forwarded
+type TransformErrorFunc func(req *protocol.HTTPRequest, err error)
+
+// ParseBodyTransform compiles a jq expression
+func ParseBodyTransform(expr string) (*BodyTransform, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transform %q: %w", expr, err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transform %q: %w", expr, err)
+	}
+	return &BodyTransform{expr: expr, code: code}, nil
+}
+
+// String returns the jq expression
+func (t *BodyTransform) String() string {
+	return t.expr
+}
+
+// applies reports whether req has a JSON body to transform
+func (t *BodyTransform) applies(req *protocol.HTTPRequest) bool {
+	if len(req.Body) == 0 {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Headers.Get("Content-Type"))
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// apply runs the expression on body and returns the single JSON value it
+// produces
+func (t *BodyTransform) apply(ctx context.Context, body []byte) ([]byte, error) {
+	var input any
+	if err := json.Unmarshal(body, &input); err != nil {
+		return nil, fmt.Errorf("body is not valid JSON: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, bodyTransformTimeout)
+	defer cancel()
+
+	iter := t.code.RunWithContext(ctx, input)
+	v, ok := iter.Next()
+	if !ok {
+		return nil, errors.New("transform produced no output")
+	}
+	if err, ok := v.(error); ok {
+		return nil, fmt.Errorf("transform failed: %w", err)
+	}
+	if _, more := iter.Next(); more {
+		return nil, errors.New("transform produced more than one value")
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("transform output is not JSON: %w", err)
+	}
+	return out, nil
+}
+
+// transformRequest returns req with its body rewritten by the forwarder's
+// transform, or req itself when there is none or it doesn't apply. On
+// failure the error is reported and req is forwarded unchanged.
+func (f *Forwarder) transformRequest(ctx context.Context, req *protocol.HTTPRequest) *protocol.HTTPRequest {
+	if f.bodyTransform == nil || !f.bodyTransform.applies(req) {
+		return req
+	}
+	body, err := f.bodyTransform.apply(ctx, req.Body)
+	if err != nil {
+		if f.onTransformError != nil {
+			f.onTransformError(req, err)
+		}
+		return req
+	}
+	out := *req
+	out.Body = body
+	return &out
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// ChaosTarget is reported as the target of requests dropped by Chaos
+const ChaosTarget = "chaos"
+
+// Chaos injects faults for testing how a target and webhook senders cope
+// with slow or failed delivery. The zero value injects nothing.
+type Chaos struct {
+	DelayMin time.Duration // Wait at least this long before handling each request
+	DelayMax time.Duration // Optional: wait a random time up to this instead of exactly DelayMin
+	DropRate float64       // Fraction of requests answered with 500 without forwarding (0-1)
+}
+
+// ParseDelay parses a delay like "500ms" or a range like "100ms-2s" and
+// returns its bounds
+func ParseDelay(spec string) (time.Duration, time.Duration, error) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+	from, err := time.ParseDuration(lo)
+	if err != nil || from < 0 {
+		return 0, 0, fmt.Errorf("invalid delay %q (e.g., 500ms or 100ms-2s)", spec)
+	}
+	if !isRange {
+		return from, from, nil
+	}
+	to, err := time.ParseDuration(hi)
+	if err != nil || to < from {
+		return 0, 0, fmt.Errorf("invalid delay range %q (e.g., 100ms-2s)", spec)
+	}
+	return from, to, nil
+}
+
+// String describes the injected faults for the startup notice, or returns
+// "" when there are none
+func (ch Chaos) String() string {
+	var parts []string
+	switch {
+	case ch.DelayMax > ch.DelayMin:
+		parts = append(parts, fmt.Sprintf("delaying requests %s-%s", ch.DelayMin, ch.DelayMax))
+	case ch.DelayMin > 0:
+		parts = append(parts, fmt.Sprintf("delaying requests %s", ch.DelayMin))
+	}
+	if ch.DropRate > 0 {
+		parts = append(parts, fmt.Sprintf("dropping %g%% with 500", ch.DropRate*100))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// delay returns how long to hold the next request
+func (ch Chaos) delay() time.Duration {
+	if ch.DelayMax <= ch.DelayMin {
+		return ch.DelayMin
+	}
+	return ch.DelayMin + rand.N(ch.DelayMax-ch.DelayMin+1)
+}
+
+// wait holds a request for the injected delay, or until ctx ends
+func (ch Chaos) wait(ctx context.Context) error {
+	d := ch.delay()
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// drop reports whether to fail the next request instead of handling it
+func (ch Chaos) drop() bool {
+	return ch.DropRate > 0 && rand.Float64() < ch.DropRate
+}
+
+// droppedResponse is the 500 a dropped request is answered with
+func droppedResponse(req *protocol.HTTPRequest) *protocol.HTTPResponse {
+	return &protocol.HTTPResponse{
+		RequestID:  req.ID,
+		StatusCode: http.StatusInternalServerError,
+		Headers:    protocol.Headers{"Content-Type": {"text/plain"}},
+		Body:       []byte("Dropped by hookshot chaos testing (--drop-rate)"),
+		Target:     ChaosTarget,
+	}
+}
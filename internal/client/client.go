@@ -1,13 +1,22 @@
 package client
 
 import (
+	"compress/flate"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -16,15 +25,66 @@ import (
 )
 
 const (
-	reconnectDelay    = 2 * time.Second
-	maxReconnectDelay = 30 * time.Second
-	pongWait          = 60 * time.Second
+	reconnectDelay     = 2 * time.Second
+	maxReconnectDelay  = 30 * time.Second
+	writeWait          = 10 * time.Second
+	defaultPongTimeout = 60 * time.Second
+
+	defaultHeartbeatTimeout = 10 * time.Second
+	defaultTargetTimeout    = protocol.ResponseTimeout - time.Second
 )
 
+// ErrIdleTimeout is returned by Run when the server closed the tunnel for
+// inactivity; the client does not reconnect
+var ErrIdleTimeout = errors.New("tunnel expired after being idle")
+
+// ServerError is a rejection or close the server gave a reason for. Run
+// stops reconnecting when the code is fatal (see protocol.FatalErrorCode).
+type ServerError struct {
+	Code    string // A protocol.ErrCode
+	Message string // Optional: the server's explanation
+}
+
+func (e *ServerError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("server error: %s (%s)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("server closed the connection (%s)", e.Code)
+}
+
+// fatal reports whether err means reconnecting would fail the same way
+func fatal(err error) bool {
+	var se *ServerError
+	return errors.Is(err, ErrIdleTimeout) || (errors.As(err, &se) && protocol.FatalErrorCode(se.Code))
+}
+
+// closeReason returns the server's reason from a close frame, if it gave one
+func closeReason(err error) *ServerError {
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) && ce.Text != "" {
+		return &ServerError{Code: ce.Text}
+	}
+	return nil
+}
+
 // Route maps a path prefix to a target
 type Route struct {
-	Path   string
-	Target string
+	Path    string
+	Methods []string // Optional: only match these methods (empty = any)
+	Target  string
+	Mirrors []string // Optional: extra targets that get a copy; their responses are logged and discarded
+
+	StripPrefix bool   // Remove Path from the forwarded path
+	Rewrite     string // Replace Path with this in the forwarded path (e.g., "/webhook")
+
+	Headers HeaderRules // Optional: header edits on top of Config.Headers
+}
+
+// TunnelConfig is one named tunnel of a multi-tunnel client
+type TunnelConfig struct {
+	Name     string
+	Target   string
+	TunnelID string // Optional: requested tunnel ID
 }
 
 // Config holds client configuration
@@ -36,6 +96,66 @@ type Config struct {
 	Token     string  // Optional: auth token
 	Verbose   bool    // Show request/response bodies
 	TUIMode   bool    // Enable TUI mode
+
+	HeartbeatInterval time.Duration // Optional: send app-level pings this often (0 = disabled)
+	HeartbeatTimeout  time.Duration // How long to wait for a pong before reconnecting
+
+	SummaryInterval time.Duration // Optional: log a summary of forwarded requests this often (0 = never; ignored in TUI mode)
+
+	PingInterval time.Duration // WebSocket ping interval (default 90% of PongTimeout)
+	PongTimeout  time.Duration // Reconnect after this long without a pong or message from the server (default 60s)
+
+	MaxReconnects int // Optional: Run gives up after this many failed reconnects in a row (0 = retry forever)
+
+	HostHeader string // Optional: Host header sent to the target
+
+	ClientIPHeader string // Optional: pass the webhook sender's IP to the target in this header (e.g., X-Forwarded-For)
+
+	BodyTransform *BodyTransform // Optional: rewrite JSON request bodies with a jq expression before forwarding
+
+	TargetTLS *tls.Config // Optional: TLS settings for https:// targets (see TargetTLSConfig)
+	RelayTLS  *tls.Config // Optional: TLS settings for a wss:// relay server (see RelayTLSConfig)
+	Proxy     *url.URL    // Optional: proxy for the relay connection (default: HTTPS_PROXY/HTTP_PROXY/NO_PROXY)
+
+	Headers HeaderRules // Optional: headers injected into or removed from forwarded requests
+
+	Echo bool // Answer every webhook with a JSON summary of it instead of forwarding
+
+	BodyDisplayLimit int // Max body chars shown in verbose logs (default 500)
+
+	ExpectStatus []StatusRange // Optional: warn when target responses fall outside these
+
+	AsyncAck int // Optional: have the server ack webhooks with this 2xx and forward in the background
+
+	Tunnels []TunnelConfig // Optional: several named tunnels over one connection (replaces Target/Routes/TunnelID)
+
+	MaxDecompressedBytes int64 // Max size of a gzip-decoded or reassembled chunked request body (default 10MB)
+	MaxResponseSize      int64 // Max size of a target's response body; larger ones fail with 502 (default 10MB)
+
+	Retry RetryPolicy // Optional: retry forwards while the target is down or failing
+
+	TargetTimeout time.Duration // Max time to forward one webhook, retries included (default 29s; kept under the server's response wait)
+
+	Mocks []Mock // Optional: canned responses served without forwarding (first match wins)
+
+	Paths PathFilter // Optional: only handle some request paths
+
+	Chaos Chaos // Optional: delay or drop requests to test retry and timeout handling
+
+	Ordered        bool // Forward each tunnel's requests one at a time, in arrival order
+	MaxConcurrency int  // Max requests forwarded at once; more are queued (0 = unlimited)
+
+	DisableWSCompression bool // Don't offer permessage-deflate on the tunnel connection
+
+	RequestLog *RequestLog // Optional: append every forwarded request to a JSONL file (see OpenRequestLog)
+}
+
+// namedTunnel tracks one tunnel of a multi-tunnel client across reconnects
+type namedTunnel struct {
+	cfg         TunnelConfig
+	forwarder   *Forwarder
+	id          string
+	resumeToken string
 }
 
 // Client is the hookshot tunnel client
@@ -48,6 +168,21 @@ type Client struct {
 	tunnelID  string
 	publicURL string
 
+	resumeToken string // From the last registration; reclaims tunnelID on reconnect
+
+	named      []*namedTunnel        // Multi-tunnel mode only, in Config.Tunnels order
+	tunnelsMu  sync.RWMutex          // Protects byTunnelID
+	byTunnelID map[string]*Forwarder // Assigned tunnel ID -> forwarder (multi-tunnel mode)
+
+	gzip        atomic.Bool  // Server negotiated gzip-compressed bodies
+	chunkSize   atomic.Int64 // Server reassembles response bodies split at this size (0 = unsupported)
+	serverWait  atomic.Int64 // Server's response wait in ms, from the last registration
+	parseErrors atomic.Int64 // Malformed frames received from the server
+	unexpected  atomic.Int64 // Target responses outside ExpectStatus
+
+	connected atomic.Bool    // Registered with the server
+	summary   summaryCounter // Requests since the last summary line
+
 	// TUI mode channels
 	tuiRequestCh chan<- tui.RequestItem
 	tuiConnCh    chan<- tui.ConnectionInfo
@@ -55,44 +190,173 @@ type Client struct {
 
 // New creates a new client
 func New(cfg Config) *Client {
+	if cfg.HeartbeatInterval > 0 && cfg.HeartbeatTimeout <= 0 {
+		cfg.HeartbeatTimeout = defaultHeartbeatTimeout
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = defaultPongTimeout
+	}
+	if cfg.PingInterval <= 0 {
+		cfg.PingInterval = cfg.PongTimeout * 9 / 10
+	}
+	if cfg.TargetTimeout <= 0 {
+		cfg.TargetTimeout = defaultTargetTimeout
+	}
+
 	var forwarder *Forwarder
 
 	if len(cfg.Routes) > 0 {
 		// Create forwarder with route-based resolution
-		forwarder = NewForwarderWithRoutes(cfg.Target, func(path string) string {
-			return matchRoute(cfg.Routes, cfg.Target, path)
+		forwarder = NewForwarderWithRoutes(cfg.Target, func(method, path string) (string, string, []string) {
+			return matchRoute(cfg.Routes, cfg.Target, method, path)
 		})
 	} else {
 		forwarder = NewForwarder(cfg.Target)
 	}
+	forwarder.hostHeader = cfg.HostHeader
+	forwarder.headerRules = headerRulesFor(cfg.Routes, cfg.Headers)
+	forwarder.maxResponseSize = cfg.MaxResponseSize
+	forwarder.clientIPHeader = cfg.ClientIPHeader
+	forwarder.bodyTransform = cfg.BodyTransform
+	if cfg.TargetTLS != nil {
+		forwarder.setTLSConfig(cfg.TargetTLS)
+	}
 
-	return &Client{
+	displayTarget := cfg.Target
+	if cfg.Echo {
+		displayTarget = "echo (webhooks are answered with a summary, not forwarded)"
+	}
+	c := &Client{
 		config:    cfg,
 		forwarder: forwarder,
-		display:   NewDisplay(cfg.Target, cfg.Verbose),
+		display:   NewDisplay(displayTarget, cfg.Verbose, cfg.BodyDisplayLimit),
 	}
+	forwarder.onMirror = c.display.LogMirror
+	forwarder.onTransformError = c.display.LogTransformError
+	for _, t := range cfg.Tunnels {
+		f := NewForwarder(t.Target)
+		f.hostHeader = cfg.HostHeader
+		f.headerRules = headerRulesFor(nil, cfg.Headers)
+		f.maxResponseSize = cfg.MaxResponseSize
+		f.clientIPHeader = cfg.ClientIPHeader
+		f.bodyTransform = cfg.BodyTransform
+		f.onTransformError = c.display.LogTransformError
+		if cfg.TargetTLS != nil {
+			f.setTLSConfig(cfg.TargetTLS)
+		}
+		c.named = append(c.named, &namedTunnel{cfg: t, forwarder: f})
+	}
+	return c
 }
 
-// matchRoute finds the best matching route for a path
-func matchRoute(routes []Route, defaultTarget, path string) string {
+// matchRoute finds the best matching route for a request and returns its
+// target, the path to forward and its mirror targets
+func matchRoute(routes []Route, defaultTarget, method, path string) (string, string, []string) {
+	if route, ok := bestRoute(routes, method, path); ok {
+		return route.Target, rewritePath(route, path), route.Mirrors
+	}
+	return defaultTarget, path, nil
+}
+
+// bestRoute returns the route with the longest prefix of path that accepts
+// method. Between routes with the same prefix, one listing the method wins
+// over one that accepts any method, then the first listed.
+func bestRoute(routes []Route, method, path string) (Route, bool) {
 	var bestMatch Route
 	bestLen := -1
 
 	for _, route := range routes {
-		if strings.HasPrefix(path, route.Path) && len(route.Path) > bestLen {
+		if !strings.HasPrefix(path, route.Path) || !route.matchesMethod(method) {
+			continue
+		}
+		if len(route.Path) > bestLen || (len(route.Path) == bestLen && len(bestMatch.Methods) == 0 && len(route.Methods) > 0) {
 			bestMatch = route
 			bestLen = len(route.Path)
 		}
 	}
+	return bestMatch, bestLen >= 0
+}
+
+// matchesMethod reports whether the route accepts a request method
+func (r Route) matchesMethod(method string) bool {
+	if len(r.Methods) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.Methods, func(m string) bool { return strings.EqualFold(m, method) })
+}
+
+// headerRulesFor returns the header edits for each request path: the global
+// rules, overridden by those of the matching route. It returns nil when
+// there are none.
+func headerRulesFor(routes []Route, global HeaderRules) func(method, path string) HeaderRules {
+	hasRouteRules := false
+	for _, r := range routes {
+		hasRouteRules = hasRouteRules || !r.Headers.empty()
+	}
+	if global.empty() && !hasRouteRules {
+		return nil
+	}
+	return func(method, path string) HeaderRules {
+		if route, ok := bestRoute(routes, method, path); ok {
+			return global.merge(route.Headers)
+		}
+		return global
+	}
+}
+
+// rewritePath strips or replaces a matched route prefix. The remainder
+// (including any query string) is kept as is.
+func rewritePath(route Route, path string) string {
+	if !route.StripPrefix && route.Rewrite == "" {
+		return path
+	}
+	rest := path[len(route.Path):]
+	prefix := strings.TrimSuffix(route.Rewrite, "/")
+	if rest == "" || rest[0] == '?' {
+		if prefix == "" {
+			prefix = "/"
+		}
+		return prefix + rest
+	}
+	if rest[0] != '/' {
+		rest = "/" + rest
+	}
+	return prefix + rest
+}
+
+// RelayTLSConfig builds the TLS settings for the connection to an https://
+// relay server, like TargetTLSConfig does for targets. It returns nil when
+// neither is set.
+func RelayTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	return loadTLSConfig("relay", caFile, insecure)
+}
 
-	if bestLen >= 0 {
-		return bestMatch.Target
+// ParseProxy parses a --proxy URL. The tunnel connection can go through an
+// HTTP CONNECT or SOCKS5 proxy; a bare host:port means HTTP.
+func ParseProxy(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
 	}
-	return defaultTarget
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q", raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "socks5" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http:// or socks5://)", u.Scheme)
+	}
+	return u, nil
 }
 
 // Run connects to the server and starts forwarding requests
 func (c *Client) Run(ctx context.Context) error {
+	if c.config.RelayTLS != nil && c.config.RelayTLS.InsecureSkipVerify {
+		log.Printf("warning: not verifying the relay server's certificate (--insecure); the connection can be intercepted")
+	}
+
+	if c.config.SummaryInterval > 0 && !c.config.TUIMode {
+		go c.printSummaries(ctx)
+	}
+
 	attempt := 0
 	delay := reconnectDelay
 
@@ -106,8 +370,14 @@ func (c *Client) Run(ctx context.Context) error {
 		err := c.connect(ctx)
 		if err != nil {
 			c.display.LogDisconnected(err)
+			if fatal(err) {
+				return err
+			}
 
 			attempt++
+			if limit := c.config.MaxReconnects; limit > 0 && attempt > limit {
+				return fmt.Errorf("giving up after %d failed reconnect attempts: %w", limit, err)
+			}
 			c.display.LogReconnecting(attempt)
 
 			select {
@@ -123,13 +393,18 @@ func (c *Client) Run(ctx context.Context) error {
 		attempt = 0
 		delay = reconnectDelay
 
+		c.connected.Store(true)
 		err = c.runLoop(ctx)
+		c.connected.Store(false)
 		if err != nil {
 			c.display.LogDisconnected(err)
 
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
+			if fatal(err) {
+				return err
+			}
 
 			// Reconnect
 			c.display.LogReconnecting(1)
@@ -160,18 +435,52 @@ func (c *Client) connect(ctx context.Context) error {
 
 	// Connect
 	dialer := websocket.Dialer{
-		HandshakeTimeout: 10 * time.Second,
+		HandshakeTimeout:  10 * time.Second,
+		EnableCompression: !c.config.DisableWSCompression,
+		TLSClientConfig:   c.config.RelayTLS,
+		Proxy:             http.ProxyFromEnvironment,
+		Subprotocols:      []string{protocol.Subprotocol},
+	}
+	if c.config.Proxy != nil {
+		dialer.Proxy = http.ProxyURL(c.config.Proxy)
 	}
-	conn, _, err := dialer.DialContext(ctx, u.String(), nil)
+	// The token goes on the upgrade too, so the server can refuse a bad one
+	// before accepting the connection; older servers read it from the
+	// register message
+	header := http.Header{}
+	if c.config.Token != "" {
+		header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
 	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return &ServerError{Code: protocol.ErrCodeUnauthorized, Message: "invalid or missing auth token"}
+		}
 		return fmt.Errorf("failed to connect: %w", err)
 	}
+	// Favor latency over ratio; no-op unless the server accepted compression
+	conn.SetCompressionLevel(flate.BestSpeed)
 	c.conn = conn
 
 	// Send register message
 	regPayload := protocol.RegisterPayload{
 		TunnelID: c.config.TunnelID,
 		Token:    c.config.Token,
+		AsyncAck: c.config.AsyncAck,
+
+		Capabilities: []string{protocol.CapGzip, protocol.CapChunked},
+	}
+	if c.resumeToken != "" {
+		regPayload.TunnelID = c.tunnelID
+		regPayload.ResumeToken = c.resumeToken
+	}
+	for _, nt := range c.named {
+		spec := protocol.TunnelSpec{Name: nt.cfg.Name, TunnelID: nt.cfg.TunnelID}
+		if nt.resumeToken != "" {
+			spec.TunnelID = nt.id
+			spec.ResumeToken = nt.resumeToken
+		}
+		regPayload.Tunnels = append(regPayload.Tunnels, spec)
 	}
 	msg, _ := protocol.NewMessage(protocol.TypeRegister, regPayload)
 	data, _ := json.Marshal(msg)
@@ -185,6 +494,9 @@ func (c *Client) connect(ctx context.Context) error {
 	_, message, err := conn.ReadMessage()
 	if err != nil {
 		conn.Close()
+		if se := closeReason(err); se != nil {
+			return se
+		}
 		return fmt.Errorf("failed to read register response: %w", err)
 	}
 	conn.SetReadDeadline(time.Time{})
@@ -199,7 +511,7 @@ func (c *Client) connect(ctx context.Context) error {
 		var errPayload protocol.ErrorPayload
 		respMsg.ParsePayload(&errPayload)
 		conn.Close()
-		return fmt.Errorf("server error: %s", errPayload.Message)
+		return &ServerError{Code: errPayload.Code, Message: errPayload.Message}
 	}
 
 	if respMsg.Type != protocol.TypeRegistered {
@@ -213,16 +525,48 @@ func (c *Client) connect(ctx context.Context) error {
 		return fmt.Errorf("invalid registered payload: %w", err)
 	}
 
-	c.tunnelID = registered.TunnelID
-	c.publicURL = registered.PublicURL
-	c.display.LogConnected(c.tunnelID, c.publicURL)
+	c.gzip.Store(protocol.HasCapability(registered.Capabilities, protocol.CapGzip))
+	c.chunkSize.Store(0)
+	if protocol.HasCapability(registered.Capabilities, protocol.CapChunked) {
+		c.chunkSize.Store(int64(registered.ChunkSize))
+	}
+	c.serverWait.Store(registered.ResponseWaitMs)
+
+	target := c.config.Target
+	if len(c.named) > 0 {
+		if len(registered.Tunnels) != len(c.named) {
+			conn.Close()
+			return fmt.Errorf("server does not support multiple tunnels per connection")
+		}
+		target = c.applyMultiRegistration(&registered)
+	} else {
+		if c.tunnelID != "" && registered.TunnelID != c.tunnelID {
+			c.display.LogTunnelChanged()
+		}
+		c.tunnelID = registered.TunnelID
+		c.publicURL = registered.PublicURL
+		c.resumeToken = registered.ResumeToken
+		c.display.LogConnected(c.tunnelID, c.publicURL, registered.ConnectionID, registered.AsyncAck)
+		if registered.Buffered > 0 {
+			c.display.LogBuffered(registered.Buffered)
+		}
+	}
+	if registered.URLExpires > 0 {
+		c.display.LogURLExpires(time.Unix(registered.URLExpires, 0))
+	}
+	if faults := c.config.Chaos.String(); faults != "" {
+		c.display.LogChaos(faults)
+	}
+	if c.config.AsyncAck != 0 && registered.AsyncAck == 0 {
+		log.Printf("warning: server does not support async ack; webhooks wait for the target's response")
+	}
 
 	// Send connection info to TUI if enabled
 	if c.tuiConnCh != nil {
 		c.tuiConnCh <- tui.ConnectionInfo{
 			TunnelID:  c.tunnelID,
 			PublicURL: c.publicURL,
-			Target:    c.config.Target,
+			Target:    target,
 			ServerURL: c.config.ServerURL,
 			Token:     c.config.Token,
 			Connected: true,
@@ -232,6 +576,53 @@ func (c *Client) connect(ctx context.Context) error {
 	return nil
 }
 
+// applyMultiRegistration records the IDs assigned to each named tunnel and
+// returns a display summary of their targets
+func (c *Client) applyMultiRegistration(registered *protocol.RegisteredPayload) string {
+	byID := make(map[string]*Forwarder, len(c.named))
+	names := make(map[string]string, len(c.named))
+	shown := make([]ConnectedTunnel, 0, len(c.named))
+	targets := make([]string, 0, len(c.named))
+	buffered := 0
+
+	for i, rt := range registered.Tunnels {
+		nt := c.named[i]
+		if nt.id != "" && rt.TunnelID != nt.id {
+			c.display.LogTunnelChanged()
+		}
+		nt.id = rt.TunnelID
+		nt.resumeToken = rt.ResumeToken
+		byID[rt.TunnelID] = nt.forwarder
+		names[rt.TunnelID] = nt.cfg.Name
+		shown = append(shown, ConnectedTunnel{Name: nt.cfg.Name, PublicURL: rt.PublicURL, Target: nt.cfg.Target})
+		targets = append(targets, nt.cfg.Name+"="+nt.cfg.Target)
+		buffered += rt.Buffered
+	}
+
+	c.tunnelsMu.Lock()
+	c.byTunnelID = byID
+	c.tunnelsMu.Unlock()
+	c.display.SetTunnelNames(names)
+
+	c.tunnelID = registered.TunnelID
+	c.publicURL = registered.PublicURL
+	c.display.LogConnectedMulti(shown, registered.ConnectionID, registered.AsyncAck)
+	if buffered > 0 {
+		c.display.LogBuffered(buffered)
+	}
+	return strings.Join(targets, ", ")
+}
+
+// forwarderFor returns the forwarder for a request's tunnel
+func (c *Client) forwarderFor(tunnelID string) *Forwarder {
+	c.tunnelsMu.RLock()
+	defer c.tunnelsMu.RUnlock()
+	if f, ok := c.byTunnelID[tunnelID]; ok {
+		return f
+	}
+	return c.forwarder
+}
+
 // runLoop handles incoming messages
 func (c *Client) runLoop(ctx context.Context) error {
 	// Create a connection-scoped context that cancels when this connection ends
@@ -239,10 +630,38 @@ func (c *Client) runLoop(ctx context.Context) error {
 	connCtx, connCancel := context.WithCancel(ctx)
 	defer connCancel()
 
+	// On shutdown, close the connection right away instead of when the next
+	// message arrives, so the server moves traffic to other clients
+	stop := context.AfterFunc(ctx, func() {
+		c.writeMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		c.conn.Close()
+	})
+	defer stop()
+
+	// A half-open connection shows up as missing pongs; without a deadline,
+	// reads would block until TCP gives up
+	pongTimeout := c.config.PongTimeout
+	c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
 		return nil
 	})
+	go c.ping(connCtx, c.conn)
+
+	// Chunked request bodies being received on this connection
+	limit := c.config.MaxDecompressedBytes
+	if limit <= 0 {
+		limit = protocol.DefaultMaxDecompressedBytes
+	}
+	requests := protocol.NewReassembler[*protocol.HTTPRequest](limit)
+	handlers := newDispatcher(connCtx, c)
+
+	// Independent liveness detection via app-level pings
+	pongCh := make(chan struct{}, 1)
+	var heartbeatFailed atomic.Bool
+	if c.config.HeartbeatInterval > 0 {
+		go c.heartbeat(connCtx, c.conn, pongCh, &heartbeatFailed)
+	}
 
 	for {
 		select {
@@ -254,11 +673,25 @@ func (c *Client) runLoop(ctx context.Context) error {
 
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if heartbeatFailed.Load() {
+				return fmt.Errorf("heartbeat timeout: no pong within %s", c.config.HeartbeatTimeout)
+			}
+			if ne := net.Error(nil); errors.As(err, &ne) && ne.Timeout() {
+				return fmt.Errorf("connection dead: no pong within %s", pongTimeout)
+			}
+			if se := closeReason(err); se != nil {
+				return se
+			}
 			return fmt.Errorf("read error: %w", err)
 		}
+		c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
 
 		var msg protocol.Message
 		if err := json.Unmarshal(message, &msg); err != nil {
+			c.logParseError("message", message, err)
 			continue
 		}
 
@@ -266,9 +699,35 @@ func (c *Client) runLoop(ctx context.Context) error {
 		case protocol.TypeRequest:
 			var req protocol.HTTPRequest
 			if err := msg.ParsePayload(&req); err != nil {
+				c.logParseError("request", message, err)
+				continue
+			}
+			if req.Chunked {
+				if err := requests.Expect(req.ID, &req); err != nil {
+					c.rejectRequest(&req, http.StatusServiceUnavailable, err)
+				}
 				continue
 			}
-			go c.handleRequest(connCtx, &req)
+			handlers.dispatch(&req)
+
+		case protocol.TypeChunk:
+			var chunk protocol.BodyChunk
+			if err := msg.ParsePayload(&chunk); err != nil {
+				c.logParseError("chunk", message, err)
+				continue
+			}
+			req, body, done, err := requests.Add(&chunk)
+			switch {
+			case err != nil && req == nil:
+				c.logParseError("chunk", message, err)
+			case errors.Is(err, protocol.ErrBodyTooLarge):
+				c.rejectRequest(req, http.StatusRequestEntityTooLarge, err)
+			case err != nil:
+				c.rejectRequest(req, http.StatusBadRequest, err)
+			case done:
+				req.Body, req.Chunked = body, false
+				handlers.dispatch(req)
+			}
 
 		case protocol.TypePing:
 			// Respond with pong
@@ -277,18 +736,173 @@ func (c *Client) runLoop(ctx context.Context) error {
 			if err := c.writeMessage(websocket.TextMessage, data); err != nil {
 				return fmt.Errorf("pong write error: %w", err)
 			}
+
+		case protocol.TypePong:
+			select {
+			case pongCh <- struct{}{}:
+			default:
+			}
+
+		case protocol.TypeError:
+			var errPayload protocol.ErrorPayload
+			msg.ParsePayload(&errPayload)
+			if errPayload.Code == protocol.ErrCodeIdleTimeout {
+				c.conn.Close()
+				return fmt.Errorf("%w: %s", ErrIdleTimeout, errPayload.Message)
+			}
+			if protocol.FatalErrorCode(errPayload.Code) {
+				c.conn.Close()
+				return &ServerError{Code: errPayload.Code, Message: errPayload.Message}
+			}
 		}
 	}
 }
 
+// logParseError counts and reports a malformed frame from the server
+func (c *Client) logParseError(what string, frame []byte, err error) {
+	n := c.parseErrors.Add(1)
+	c.display.LogProtocolError(fmt.Errorf("failed to parse %s (%d bytes): %w", what, len(frame), err), n)
+	if c.config.Verbose {
+		log.Printf("[debug] frame: %s", protocol.DumpFrame(frame, 256))
+	}
+}
+
+// UnexpectedStatuses returns how many target responses fell outside ExpectStatus
+func (c *Client) UnexpectedStatuses() int64 {
+	return c.unexpected.Load()
+}
+
+// ParseErrors returns the number of malformed frames received from the server
+func (c *Client) ParseErrors() int64 {
+	return c.parseErrors.Load()
+}
+
+// ping sends WebSocket pings every PingInterval; the pongs extend the read
+// deadline set in runLoop
+func (c *Client) ping(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(c.config.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// heartbeat sends periodic app-level pings and closes the connection if the
+// server doesn't answer within HeartbeatTimeout, forcing a reconnect
+func (c *Client) heartbeat(ctx context.Context, conn *websocket.Conn, pongCh <-chan struct{}, failed *atomic.Bool) {
+	ticker := time.NewTicker(c.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	pingMsg, _ := protocol.NewMessage(protocol.TypePing, nil)
+	data, _ := json.Marshal(pingMsg)
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		// Discard any stale pong from a previous round
+		select {
+		case <-pongCh:
+		default:
+		}
+
+		if err := c.writeMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+
+		timer := time.NewTimer(c.config.HeartbeatTimeout)
+		select {
+		case <-pongCh:
+			timer.Stop()
+		case <-timer.C:
+			failed.Store(true)
+			conn.Close()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// forwardTimeout is how long a forward may take, retries included. It ends a
+// second before the server stops waiting, so a forward never outlives the
+// webhook it answers.
+func (c *Client) forwardTimeout() time.Duration {
+	wait := time.Duration(c.serverWait.Load()) * time.Millisecond
+	if wait <= 0 {
+		wait = protocol.ResponseTimeout
+	}
+	return min(c.config.TargetTimeout, wait-time.Second)
+}
+
 // handleRequest forwards a request to the local target
 func (c *Client) handleRequest(ctx context.Context, req *protocol.HTTPRequest) {
+	if req.BodyEncoding != "" {
+		body, err := protocol.DecompressBody(req.Body, req.BodyEncoding, c.config.MaxDecompressedBytes)
+		switch {
+		case errors.Is(err, protocol.ErrDecompressedTooLarge):
+			// Forward what fits, flagged so the target knows it is incomplete
+			c.display.LogTruncated(req, int64(len(body)))
+			req.Headers = protocol.MarkTruncated(req.Headers, int64(len(body)))
+		case err != nil:
+			c.rejectRequest(req, http.StatusBadRequest, err)
+			return
+		}
+		req.Body, req.BodyEncoding = body, ""
+	}
+
+	// Paths this client doesn't handle are answered without forwarding
+	if !c.config.Paths.accepts(req.Path) {
+		resp := c.config.Paths.filteredResponse(req)
+		c.display.LogFiltered(req, resp.StatusCode)
+		c.sendResponse(req, resp)
+		return
+	}
+
 	c.display.LogRequest(req)
 
 	start := time.Now()
 
-	// Forward the request
-	resp, err := c.forwarder.Forward(ctx, req)
+	fwdCtx, cancel := context.WithTimeout(ctx, c.forwardTimeout())
+	defer cancel()
+
+	// Forward the request, unless chaos testing drops it or a mock answers it
+	var target string
+	var resp *protocol.HTTPResponse
+	var err error
+	mock := c.findMock(req)
+	dropped := c.config.Chaos.drop()
+	if !dropped {
+		err = c.config.Chaos.wait(fwdCtx)
+	}
+	switch {
+	case dropped:
+		target = ChaosTarget
+		resp = droppedResponse(req)
+	case err != nil:
+		// The injected delay outlasted the forward timeout
+	case mock != nil:
+		target = MockTarget
+		resp, err = mockResponse(fwdCtx, req, mock)
+	case c.config.Echo:
+		target = EchoTarget
+		resp = echoResponse(req)
+	default:
+		forwarder := c.forwarderFor(req.TunnelID)
+		target, _, _ = forwarder.resolveTarget(req.Method, req.Path)
+		resp, err = c.forward(fwdCtx, forwarder, req)
+	}
 	duration := time.Since(start)
 
 	var errMsg string
@@ -299,12 +913,26 @@ func (c *Client) handleRequest(ctx context.Context, req *protocol.HTTPRequest) {
 		resp = &protocol.HTTPResponse{
 			RequestID:  req.ID,
 			StatusCode: 502,
-			Headers:    map[string]string{"Content-Type": "text/plain"},
+			Headers:    protocol.Headers{"Content-Type": {"text/plain"}},
 			Body:       []byte(fmt.Sprintf("Failed to forward: %v", err)),
+			Target:     target,
+			Error:      errMsg,
 		}
 	} else {
 		c.display.LogResponse(req, resp, duration)
 	}
+	c.summary.record(duration, err != nil)
+
+	// Contract check on target status codes (mocks, echo and chaos drops are exempt)
+	unexpected := err == nil && mock == nil && !dropped && !c.config.Echo && !statusExpected(c.config.ExpectStatus, resp.StatusCode)
+	if unexpected {
+		n := c.unexpected.Add(1)
+		c.display.LogUnexpectedStatus(req, resp.StatusCode, n)
+	}
+
+	if c.config.RequestLog != nil {
+		c.config.RequestLog.Log(req, resp, duration, errMsg)
+	}
 
 	// Send to TUI if enabled
 	if c.tuiRequestCh != nil {
@@ -313,29 +941,86 @@ func (c *Client) handleRequest(ctx context.Context, req *protocol.HTTPRequest) {
 			Method:     req.Method,
 			Path:       req.Path,
 			StatusCode: resp.StatusCode,
+			StatusText: resp.StatusText,
 			Duration:   duration,
 			Timestamp:  time.Now(),
-			ReqHeaders: req.Headers,
+			ReqHeaders: http.Header(req.Headers),
 			ReqBody:    req.Body,
-			ResHeaders: resp.Headers,
+			ResHeaders: http.Header(resp.Headers),
 			ResBody:    resp.Body,
 			Error:      errMsg,
+			BodyHash:   bodyHash(req.Body),
+			Target:     resp.Target,
+			Unexpected: unexpected,
+			Verified:   req.Verified,
 		}
 		select {
 		case c.tuiRequestCh <- tuiReq:
 		default:
 			// Channel full - log at debug level to avoid spam
 			// This indicates TUI can't keep up with request volume
-			log.Printf("[debug] TUI channel full, dropped request display for %s %s", req.Method, req.Path)
+			log.Printf("[debug] [%s] TUI channel full, dropped request display for %s %s", req.ID, req.Method, req.Path)
+		}
+	}
+
+	c.sendResponse(req, resp)
+}
+
+// rejectRequest answers a request whose body couldn't be decoded without
+// forwarding it
+func (c *Client) rejectRequest(req *protocol.HTTPRequest, status int, err error) {
+	c.display.LogError(req, fmt.Errorf("bad request body: %w", err))
+	c.sendResponse(req, &protocol.HTTPResponse{
+		RequestID:  req.ID,
+		StatusCode: status,
+		Headers:    protocol.Headers{"Content-Type": {"text/plain"}},
+		Body:       []byte(fmt.Sprintf("Failed to decode request body: %v", err)),
+	})
+}
+
+// sendResponse sends a response back to the server, compressing large
+// bodies when the server supports it and splitting them into chunks past
+// the server's chunk size
+func (c *Client) sendResponse(req *protocol.HTTPRequest, resp *protocol.HTTPResponse) {
+	if c.gzip.Load() {
+		if body, enc := protocol.CompressBody(resp.Body); enc != "" {
+			wire := *resp
+			wire.Body, wire.BodyEncoding = body, enc
+			resp = &wire
 		}
 	}
 
-	// Send response back
+	var chunks []protocol.BodyChunk
+	if size := int(c.chunkSize.Load()); size > 0 && len(resp.Body) > size {
+		wire := *resp
+		chunks = protocol.SplitBody(resp.RequestID, wire.Body, size)
+		wire.Body, wire.Chunked = nil, true
+		resp = &wire
+	}
+
 	msg, _ := protocol.NewMessage(protocol.TypeResponse, resp)
 	data, _ := json.Marshal(msg)
 	if err := c.writeMessage(websocket.TextMessage, data); err != nil {
 		c.display.LogError(req, fmt.Errorf("failed to send response: %w", err))
+		return
+	}
+	for i := range chunks {
+		chunkMsg, _ := protocol.NewMessage(protocol.TypeChunk, &chunks[i])
+		data, _ := json.Marshal(chunkMsg)
+		if err := c.writeMessage(websocket.TextMessage, data); err != nil {
+			c.display.LogError(req, fmt.Errorf("failed to send response: %w", err))
+			return
+		}
+	}
+}
+
+// bodyHash returns the first 8 hex chars of the body's sha256 (empty for no body)
+func bodyHash(body []byte) string {
+	if len(body) == 0 {
+		return ""
 	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:4])
 }
 
 // GetTunnelID returns the current tunnel ID
@@ -368,6 +1053,8 @@ func (c *Client) writeMessage(messageType int, data []byte) error {
 		return fmt.Errorf("connection is nil")
 	}
 
+	// Small frames aren't worth deflating
+	c.conn.EnableWriteCompression(len(data) >= protocol.CompressThreshold)
 	if err := c.conn.WriteMessage(messageType, data); err != nil {
 		log.Printf("websocket write error: %v", err)
 		return err
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// CurlCommand returns a shell command that re-sends req to target the way
+// the forwarder would. Binary bodies are piped in from base64.
+func CurlCommand(req *protocol.HTTPRequest, target string) (string, error) {
+	fullURL, err := buildURL(target, req.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to build URL: %w", err)
+	}
+
+	// One flag per line for readability
+	lines := []string{"curl -X " + shellQuote(req.Method) + " " + shellQuote(fullURL)}
+
+	names := make([]string, 0, len(req.Headers))
+	for k := range req.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		// curl sets these itself for the new connection
+		if isHopByHop(k) || http.CanonicalHeaderKey(k) == "Host" || http.CanonicalHeaderKey(k) == "Content-Length" {
+			continue
+		}
+		for _, v := range req.Headers[k] {
+			lines = append(lines, "-H "+shellQuote(k+": "+v))
+		}
+	}
+
+	prefix := ""
+	if len(req.Body) > 0 {
+		if utf8.Valid(req.Body) && !strings.ContainsRune(string(req.Body), 0) {
+			lines = append(lines, "--data-binary "+shellQuote(string(req.Body)))
+		} else {
+			prefix = "echo " + base64.StdEncoding.EncodeToString(req.Body) + " | base64 -d | "
+			lines = append(lines, "--data-binary @-")
+		}
+	}
+
+	return prefix + strings.Join(lines, " \\\n  "), nil
+}
+
+// shellQuote quotes s for a POSIX shell, leaving simple words unquoted
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
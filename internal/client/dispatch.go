@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// maxQueued caps requests waiting for a handler, per tunnel in ordered mode
+// and per connection with MaxConcurrency; further requests are rejected
+const maxQueued = 256
+
+// dispatcher starts the handling of each request received on a connection.
+// By default every request gets its own goroutine. With MaxConcurrency, a
+// fixed pool of workers takes requests from a queue. In ordered mode each
+// tunnel's requests are handled one at a time, in the order they arrived,
+// and MaxConcurrency bounds how many tunnels are handled at once.
+type dispatcher struct {
+	c       *Client
+	ctx     context.Context // Connection-scoped
+	ordered bool
+
+	pool  chan *protocol.HTTPRequest // Shared queue of the worker pool (nil = unbounded)
+	slots chan struct{}              // Held while handling, in ordered mode with MaxConcurrency
+
+	mu     sync.Mutex
+	queues map[string]chan *protocol.HTTPRequest // Tunnel ID -> pending requests (ordered mode)
+}
+
+func newDispatcher(ctx context.Context, c *Client) *dispatcher {
+	d := &dispatcher{
+		c:       c,
+		ctx:     ctx,
+		ordered: c.config.Ordered,
+		queues:  make(map[string]chan *protocol.HTTPRequest),
+	}
+	if n := c.config.MaxConcurrency; n > 0 {
+		if d.ordered {
+			d.slots = make(chan struct{}, n)
+		} else {
+			d.pool = make(chan *protocol.HTTPRequest, maxQueued)
+			for range n {
+				go d.work(d.pool)
+			}
+		}
+	}
+	return d
+}
+
+// dispatch hands req to a handler without blocking the read loop
+func (d *dispatcher) dispatch(req *protocol.HTTPRequest) {
+	q := d.pool
+	switch {
+	case d.ordered:
+		d.mu.Lock()
+		var ok bool
+		if q, ok = d.queues[req.TunnelID]; !ok {
+			q = make(chan *protocol.HTTPRequest, maxQueued)
+			d.queues[req.TunnelID] = q
+			go d.work(q)
+		}
+		d.mu.Unlock()
+	case q == nil:
+		go d.c.handleRequest(d.ctx, req)
+		return
+	}
+
+	select {
+	case q <- req:
+	default:
+		d.c.rejectRequest(req, http.StatusServiceUnavailable,
+			fmt.Errorf("request queue full (%d requests waiting)", maxQueued))
+	}
+}
+
+// work handles requests from q one at a time until the connection ends.
+// Requests still queued then are dropped; the server has already failed
+// them along with the connection.
+func (d *dispatcher) work(q <-chan *protocol.HTTPRequest) {
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case req := <-q:
+			if !d.acquire() {
+				return
+			}
+			d.c.handleRequest(d.ctx, req)
+			d.release()
+		}
+	}
+}
+
+// acquire waits for a free slot in ordered mode with MaxConcurrency. It
+// returns false if the connection ends first.
+func (d *dispatcher) acquire() bool {
+	if d.slots == nil {
+		return true
+	}
+	select {
+	case d.slots <- struct{}{}:
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
+
+func (d *dispatcher) release() {
+	if d.slots != nil {
+		<-d.slots
+	}
+}
@@ -2,16 +2,17 @@ package client
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"sync"
 	"time"
-	"unicode/utf8"
 
 	"github.com/fatih/color"
 	"github.com/lance0/hookshot/internal/protocol"
 )
 
 const (
-	maxBodyDisplay = 500 // Max chars to display for body
+	defaultBodyDisplayLimit = 500 // Default max chars to display for body
 )
 
 var (
@@ -38,17 +39,45 @@ var (
 	arrowColor  = color.New(color.FgCyan)
 	idColor     = color.New(color.FgHiBlack)
 	bodyColor   = color.New(color.FgHiBlack)
+	labelColor  = color.New(color.FgMagenta)
+	signedColor = color.New(color.FgGreen)
 )
 
+// SetColor decides whether output is colorized. By default color is used
+// only when stdout is a terminal and NO_COLOR is unset. FORCE_COLOR turns it
+// on regardless, and disable (--no-color) turns it off over everything.
+func SetColor(disable bool) {
+	switch force := os.Getenv("FORCE_COLOR"); {
+	case disable:
+		color.NoColor = true
+	case force != "" && force != "0" && force != "false":
+		color.NoColor = false
+	}
+}
+
 // Display handles request/response logging
 type Display struct {
-	target  string
-	verbose bool
+	target    string
+	verbose   bool
+	bodyLimit int // Max body chars shown in verbose mode
+
+	labelsMu sync.RWMutex
+	labels   map[string]string // tunnelID -> name, for multi-tunnel clients
 }
 
-// NewDisplay creates a new display
-func NewDisplay(target string, verbose bool) *Display {
-	return &Display{target: target, verbose: verbose}
+// NewDisplay creates a new display (bodyLimit <= 0 uses the default)
+func NewDisplay(target string, verbose bool, bodyLimit int) *Display {
+	if bodyLimit <= 0 {
+		bodyLimit = defaultBodyDisplayLimit
+	}
+	return &Display{target: target, verbose: verbose, bodyLimit: bodyLimit}
+}
+
+// SetTunnelNames labels request lines with tunnel names (multi-tunnel mode)
+func (d *Display) SetTunnelNames(names map[string]string) {
+	d.labelsMu.Lock()
+	defer d.labelsMu.Unlock()
+	d.labels = names
 }
 
 // LogRequest logs an incoming request
@@ -60,18 +89,31 @@ func (d *Display) LogRequest(req *protocol.HTTPRequest) {
 		methodColor = defaultMethodColor
 	}
 
+	d.labelsMu.RLock()
+	label := d.labels[req.TunnelID]
+	d.labelsMu.RUnlock()
+	arrow := arrowColor.Sprint("→")
+	if label != "" {
+		arrow += " " + labelColor.Sprintf("[%s]", label)
+	}
+
+	id := idColor.Sprintf("(%s)", req.ID)
+	if req.Verified {
+		id += " " + signedColor.Sprint("✓ signed")
+	}
+
 	// Format: [15:04:05] → POST /webhooks/stripe (abc123)
 	fmt.Printf("%s %s %s %s %s\n",
 		dimColor.Sprintf("[%s]", timestamp),
-		arrowColor.Sprint("→"),
+		arrow,
 		methodColor.Sprintf("%-7s", req.Method),
 		req.Path,
-		idColor.Sprintf("(%s)", req.ID),
+		id,
 	)
 
 	// Show body in verbose mode
 	if d.verbose && len(req.Body) > 0 {
-		d.logBody("   req", req.Body)
+		d.logBody("   req", req.Body, req.Headers)
 	}
 }
 
@@ -84,17 +126,18 @@ func (d *Display) LogResponse(req *protocol.HTTPRequest, resp *protocol.HTTPResp
 		statusColor = defaultStatusColor
 	}
 
-	// Format: [15:04:05] ← 200 OK (15ms)
-	fmt.Printf("%s %s %s %s\n",
+	// Format: [15:04:05] ← 200 OK (15ms) (abc123)
+	fmt.Printf("%s %s %s %s %s\n",
 		dimColor.Sprintf("[%s]", timestamp),
 		arrowColor.Sprint("←"),
-		statusColor.Sprintf("%d", resp.StatusCode),
+		statusColor.Sprint(resp.Status()),
 		dimColor.Sprintf("(%s)", formatDuration(duration)),
+		idColor.Sprintf("(%s)", req.ID),
 	)
 
 	// Show body in verbose mode
 	if d.verbose && len(resp.Body) > 0 {
-		d.logBody("   res", resp.Body)
+		d.logBody("   res", resp.Body, resp.Headers)
 	}
 }
 
@@ -102,26 +145,220 @@ func (d *Display) LogResponse(req *protocol.HTTPRequest, resp *protocol.HTTPResp
 func (d *Display) LogError(req *protocol.HTTPRequest, err error) {
 	timestamp := time.Now().Format("15:04:05")
 
-	fmt.Printf("%s %s %s\n",
+	fmt.Printf("%s %s %s %s\n",
 		dimColor.Sprintf("[%s]", timestamp),
 		color.RedString("✗"),
 		color.RedString("error: %v", err),
+		idColor.Sprintf("(%s)", req.ID),
+	)
+}
+
+// LogFiltered logs a request rejected by the path filter. Shown only in
+// verbose mode, since filters are often there to silence noisy probes.
+func (d *Display) LogFiltered(req *protocol.HTTPRequest, status int) {
+	if !d.verbose {
+		return
+	}
+	timestamp := time.Now().Format("15:04:05")
+
+	// Format: [15:04:05] ⊘ GET /health filtered 404 (abc123)
+	fmt.Printf("%s %s %s %s\n",
+		dimColor.Sprintf("[%s]", timestamp),
+		dimColor.Sprint("⊘"),
+		dimColor.Sprintf("%s %s filtered %d", req.Method, req.Path, status),
+		idColor.Sprintf("(%s)", req.ID),
+	)
+}
+
+// LogMirror logs the result of a request copied to a mirror target
+func (d *Display) LogMirror(req *protocol.HTTPRequest, target string, resp *protocol.HTTPResponse, err error, duration time.Duration) {
+	timestamp := time.Now().Format("15:04:05")
+
+	var result string
+	if err != nil {
+		result = color.RedString("error: %v", err)
+	} else {
+		statusColor := statusColors[resp.StatusCode/100]
+		if statusColor == nil {
+			statusColor = defaultStatusColor
+		}
+		result = statusColor.Sprintf("%d", resp.StatusCode) + " " + dimColor.Sprintf("(%s)", formatDuration(duration))
+	}
+
+	// Format: [15:04:05] ⇉ mirror http://localhost:4000 200 (3ms) (abc123)
+	fmt.Printf("%s %s %s %s %s\n",
+		dimColor.Sprintf("[%s]", timestamp),
+		arrowColor.Sprint("⇉"),
+		dimColor.Sprintf("mirror %s", target),
+		result,
+		idColor.Sprintf("(%s)", req.ID),
+	)
+}
+
+// LogRetry logs that a forward is about to be retried
+func (d *Display) LogRetry(req *protocol.HTTPRequest, reason string, attempt, maxAttempts int, delay time.Duration) {
+	timestamp := time.Now().Format("15:04:05")
+
+	fmt.Printf("%s %s %s %s\n",
+		dimColor.Sprintf("[%s]", timestamp),
+		color.YellowString("↻"),
+		color.YellowString("%s, retrying in %s (attempt %d/%d)", reason, delay, attempt, maxAttempts),
+		idColor.Sprintf("(%s)", req.ID),
+	)
+}
+
+// LogTransformError warns that a request body couldn't be transformed and
+// is forwarded as received
+func (d *Display) LogTransformError(req *protocol.HTTPRequest, err error) {
+	timestamp := time.Now().Format("15:04:05")
+
+	fmt.Printf("%s %s %s %s\n",
+		dimColor.Sprintf("[%s]", timestamp),
+		color.YellowString("⚠"),
+		color.YellowString("%v; forwarding the original body", err),
+		idColor.Sprintf("(%s)", req.ID),
+	)
+}
+
+// LogTruncated warns that a request body inflated past the decompression
+// limit and is forwarded cut short
+func (d *Display) LogTruncated(req *protocol.HTTPRequest, limit int64) {
+	timestamp := time.Now().Format("15:04:05")
+
+	fmt.Printf("%s %s %s %s\n",
+		dimColor.Sprintf("[%s]", timestamp),
+		color.YellowString("⚠"),
+		color.YellowString("body exceeds --max-decompressed-bytes; forwarding the first %d bytes", limit),
+		idColor.Sprintf("(%s)", req.ID),
+	)
+}
+
+// LogUnexpectedStatus warns that the target returned a status outside expect_status
+func (d *Display) LogUnexpectedStatus(req *protocol.HTTPRequest, status int, total int64) {
+	timestamp := time.Now().Format("15:04:05")
+
+	fmt.Printf("%s %s %s %s\n",
+		dimColor.Sprintf("[%s]", timestamp),
+		color.New(color.FgHiRed, color.Bold).Sprint("!"),
+		color.New(color.FgHiRed, color.Bold).Sprintf("unexpected status %d for %s %s", status, req.Method, req.Path),
+		idColor.Sprintf("(%s, %d total)", req.ID, total),
+	)
+}
+
+// LogSummary logs the periodic summary of forwarded requests
+func (d *Display) LogSummary(s Summary, connected bool) {
+	timestamp := time.Now().Format("15:04:05")
+
+	state := color.GreenString("connected")
+	if !connected {
+		state = color.YellowString("disconnected")
+	}
+	failed := dimColor.Sprint("0 failed")
+	if s.Failed > 0 {
+		failed = color.RedString("%d failed", s.Failed)
+	}
+	avg := "-"
+	if s.Forwarded > 0 {
+		avg = formatDuration(s.Average())
+	}
+
+	// Format: [15:04:05] ≡ connected: 12 forwarded, 1 failed, avg 34ms in the last 1m0s (340 total)
+	fmt.Printf("%s %s %s: %d forwarded, %s, avg %s %s\n",
+		dimColor.Sprintf("[%s]", timestamp),
+		arrowColor.Sprint("≡"),
+		state,
+		s.Forwarded,
+		failed,
+		avg,
+		dimColor.Sprintf("in the last %s (%d total)", s.Interval.Round(time.Second), s.Total),
+	)
+}
+
+// LogProtocolError logs a malformed message from the server
+func (d *Display) LogProtocolError(err error, total int64) {
+	timestamp := time.Now().Format("15:04:05")
+
+	fmt.Printf("%s %s %s %s\n",
+		dimColor.Sprintf("[%s]", timestamp),
+		color.YellowString("⚠"),
+		color.YellowString("protocol error: %v", err),
+		dimColor.Sprintf("(%d total)", total),
 	)
 }
 
 // LogConnected logs successful connection
-func (d *Display) LogConnected(tunnelID, publicURL string) {
+func (d *Display) LogConnected(tunnelID, publicURL, connID string, asyncAck int) {
 	fmt.Println()
 	color.Green("✓ Connected!")
 	fmt.Println()
 	fmt.Printf("  Tunnel ID:  %s\n", color.CyanString(tunnelID))
+	if connID != "" {
+		fmt.Printf("  Connection: %s\n", dimColor.Sprint(connID))
+	}
 	fmt.Printf("  Public URL: %s\n", color.CyanString(publicURL))
 	fmt.Printf("  Forwarding: %s\n", color.CyanString(d.target))
+	if asyncAck != 0 {
+		fmt.Printf("  Async ack:  %s\n", color.YellowString("%d (senders don't see target responses)", asyncAck))
+	}
 	fmt.Println()
 	fmt.Println(dimColor.Sprint("  Waiting for requests..."))
 	fmt.Println(strings.Repeat("─", 50))
 }
 
+// ConnectedTunnel describes one tunnel of a multi-tunnel client for display
+type ConnectedTunnel struct {
+	Name      string
+	PublicURL string
+	Target    string
+}
+
+// LogConnectedMulti logs a successful multi-tunnel connection
+func (d *Display) LogConnectedMulti(tunnels []ConnectedTunnel, connID string, asyncAck int) {
+	fmt.Println()
+	color.Green("✓ Connected! (%d tunnels)", len(tunnels))
+	fmt.Println()
+	if connID != "" {
+		fmt.Printf("  Connection: %s\n", dimColor.Sprint(connID))
+	}
+	width := 0
+	for _, t := range tunnels {
+		width = max(width, len(t.Name))
+	}
+	for _, t := range tunnels {
+		fmt.Printf("  %s %s %s %s\n",
+			labelColor.Sprintf("%-*s", width, t.Name),
+			color.CyanString(t.PublicURL),
+			arrowColor.Sprint("→"),
+			color.CyanString(t.Target))
+	}
+	if asyncAck != 0 {
+		fmt.Printf("  Async ack:  %s\n", color.YellowString("%d (senders don't see target responses)", asyncAck))
+	}
+	fmt.Println()
+	fmt.Println(dimColor.Sprint("  Waiting for requests..."))
+	fmt.Println(strings.Repeat("─", 50))
+}
+
+// LogTunnelChanged warns that the server assigned a different tunnel ID on reconnect
+func (d *Display) LogTunnelChanged() {
+	color.Yellow("⚠ Tunnel ID changed on reconnect; update your webhook URL")
+}
+
+// LogBuffered notes webhooks the server buffered while we were disconnected
+func (d *Display) LogBuffered(n int) {
+	color.Yellow("  Delivering %d webhooks buffered while disconnected", n)
+}
+
+// LogURLExpires notes when the server's signed public URLs stop working
+func (d *Display) LogURLExpires(at time.Time) {
+	color.Yellow("  Public URL expires at %s; reconnecting issues a new one", at.Format("2006-01-02 15:04:05"))
+}
+
+// LogChaos warns that chaos testing is delaying or failing requests
+func (d *Display) LogChaos(faults string) {
+	color.Yellow("  Chaos testing: %s", faults)
+}
+
 // LogDisconnected logs disconnection
 func (d *Display) LogDisconnected(err error) {
 	if err != nil {
@@ -147,10 +384,15 @@ func formatDuration(d time.Duration) string {
 }
 
 // logBody logs a truncated body with prefix
-func (d *Display) logBody(prefix string, body []byte) {
-	// Only display if it looks like text
-	if !isTextBody(body) {
-		fmt.Printf("%s %s\n", bodyColor.Sprint(prefix), dimColor.Sprintf("[binary %d bytes]", len(body)))
+func (d *Display) logBody(prefix string, body []byte, headers protocol.Headers) {
+	// Only display if it is text
+	contentType := headers.Get("Content-Type")
+	if !protocol.IsTextBody(body, contentType, headers.Get("Content-Encoding")) {
+		label := fmt.Sprintf("[binary %d bytes]", len(body))
+		if contentType != "" {
+			label = fmt.Sprintf("[binary %d bytes, %s]", len(body), contentType)
+		}
+		fmt.Printf("%s %s\n", bodyColor.Sprint(prefix), dimColor.Sprint(label))
 		return
 	}
 
@@ -161,8 +403,8 @@ func (d *Display) logBody(prefix string, body []byte) {
 	s = strings.ReplaceAll(s, "\t", " ")
 
 	truncated := false
-	if len(s) > maxBodyDisplay {
-		s = s[:maxBodyDisplay]
+	if len(s) > d.bodyLimit {
+		s = s[:d.bodyLimit]
 		truncated = true
 	}
 
@@ -172,27 +414,3 @@ func (d *Display) logBody(prefix string, body []byte) {
 		fmt.Printf("%s %s\n", bodyColor.Sprint(prefix), bodyColor.Sprint(s))
 	}
 }
-
-// isTextBody checks if body appears to be text content
-func isTextBody(body []byte) bool {
-	if len(body) == 0 {
-		return false
-	}
-	// Check if it's valid UTF-8 and doesn't contain too many control chars
-	if !utf8.Valid(body) {
-		return false
-	}
-	// Sample first 512 bytes
-	sample := body
-	if len(sample) > 512 {
-		sample = sample[:512]
-	}
-	controlChars := 0
-	for _, b := range sample {
-		if b < 32 && b != '\n' && b != '\r' && b != '\t' {
-			controlChars++
-		}
-	}
-	// If more than 10% control chars, consider it binary
-	return float64(controlChars)/float64(len(sample)) < 0.1
-}
@@ -3,38 +3,66 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/lance0/hookshot/internal/protocol"
 )
 
-// TargetResolver resolves the target URL for a given path
-type TargetResolver func(path string) string
+// TargetResolver resolves the target URL for a request's method and path,
+// the path to request on it, and any mirror targets that also get a copy
+type TargetResolver func(method, path string) (target, forwardPath string, mirrors []string)
+
+// MirrorFunc receives the result of a request copied to a mirror target
+type MirrorFunc func(req *protocol.HTTPRequest, target string, resp *protocol.HTTPResponse, err error, duration time.Duration)
 
 // Forwarder forwards requests to a local target
 type Forwarder struct {
 	defaultTarget  string
 	targetResolver TargetResolver
 	httpClient     *http.Client
+	h2cClient      *http.Client // For h2c:// targets
+	hostHeader     string       // Optional: override outgoing Host header
+	onMirror       MirrorFunc   // Optional: called with each mirror target's result
+
+	maxResponseSize int64  // Max target response body size (0 = DefaultMaxResponseSize)
+	clientIPHeader  string // Optional: header that carries the webhook sender's IP to the target
+
+	headerRules func(method, path string) HeaderRules // Optional: header edits for a request's method and path
+
+	bodyTransform    *BodyTransform     // Optional: jq expression applied to JSON request bodies
+	onTransformError TransformErrorFunc // Optional: called when bodyTransform fails
 }
 
+// DefaultMaxResponseSize caps target response bodies, matching the server's
+// default body limit
+const DefaultMaxResponseSize = 10 * 1024 * 1024
+
+// ErrResponseTooLarge is returned when a target's response body exceeds the
+// forwarder's limit
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// h2cScheme marks a target that speaks cleartext HTTP/2 with prior knowledge
+// (e.g., a local gRPC server): h2c://localhost:50051
+const h2cScheme = "h2c://"
+
 // NewForwarder creates a new forwarder with a single default target
 func NewForwarder(target string) *Forwarder {
 	return &Forwarder{
 		defaultTarget:  target,
 		targetResolver: nil,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-			// Don't follow redirects automatically
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
-		},
+		httpClient:     newHTTPClient(nil),
+		h2cClient:      newHTTPClient(h2cTransport()),
 	}
 }
 
@@ -43,30 +71,126 @@ func NewForwarderWithRoutes(defaultTarget string, resolver TargetResolver) *Forw
 	return &Forwarder{
 		defaultTarget:  defaultTarget,
 		targetResolver: resolver,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
+		httpClient:     newHTTPClient(nil),
+		h2cClient:      newHTTPClient(h2cTransport()),
+	}
+}
+
+// newHTTPClient creates a client for forwarding (nil transport = default).
+// It has no timeout of its own; the caller's context bounds each request.
+func newHTTPClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		// Don't follow redirects automatically
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
 		},
 	}
 }
 
-// resolveTarget gets the target for a path
-func (f *Forwarder) resolveTarget(path string) string {
+// h2cTransport speaks HTTP/2 without TLS, skipping the HTTP/1.1 upgrade
+func h2cTransport() *http.Transport {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Protocols = &protocols
+	return t
+}
+
+// TargetTLSConfig builds the TLS settings for https:// targets: trusting the
+// PEM certificates in caFile on top of the system roots, or skipping
+// verification entirely with insecure. It returns nil when neither is set.
+func TargetTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	return loadTLSConfig("target", caFile, insecure)
+}
+
+// loadTLSConfig builds client TLS settings trusting caFile and/or skipping
+// verification; what names the peer in errors
+func loadTLSConfig(what, caFile string, insecure bool) (*tls.Config, error) {
+	if caFile == "" && !insecure {
+		return nil, nil
+	}
+	cfg := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s CA: %w", what, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s CA %s", what, caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// setTLSConfig uses cfg for https:// targets
+func (f *Forwarder) setTLSConfig(cfg *tls.Config) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = cfg
+	f.httpClient.Transport = t
+}
+
+// resolveTarget gets the target for a request's method and path, the path
+// to forward and any mirror targets
+func (f *Forwarder) resolveTarget(method, path string) (string, string, []string) {
 	if f.targetResolver != nil {
-		return f.targetResolver(path)
+		return f.targetResolver(method, path)
 	}
-	return f.defaultTarget
+	return f.defaultTarget, path, nil
 }
 
-// Forward forwards a request to the local target and returns the response
+// Forward forwards a request to the local target and returns the response.
+// Mirror targets for the path get a copy concurrently; only the primary
+// target's response is returned.
 func (f *Forwarder) Forward(ctx context.Context, req *protocol.HTTPRequest) (*protocol.HTTPResponse, error) {
+	return f.forward(ctx, req, true)
+}
+
+// forward sends req to its primary target, copying it to the mirror
+// targets first when mirror is set (retries skip them)
+func (f *Forwarder) forward(ctx context.Context, req *protocol.HTTPRequest, mirror bool) (*protocol.HTTPResponse, error) {
 	// Resolve target based on path
-	target := f.resolveTarget(req.Path)
+	target, path, mirrors := f.resolveTarget(req.Method, req.Path)
+	req = f.transformRequest(ctx, req)
+
+	if mirror {
+		for _, m := range mirrors {
+			go f.mirror(ctx, req, m, path)
+		}
+	}
+	return f.send(ctx, req, target, path)
+}
+
+// mirror sends a copy of req to a mirror target and reports the result.
+// The response is discarded.
+func (f *Forwarder) mirror(ctx context.Context, req *protocol.HTTPRequest, target, path string) {
+	// Not tied to the primary: its context ends once the response is sent
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), protocol.ResponseTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := f.send(ctx, req, target, path)
+	if f.onMirror != nil {
+		f.onMirror(req, target, resp, err, time.Since(start))
+	}
+}
+
+// send makes a single request to target and reads the response
+func (f *Forwarder) send(ctx context.Context, req *protocol.HTTPRequest, target, path string) (*protocol.HTTPResponse, error) {
+	// h2c:// targets are plain http:// over HTTP/2
+	client, base := f.httpClient, target
+	h2c := strings.HasPrefix(target, h2cScheme)
+	if h2c {
+		client, base = f.h2cClient, "http://"+strings.TrimPrefix(target, h2cScheme)
+	}
 
 	// Build the full URL using proper URL parsing
-	fullURL, err := buildURL(target, req.Path)
+	fullURL, err := buildURL(base, path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build URL: %w", err)
 	}
@@ -78,46 +202,106 @@ func (f *Forwarder) Forward(ctx context.Context, req *protocol.HTTPRequest) (*pr
 	}
 
 	// Copy headers
-	for k, v := range req.Headers {
+	for k, values := range req.Headers {
 		// Skip hop-by-hop headers
 		if isHopByHop(k) {
 			continue
 		}
-		httpReq.Header.Set(k, v)
+		for _, v := range values {
+			httpReq.Header.Add(k, v)
+		}
+	}
+
+	if f.clientIPHeader != "" && req.RemoteIP != "" {
+		setClientIP(httpReq.Header, f.clientIPHeader, req.RemoteIP)
+	}
+
+	// gRPC servers require TE: trailers, which is otherwise dropped as hop-by-hop
+	if h2c && strings.HasPrefix(httpReq.Header.Get("Content-Type"), "application/grpc") {
+		httpReq.Header.Set("Te", "trailers")
+	}
+
+	if f.headerRules != nil {
+		f.headerRules(req.Method, req.Path).apply(httpReq.Header)
+	}
+
+	// Override Host for name-based virtual hosting behind the target
+	if f.hostHeader != "" {
+		httpReq.Host = f.hostHeader
+		httpReq.Header.Del("Host")
 	}
 
 	// Make the request
-	resp, err := f.httpClient.Do(httpReq)
+	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to forward request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+	// Read the response body, stopping a runaway target before it exhausts memory
+	limit := f.maxResponseSize
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: target sent more than %d bytes", ErrResponseTooLarge, limit)
+	}
 
 	// Build response headers (skip hop-by-hop)
-	headers := make(map[string]string)
+	headers := make(protocol.Headers)
 	for k, v := range resp.Header {
 		if isHopByHop(k) {
 			continue
 		}
 		if len(v) > 0 {
-			headers[k] = v[0]
+			headers[k] = v
+		}
+	}
+	// The tunnel has no trailers; pass them (e.g., grpc-status) as headers
+	if h2c {
+		for k, v := range resp.Trailer {
+			if len(v) > 0 {
+				headers[k] = v
+			}
 		}
 	}
 
 	return &protocol.HTTPResponse{
 		RequestID:  req.ID,
 		StatusCode: resp.StatusCode,
+		StatusText: strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
 		Headers:    headers,
 		Body:       body,
+		Target:     target,
 	}, nil
 }
 
+// setClientIP passes the sender's IP in header. X-Forwarded-For is a chain,
+// so the IP is appended unless a proxy in front of the relay already listed
+// it; any other header is replaced.
+func setClientIP(h http.Header, header, ip string) {
+	if http.CanonicalHeaderKey(header) != "X-Forwarded-For" {
+		h.Set(header, ip)
+		return
+	}
+	var hops []string
+	for _, v := range h.Values(header) {
+		for hop := range strings.SplitSeq(v, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if !slices.Contains(hops, ip) {
+		hops = append(hops, ip)
+	}
+	h.Set(header, strings.Join(hops, ", "))
+}
+
 // buildURL properly joins a base URL with a path, handling edge cases
 func buildURL(baseURL, path string) (string, error) {
 	base, err := url.Parse(baseURL)
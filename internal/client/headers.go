@@ -0,0 +1,81 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HeaderRules edits the headers of requests forwarded to a target. Renames
+// run first, then removals, so a header can be replaced by removing and
+// setting it.
+type HeaderRules struct {
+	Rename map[string]string // Headers moved to a new name with their values (old name -> new name)
+	Set    map[string]string // Headers added to (or replacing those on) the forwarded request
+	Remove []string          // Headers stripped from the forwarded request
+}
+
+// ParseHeader parses a "Name: value" header flag
+func ParseHeader(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q (want \"Name: value\")", s)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// empty reports whether the rules change nothing
+func (h HeaderRules) empty() bool {
+	return len(h.Rename) == 0 && len(h.Set) == 0 && len(h.Remove) == 0
+}
+
+// merge returns h with o layered on top: o's headers win, and both sets of
+// removals apply
+func (h HeaderRules) merge(o HeaderRules) HeaderRules {
+	if o.empty() {
+		return h
+	}
+	merged := HeaderRules{
+		Rename: h.Rename,
+		Set:    make(map[string]string, len(h.Set)+len(o.Set)),
+		Remove: append(append([]string(nil), h.Remove...), o.Remove...),
+	}
+	if len(o.Rename) > 0 {
+		merged.Rename = make(map[string]string, len(h.Rename)+len(o.Rename))
+		for k, v := range h.Rename {
+			merged.Rename[http.CanonicalHeaderKey(k)] = v
+		}
+		for k, v := range o.Rename {
+			merged.Rename[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	for k, v := range h.Set {
+		merged.Set[http.CanonicalHeaderKey(k)] = v
+	}
+	for k, v := range o.Set {
+		merged.Set[http.CanonicalHeaderKey(k)] = v
+	}
+	return merged
+}
+
+// apply edits the outgoing headers. A renamed header replaces any already
+// sent under the new name; headers that aren't sent are left alone.
+func (h HeaderRules) apply(header http.Header) {
+	renamed := make(http.Header, len(h.Rename))
+	for from, to := range h.Rename {
+		if values := header.Values(from); len(values) > 0 {
+			header.Del(from)
+			renamed[http.CanonicalHeaderKey(to)] = values
+		}
+	}
+	for k, values := range renamed {
+		header[k] = values
+	}
+	for _, k := range h.Remove {
+		header.Del(k)
+	}
+	for k, v := range h.Set {
+		header.Set(k, v)
+	}
+}
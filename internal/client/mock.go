@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// MockTarget is reported as the target of mocked responses
+const MockTarget = "mock"
+
+// EchoTarget is reported as the target of echo mode responses
+const EchoTarget = "echo"
+
+// Mock is a canned response served instead of forwarding to a target
+type Mock struct {
+	Method  string            // Optional: only match this method
+	Path    string            // path.Match pattern for the request path (e.g., "/stripe/*")
+	Status  int               // Response status (default 200)
+	Headers map[string]string // Response headers
+	Body    string            // Response body
+	Delay   time.Duration     // Optional: wait before responding, to emulate slow endpoints
+}
+
+// matches reports whether the mock applies to req. The query string is
+// ignored.
+func (m Mock) matches(req *protocol.HTTPRequest) bool {
+	if m.Method != "" && !strings.EqualFold(m.Method, req.Method) {
+		return false
+	}
+	p, _, _ := strings.Cut(req.Path, "?")
+	ok, _ := path.Match(m.Path, p)
+	return ok
+}
+
+// findMock returns the first mock matching req, or nil
+func (c *Client) findMock(req *protocol.HTTPRequest) *Mock {
+	for i := range c.config.Mocks {
+		if c.config.Mocks[i].matches(req) {
+			return &c.config.Mocks[i]
+		}
+	}
+	return nil
+}
+
+// mockResponse builds the canned response for req after the mock's delay
+func mockResponse(ctx context.Context, req *protocol.HTTPRequest, m *Mock) (*protocol.HTTPResponse, error) {
+	if m.Delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(m.Delay):
+		}
+	}
+
+	status := m.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	headers := make(protocol.Headers, len(m.Headers))
+	for k, v := range m.Headers {
+		headers[http.CanonicalHeaderKey(k)] = []string{v}
+	}
+	return &protocol.HTTPResponse{
+		RequestID:  req.ID,
+		StatusCode: status,
+		Headers:    headers,
+		Body:       []byte(m.Body),
+		Target:     MockTarget,
+	}, nil
+}
+
+// echoSummary is the body of an echo mode response
+type echoSummary struct {
+	ID         string           `json:"id"`
+	Method     string           `json:"method"`
+	Path       string           `json:"path"`
+	Headers    protocol.Headers `json:"headers"`
+	BodyLength int              `json:"body_length"`
+	RemoteIP   string           `json:"remote_ip,omitempty"`
+}
+
+// echoResponse answers req with a JSON summary of what arrived, to check a
+// tunnel end to end before any handler exists
+func echoResponse(req *protocol.HTTPRequest) *protocol.HTTPResponse {
+	body, _ := json.MarshalIndent(echoSummary{
+		ID:         req.ID,
+		Method:     req.Method,
+		Path:       req.Path,
+		Headers:    req.Headers,
+		BodyLength: len(req.Body),
+		RemoteIP:   req.RemoteIP,
+	}, "", "  ")
+	return &protocol.HTTPResponse{
+		RequestID:  req.ID,
+		StatusCode: http.StatusOK,
+		Headers:    protocol.Headers{"Content-Type": {"application/json"}},
+		Body:       append(body, '\n'),
+		Target:     EchoTarget,
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// PathFilter decides which request paths the client handles. Entries with
+// glob characters are path.Match patterns; others are path prefixes, as in
+// routes. The query string is ignored.
+type PathFilter struct {
+	Accept []string // Only handle paths matching one of these (empty = all)
+	Ignore []string // Never handle paths matching these (wins over Accept)
+	Status int      // Response status for filtered requests (default 404)
+}
+
+// Validate checks the patterns and status
+func (f PathFilter) Validate() error {
+	for _, patterns := range [][]string{f.Accept, f.Ignore} {
+		for _, p := range patterns {
+			if !strings.HasPrefix(p, "/") {
+				return fmt.Errorf("path filter %q must start with /", p)
+			}
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid path filter %q: %w", p, err)
+			}
+		}
+	}
+	if f.Status != 0 && (f.Status < 100 || f.Status > 599) {
+		return fmt.Errorf("invalid ignore status %d (must be 100-599)", f.Status)
+	}
+	return nil
+}
+
+// accepts reports whether a request for p should be handled
+func (f PathFilter) accepts(p string) bool {
+	p, _, _ = strings.Cut(p, "?")
+	for _, pattern := range f.Ignore {
+		if matchPathPattern(pattern, p) {
+			return false
+		}
+	}
+	if len(f.Accept) == 0 {
+		return true
+	}
+	for _, pattern := range f.Accept {
+		if matchPathPattern(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPathPattern(pattern, p string) bool {
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := path.Match(pattern, p)
+		return ok
+	}
+	return strings.HasPrefix(p, pattern)
+}
+
+// filteredResponse answers a request the client doesn't handle
+func (f PathFilter) filteredResponse(req *protocol.HTTPRequest) *protocol.HTTPResponse {
+	status := f.Status
+	if status == 0 {
+		status = http.StatusNotFound
+	}
+	return &protocol.HTTPResponse{
+		RequestID:  req.ID,
+		StatusCode: status,
+		Headers:    protocol.Headers{"Content-Type": {"text/plain"}},
+		Body:       []byte("path not handled by this hookshot client\n"),
+	}
+}
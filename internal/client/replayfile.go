@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// tunnelPathPrefix matches the /t/<tunnel-id> prefix of public webhook URLs,
+// which HAR archives from `hookshot export` include
+var tunnelPathPrefix = regexp.MustCompile(`^/t/[^/?]+`)
+
+// LoadRequestFile reads a request saved as a HAR archive (from `hookshot
+// export` or the TUI), as `hookshot inspect --json` output, or as a bare
+// request object. entry picks one of a HAR archive's entries.
+func LoadRequestFile(path string, entry int) (*protocol.HTTPRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request file: %w", err)
+	}
+
+	var file struct {
+		Log     *protocol.HARLog      `json:"log"`     // HAR archive
+		Request *protocol.HTTPRequest `json:"request"` // inspect --json
+		Method  string                `json:"method"`  // Bare request
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var req *protocol.HTTPRequest
+	switch {
+	case file.Log != nil:
+		entries := file.Log.Entries
+		if entry < 0 || entry >= len(entries) {
+			return nil, fmt.Errorf("%s has %d entries, no entry %d", path, len(entries), entry)
+		}
+		if req, err = entries[entry].HTTPRequest(); err != nil {
+			return nil, err
+		}
+		if file.Log.Creator.Name == "hookshot" {
+			if loc := tunnelPathPrefix.FindStringIndex(req.Path); loc != nil {
+				req.Path = req.Path[loc[1]:]
+				if req.Path == "" || req.Path[0] == '?' {
+					req.Path = "/" + req.Path
+				}
+			}
+		}
+	case file.Request != nil:
+		req = file.Request
+	case file.Method != "":
+		req = new(protocol.HTTPRequest)
+		if err := json.Unmarshal(data, req); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a HAR archive or a hookshot request", path)
+	}
+
+	if req.Method == "" || req.Path == "" {
+		return nil, fmt.Errorf("request in %s has no method or path", path)
+	}
+	if req.Headers == nil {
+		req.Headers = make(protocol.Headers)
+	}
+	return req, nil
+}
+
+// ReplayRequest sends a saved request straight to target, without a relay,
+// the way the client forwards webhooks. tlsConfig may be nil.
+func ReplayRequest(ctx context.Context, req *protocol.HTTPRequest, target string, tlsConfig *tls.Config) (*protocol.HTTPResponse, error) {
+	f := NewForwarder(target)
+	if tlsConfig != nil {
+		f.setTLSConfig(tlsConfig)
+	}
+	return f.Forward(ctx, req)
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// requestLogQueueSize caps entries waiting to be written; further entries
+// are dropped so a slow disk never delays forwarding
+const requestLogQueueSize = 1000
+
+// RequestLogEntry is one line of the request log
+type RequestLogEntry struct {
+	Time       time.Time `json:"time"`
+	ID         string    `json:"id"`
+	TunnelID   string    `json:"tunnel_id"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"duration_ms"`
+	Target     string    `json:"target,omitempty"`
+	Error      string    `json:"error,omitempty"`
+
+	// With bodies enabled only; base64 in the JSON
+	RequestBody  []byte `json:"request_body,omitempty"`
+	ResponseBody []byte `json:"response_body,omitempty"`
+}
+
+// RequestLog appends an entry for every forwarded request to a JSONL file.
+// Entries are written in the background by a single writer.
+type RequestLog struct {
+	file   *os.File
+	bodies bool
+	queue  chan *RequestLogEntry
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	dropped atomic.Uint64
+}
+
+// OpenRequestLog opens path for appending, creating it if needed. With
+// bodies, entries include the request and response bodies.
+func OpenRequestLog(path string, bodies bool) (*RequestLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request log: %w", err)
+	}
+	l := &RequestLog{
+		file:   f,
+		bodies: bodies,
+		queue:  make(chan *RequestLogEntry, requestLogQueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+// Log queues an entry for a forwarded request, dropping it if the queue is
+// full
+func (l *RequestLog) Log(req *protocol.HTTPRequest, resp *protocol.HTTPResponse, duration time.Duration, errMsg string) {
+	entry := &RequestLogEntry{
+		Time:       time.Now(),
+		ID:         req.ID,
+		TunnelID:   req.TunnelID,
+		Method:     req.Method,
+		Path:       req.Path,
+		Status:     resp.StatusCode,
+		DurationMs: float64(duration) / float64(time.Millisecond),
+		Target:     resp.Target,
+		Error:      errMsg,
+	}
+	if l.bodies {
+		entry.RequestBody = req.Body
+		entry.ResponseBody = resp.Body
+	}
+
+	select {
+	case l.queue <- entry:
+	default:
+		if n := l.dropped.Add(1); n == 1 || n%100 == 0 {
+			log.Printf("[%s] request log queue full, dropped entry (%d dropped total)", req.ID, n)
+		}
+	}
+}
+
+// run writes queued entries until Close, then writes what is still queued
+func (l *RequestLog) run() {
+	defer close(l.done)
+	for {
+		select {
+		case entry := <-l.queue:
+			l.write(entry)
+		case <-l.stop:
+			for {
+				select {
+				case entry := <-l.queue:
+					l.write(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write appends one entry. Each line goes out in a single write, so
+// entries stay whole even if another process appends to the same file.
+func (l *RequestLog) write(entry *RequestLogEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		log.Printf("[%s] failed to write request log: %v", entry.ID, err)
+	}
+}
+
+// Close writes the queued entries and closes the file. Entries logged after
+// Close are discarded.
+func (l *RequestLog) Close() error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+	return l.file.Close()
+}
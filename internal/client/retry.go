@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+const defaultRetryDelay = 500 * time.Millisecond
+
+// RetryPolicy controls how failed forwards to the target are retried
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first (<= 1 disables retries)
+	BaseDelay   time.Duration // Wait before the first retry; doubles on each retry (default 500ms)
+	Statuses    []StatusRange // Target statuses that trigger a retry (default 5xx)
+}
+
+// retryReason returns why a forward attempt should be retried, or "" if
+// its result is final
+func (p RetryPolicy) retryReason(resp *protocol.HTTPResponse, err error) string {
+	if err != nil {
+		if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+			return "target unreachable"
+		}
+		return ""
+	}
+	statuses := p.Statuses
+	if len(statuses) == 0 {
+		statuses = []StatusRange{{Min: 500, Max: 599}}
+	}
+	if statusExpected(statuses, resp.StatusCode) {
+		return fmt.Sprintf("target returned %d", resp.StatusCode)
+	}
+	return ""
+}
+
+// forward sends req to the target, retrying with exponential backoff while
+// the policy allows and ctx's deadline leaves room for another attempt
+func (c *Client) forward(ctx context.Context, f *Forwarder, req *protocol.HTTPRequest) (*protocol.HTTPResponse, error) {
+	policy := c.config.Retry
+	delay := policy.BaseDelay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := f.forward(ctx, req, attempt == 1)
+		if attempt >= policy.MaxAttempts {
+			return resp, err
+		}
+		reason := policy.retryReason(resp, err)
+		if reason == "" {
+			return resp, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+
+		c.display.LogRetry(req, reason, attempt+1, policy.MaxAttempts, delay)
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
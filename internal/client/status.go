@@ -0,0 +1,67 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusRange is an inclusive range of HTTP status codes
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// ParseStatusRanges parses status specs like "200", "2xx", or "200-204"
+func ParseStatusRanges(specs []string) ([]StatusRange, error) {
+	var ranges []StatusRange
+	for _, spec := range specs {
+		spec = strings.ToLower(strings.TrimSpace(spec))
+		if spec == "" {
+			continue
+		}
+
+		var r StatusRange
+		switch {
+		case len(spec) == 3 && strings.HasSuffix(spec, "xx"):
+			class, err := strconv.Atoi(spec[:1])
+			if err != nil || class < 1 || class > 5 {
+				return nil, fmt.Errorf("invalid status class %q", spec)
+			}
+			r = StatusRange{Min: class * 100, Max: class*100 + 99}
+		case strings.Contains(spec, "-"):
+			lo, hi, _ := strings.Cut(spec, "-")
+			min, err1 := strconv.Atoi(lo)
+			max, err2 := strconv.Atoi(hi)
+			if err1 != nil || err2 != nil || min > max {
+				return nil, fmt.Errorf("invalid status range %q", spec)
+			}
+			r = StatusRange{Min: min, Max: max}
+		default:
+			code, err := strconv.Atoi(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid status code %q", spec)
+			}
+			r = StatusRange{Min: code, Max: code}
+		}
+
+		if r.Min < 100 || r.Max > 599 {
+			return nil, fmt.Errorf("status %q out of range (100-599)", spec)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// statusExpected reports whether code falls in any range (true if none set)
+func statusExpected(ranges []StatusRange, code int) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Summary counts the requests handled during one summary interval
+type Summary struct {
+	Forwarded int           // Requests answered, failures included
+	Failed    int           // Requests that couldn't be forwarded (answered with 502)
+	Latency   time.Duration // Total time spent answering them
+	Interval  time.Duration // How long the summary covers
+	Total     int64         // Requests answered since the client started
+}
+
+// Average returns the mean latency, or 0 with no requests
+func (s Summary) Average() time.Duration {
+	if s.Forwarded == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Forwarded)
+}
+
+// summaryCounter accumulates requests between summaries
+type summaryCounter struct {
+	mu      sync.Mutex
+	current Summary
+	total   int64
+	since   time.Time
+}
+
+// record counts one answered request
+func (s *summaryCounter) record(duration time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Forwarded++
+	s.current.Latency += duration
+	if failed {
+		s.current.Failed++
+	}
+	s.total++
+}
+
+// take returns the counts since the last take and starts a new interval
+func (s *summaryCounter) take(now time.Time) Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum := s.current
+	sum.Interval = now.Sub(s.since)
+	sum.Total = s.total
+	s.current = Summary{}
+	s.since = now
+	return sum
+}
+
+// printSummaries logs a summary line every SummaryInterval until ctx ends,
+// including intervals without requests so the line doubles as a heartbeat
+func (c *Client) printSummaries(ctx context.Context) {
+	c.summary.take(time.Now())
+	ticker := time.NewTicker(c.config.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.display.LogSummary(c.summary.take(now), c.connected.Load())
+		}
+	}
+}
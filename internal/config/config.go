@@ -2,18 +2,37 @@ package config
 
 import (
 	"fmt"
+	"maps"
+	"net"
+	"net/netip"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// ProfileEnv selects a profile when no --profile flag is given
+const ProfileEnv = "HOOKSHOT_PROFILE"
+
 // Config represents the full configuration file
 type Config struct {
 	Server ServerConfig `yaml:"server,omitempty"`
 	Client ClientConfig `yaml:"client,omitempty"`
+
+	Profiles map[string]Profile `yaml:"profiles,omitempty"` // Named overrides, applied with --profile
+}
+
+// Profile holds settings that override the top-level server and client
+// sections when the profile is active. Only the keys it sets are replaced.
+type Profile struct {
+	Server ServerConfig `yaml:"server,omitempty"`
+	Client ClientConfig `yaml:"client,omitempty"`
 }
 
 // ServerConfig holds server configuration
@@ -22,9 +41,78 @@ type ServerConfig struct {
 	Host        string `yaml:"host,omitempty"`
 	PublicURL   string `yaml:"public_url,omitempty"`
 	MaxRequests int    `yaml:"max_requests,omitempty"`
+	StorePath   string `yaml:"store_path,omitempty"` // SQLite file for persistent request history
 	Token       string `yaml:"token,omitempty"`
 	TLSCert     string `yaml:"tls_cert,omitempty"`
 	TLSKey      string `yaml:"tls_key,omitempty"`
+
+	ACMEDomains  []string `yaml:"acme_domains,omitempty"`   // Get certificates for these domains from Let's Encrypt
+	ACMEEmail    string   `yaml:"acme_email,omitempty"`     // Contact address for the ACME account
+	ACMECacheDir string   `yaml:"acme_cache_dir,omitempty"` // Where ACME certificates are kept
+
+	Tokens []Token `yaml:"tokens,omitempty"` // Further accepted tokens, optionally scoped to some tunnels
+
+	RegisterRateLimit int `yaml:"register_rate_limit,omitempty"` // New tunnels per minute (0 = unlimited)
+	MaxTunnels        int `yaml:"max_tunnels,omitempty"`         // Tunnels connected at once (0 = unlimited)
+
+	IPFilter       IPFilter `yaml:"ip_filter,omitempty"`       // Source addresses allowed to send webhooks
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"` // Proxies whose X-Forwarded-For is believed
+
+	CORS CORS `yaml:"cors,omitempty"` // CORS headers for browser-sent webhooks (off unless origins are set)
+
+	RateLimit      float64 `yaml:"rate_limit,omitempty"`       // Webhooks per second per tunnel (0 = unlimited)
+	RateLimitBurst int     `yaml:"rate_limit_burst,omitempty"` // Burst above rate_limit (default: the rate)
+
+	NoTunnelStatus     int    `yaml:"no_tunnel_status,omitempty"`      // Status for webhooks with no connected tunnel (default 404)
+	NoTunnelRetryAfter int    `yaml:"no_tunnel_retry_after,omitempty"` // Retry-After seconds for no-tunnel responses
+	NoTunnelBody       string `yaml:"no_tunnel_body,omitempty"`        // Response body for no-tunnel responses, plain text or HTML
+	NoTunnelBodyFile   string `yaml:"no_tunnel_body_file,omitempty"`   // Read the no-tunnel body from this file instead
+
+	BufferOffline     bool          `yaml:"buffer_offline,omitempty"`      // Buffer webhooks while a tunnel's client reconnects
+	OfflineBufferSize int           `yaml:"offline_buffer_size,omitempty"` // Max buffered webhooks per tunnel (default 100)
+	OfflineBufferAge  time.Duration `yaml:"offline_buffer_age,omitempty"`  // How long to wait for a client to return (default 10m)
+
+	LockTunnels bool   `yaml:"lock_tunnels,omitempty"` // Only accept tunnel IDs listed in tunnels_file
+	TunnelsFile string `yaml:"tunnels_file,omitempty"` // YAML list of bound tunnel IDs, e.g. with signing secrets (reloaded on SIGHUP)
+
+	AllowMultiClient bool   `yaml:"allow_multi_client,omitempty"` // Let clients share a requested tunnel ID
+	Balance          string `yaml:"balance,omitempty"`            // round-robin, least-in-flight or failover
+
+	Debug bool `yaml:"debug,omitempty"` // Log redacted dumps of malformed protocol frames
+
+	Dashboard bool `yaml:"dashboard,omitempty"` // Serve the browser dashboard at /dashboard (requires token)
+
+	TransformScript  string        `yaml:"transform_script,omitempty"`  // Starlark script run per webhook
+	TransformTimeout time.Duration `yaml:"transform_timeout,omitempty"` // Max execution time per webhook
+
+	Sink          string `yaml:"sink,omitempty"`            // URL to publish an event for every webhook
+	SinkPayload   string `yaml:"sink_payload,omitempty"`    // summary (default) or full
+	SinkQueueSize int    `yaml:"sink_queue_size,omitempty"` // Events buffered before dropping (default 1000)
+
+	MaxDecompressedBytes int64 `yaml:"max_decompressed_bytes,omitempty"` // Cap on gzip-decoded response bodies (default 10MB)
+	MaxBodySize          int64 `yaml:"max_body_size,omitempty"`          // Cap on webhook and response bodies (default 10MB)
+	ChunkSize            int   `yaml:"chunk_size,omitempty"`             // Larger bodies cross the tunnel in chunks (default 1MB)
+	SendBuffer           int   `yaml:"send_buffer,omitempty"`            // Messages queued per client; webhooks get 503 at 90% (default 256)
+
+	NoWSCompression bool `yaml:"no_ws_compression,omitempty"` // Don't negotiate permessage-deflate with clients
+
+	RequireUpgradeAuth bool `yaml:"require_upgrade_auth,omitempty"` // Refuse WebSocket upgrades without a valid Authorization header
+
+	DrainTimeout time.Duration `yaml:"drain_timeout,omitempty"` // Wait for in-flight webhooks on shutdown (default 10s)
+	ResponseWait time.Duration `yaml:"response_wait,omitempty"` // Wait for the client's response to each webhook (default 30s)
+
+	PingInterval time.Duration `yaml:"ping_interval,omitempty"` // WebSocket ping interval (default 90% of pong_timeout)
+	PongTimeout  time.Duration `yaml:"pong_timeout,omitempty"`  // Drop clients that don't answer pings within this (default 60s)
+
+	TunnelIdleTimeout time.Duration `yaml:"tunnel_idle_timeout,omitempty"` // Close tunnels without webhooks for this long (0 = never)
+
+	URLTTL time.Duration `yaml:"url_ttl,omitempty"` // Sign public URLs so they expire after this long (0 = never)
+
+	DedupWindow time.Duration `yaml:"dedup_window,omitempty"` // Answer redeliveries within this long from cache (0 = off)
+	DedupHeader string        `yaml:"dedup_header,omitempty"` // Header identifying a delivery (default: a body hash)
+
+	Aliases     map[string]string `yaml:"aliases,omitempty"`      // Memorable names for tunnel IDs (alias: tunnel-id)
+	AliasPrefix string            `yaml:"alias_prefix,omitempty"` // Also serve aliases under this path ("/" for the root)
 }
 
 // ClientConfig holds client configuration
@@ -34,17 +122,152 @@ type ClientConfig struct {
 	TunnelID string   `yaml:"tunnel_id,omitempty"`
 	Token    string   `yaml:"token,omitempty"`
 	Verbose  bool     `yaml:"verbose,omitempty"`
+	Echo     bool     `yaml:"echo,omitempty"`   // Answer webhooks with a summary instead of forwarding
 	Routes   []Route  `yaml:"routes,omitempty"` // Multiple targets by path
+
+	Tunnels []Tunnel `yaml:"tunnels,omitempty"` // Several named tunnels over one connection
+
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval,omitempty"` // App-level ping interval (0 = disabled)
+	HeartbeatTimeout  time.Duration `yaml:"heartbeat_timeout,omitempty"`  // Wait for pong before reconnecting
+
+	SummaryInterval time.Duration `yaml:"summary_interval,omitempty"` // Print a summary of forwarded requests this often (0 = never)
+
+	PingInterval time.Duration `yaml:"ping_interval,omitempty"` // WebSocket ping interval (default 90% of pong_timeout)
+	PongTimeout  time.Duration `yaml:"pong_timeout,omitempty"`  // Reconnect when the server is silent this long (default 60s)
+
+	MaxReconnects int `yaml:"max_reconnects,omitempty"` // Give up after this many failed reconnects in a row (0 = never)
+
+	HostHeader string `yaml:"host_header,omitempty"` // Optional: override the Host header sent to the target
+
+	ClientIPHeader string `yaml:"client_ip_header,omitempty"` // Optional: header carrying the webhook sender's IP to the target
+
+	Transform string `yaml:"transform,omitempty"` // Optional: jq expression applied to JSON request bodies
+
+	BodyDisplayLimit int `yaml:"body_display_limit,omitempty"` // Max body chars in verbose logs (default 500)
+
+	ExpectStatus []string `yaml:"expect_status,omitempty"` // Expected target statuses (e.g., "2xx", "404", "200-204")
+
+	AsyncAck int `yaml:"async_ack,omitempty"` // Server acks webhooks with this 2xx and forwards in the background
+
+	MaxDecompressedBytes int64 `yaml:"max_decompressed_bytes,omitempty"` // Cap on gzip-decoded or reassembled request bodies (default 10MB)
+	MaxResponseSize      int64 `yaml:"max_response_size,omitempty"`      // Cap on target response bodies (default 10MB)
+
+	NoWSCompression bool `yaml:"no_ws_compression,omitempty"` // Don't offer permessage-deflate to the server
+
+	Retry Retry `yaml:"retry,omitempty"` // Retry forwards while the target is down
+
+	TargetTimeout time.Duration `yaml:"target_timeout,omitempty"` // Max time per forward, retries included (default 29s)
+
+	InjectHeaders map[string]string `yaml:"inject_headers,omitempty"` // Added to every forwarded request
+	RemoveHeaders []string          `yaml:"remove_headers,omitempty"` // Stripped from every forwarded request
+
+	TargetHeaderMap map[string]string `yaml:"target_header_map,omitempty"` // Rename headers before forwarding (sent name -> forwarded name)
+
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"` // Don't verify https:// targets' certificates
+	TargetCA           string `yaml:"target_ca,omitempty"`            // PEM bundle trusted for https:// targets
+	Insecure           bool   `yaml:"insecure,omitempty"`             // Don't verify an https:// relay server's certificate
+	RelayCA            string `yaml:"relay_ca,omitempty"`             // PEM bundle trusted for an https:// relay server
+	Proxy              string `yaml:"proxy,omitempty"`                // Proxy for the relay connection (http:// or socks5://)
+
+	Mocks []Mock `yaml:"mocks,omitempty"` // Canned responses served without forwarding
+
+	Ordered        bool `yaml:"ordered,omitempty"`         // Forward each tunnel's webhooks one at a time, in arrival order
+	MaxConcurrency int  `yaml:"max_concurrency,omitempty"` // Max webhooks forwarded at once; more are queued (0 = unlimited)
+
+	AcceptPaths  []string `yaml:"accept_paths,omitempty"`  // Only handle these paths (prefixes or globs)
+	IgnorePaths  []string `yaml:"ignore_paths,omitempty"`  // Never handle these paths (prefixes or globs)
+	IgnoreStatus int      `yaml:"ignore_status,omitempty"` // Status for paths not handled (default 404)
+
+	InjectDelay string  `yaml:"inject_delay,omitempty"` // Chaos testing: wait this long before handling each webhook (e.g., 500ms or 100ms-2s)
+	DropRate    float64 `yaml:"drop_rate,omitempty"`    // Chaos testing: fraction of webhooks answered with 500 without forwarding (0-1)
+
+	LogFile   string `yaml:"log_file,omitempty"`   // Append every forwarded request to this JSONL file
+	LogBodies bool   `yaml:"log_bodies,omitempty"` // Include request and response bodies in log_file
+
+	HistoryFile string `yaml:"history_file,omitempty"` // With --tui, keep the request list here across sessions
+}
+
+// Mock is a canned response for matching requests
+type Mock struct {
+	Path    string            `yaml:"path"`              // Glob pattern for the request path (e.g., "/stripe/*")
+	Method  string            `yaml:"method,omitempty"`  // Only match this method (default: any)
+	Status  int               `yaml:"status,omitempty"`  // Response status (default 200)
+	Headers map[string]string `yaml:"headers,omitempty"` // Response headers
+	Body    string            `yaml:"body,omitempty"`    // Response body
+	Delay   time.Duration     `yaml:"delay,omitempty"`   // Wait before responding (e.g., "2s")
+}
+
+// Retry configures client-side retries of failed forwards
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts,omitempty"` // Total attempts including the first (0 or 1 = no retries)
+	BaseDelay   time.Duration `yaml:"base_delay,omitempty"`   // Wait before the first retry, doubled each time (default 500ms)
+	Statuses    []string      `yaml:"statuses,omitempty"`     // Target statuses that trigger a retry (default ["5xx"])
+}
+
+// Token is an accepted auth token. Scoped tokens only register and see
+// tunnels within their scope.
+type Token struct {
+	Name         string `yaml:"name"`                    // Shown in logs (e.g., "alice")
+	Token        string `yaml:"token"`                   // The secret itself
+	TunnelPrefix string `yaml:"tunnel_prefix,omitempty"` // Tunnel IDs start with this (e.g., "alice-")
+	Label        string `yaml:"label,omitempty"`         // Tokens with the same label share access to their tunnels
+}
+
+// IPFilter lists CIDR ranges or single IPs. Deny wins; a non-empty Allow
+// rejects everything not listed.
+type IPFilter struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+}
+
+// CORS lists the origins, methods and headers browsers may use to send
+// webhooks
+type CORS struct {
+	Origins []string `yaml:"origins,omitempty"` // "*" or scheme://host[:port]
+	Methods []string `yaml:"methods,omitempty"` // Default GET, POST, PUT, PATCH, DELETE
+	Headers []string `yaml:"headers,omitempty"` // Default: the headers the browser asks for
 }
 
 // Route maps a path prefix to a target
 type Route struct {
-	Path   string `yaml:"path"`   // Path prefix to match (e.g., "/api")
-	Target string `yaml:"target"` // Target URL (e.g., "http://localhost:3000")
+	Path    string   `yaml:"path"`              // Path prefix to match (e.g., "/api")
+	Methods []string `yaml:"methods,omitempty"` // Only match these methods (default: any)
+	Target  string   `yaml:"target,omitempty"`  // Target URL (e.g., "http://localhost:3000")
+	Targets []string `yaml:"targets,omitempty"` // Fan out to several targets; the first one's response is returned
+
+	StripPrefix bool   `yaml:"strip_prefix,omitempty"` // Remove the matched prefix before forwarding
+	Rewrite     string `yaml:"rewrite,omitempty"`      // Replace the matched prefix (e.g., "/webhook")
+
+	InjectHeaders map[string]string `yaml:"inject_headers,omitempty"` // Added to this route's requests, overriding client-wide ones
+	RemoveHeaders []string          `yaml:"remove_headers,omitempty"` // Stripped from this route's requests
 }
 
-// Load loads configuration from a YAML file
-func Load(path string) (*Config, error) {
+// PrimaryTarget returns the target whose response is sent back to the server
+func (r Route) PrimaryTarget() string {
+	if len(r.Targets) > 0 {
+		return r.Targets[0]
+	}
+	return r.Target
+}
+
+// MatchesMethod reports whether the route accepts a request method
+func (r Route) MatchesMethod(method string) bool {
+	if len(r.Methods) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.Methods, func(m string) bool { return strings.EqualFold(m, method) })
+}
+
+// Tunnel is one named tunnel of a multi-tunnel client
+type Tunnel struct {
+	Name   string `yaml:"name"`         // Label shown in logs (e.g., "billing")
+	Target string `yaml:"target"`       // Target URL for this tunnel's webhooks
+	ID     string `yaml:"id,omitempty"` // Optional: requested tunnel ID
+}
+
+// Load loads configuration from a YAML file. With a profile name, that
+// profile's keys replace the same keys of the top-level sections.
+func Load(path, profile string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -54,8 +277,74 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	if profile == "" {
+		return &cfg, nil
+	}
+
+	if _, ok := cfg.Profiles[profile]; !ok {
+		if len(cfg.Profiles) == 0 {
+			return nil, fmt.Errorf("unknown profile %q: the config file defines no profiles", profile)
+		}
+		return nil, fmt.Errorf("unknown profile %q (defined: %s)", profile, strings.Join(cfg.ProfileNames(), ", "))
+	}
+
+	// Merge on the YAML tree rather than the structs, so a profile can also
+	// set a key back to false or zero
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	root := doc.Content[0]
+	override := mappingValue(mappingValue(root, "profiles"), profile)
+	for _, section := range []string{"server", "client"} {
+		over := mappingValue(override, section)
+		if over == nil || over.Kind != yaml.MappingNode {
+			continue
+		}
+		base := mappingValue(root, section)
+		if base == nil || base.Kind != yaml.MappingNode {
+			setMappingValue(root, section, over)
+			continue
+		}
+		for i := 0; i+1 < len(over.Content); i += 2 {
+			setMappingValue(base, over.Content[i].Value, over.Content[i+1])
+		}
+	}
 
-	return &cfg, nil
+	var merged Config
+	if err := root.Decode(&merged); err != nil {
+		return nil, fmt.Errorf("failed to apply profile %q: %w", profile, err)
+	}
+	return &merged, nil
+}
+
+// ProfileNames returns the names of the defined profiles, sorted
+func (c *Config) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(c.Profiles))
+}
+
+// mappingValue returns the value of key in a YAML mapping, or nil
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key in a YAML mapping, adding it if missing
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
 }
 
 // FindConfigFile looks for hookshot.yaml in common locations
@@ -84,8 +373,10 @@ func FindConfigFile() string {
 	return ""
 }
 
-// MatchRoute finds the best matching route for a path
-func (c *ClientConfig) MatchRoute(path string) string {
+// MatchRoute finds the best matching route for a request. The longest
+// matching prefix wins; between routes with the same prefix, one listing
+// the method wins over one that accepts any method.
+func (c *ClientConfig) MatchRoute(method, path string) string {
 	if len(c.Routes) == 0 {
 		return c.Target
 	}
@@ -95,14 +386,17 @@ func (c *ClientConfig) MatchRoute(path string) string {
 	bestLen := -1
 
 	for _, route := range c.Routes {
-		if strings.HasPrefix(path, route.Path) && len(route.Path) > bestLen {
+		if !strings.HasPrefix(path, route.Path) || !route.MatchesMethod(method) {
+			continue
+		}
+		if len(route.Path) > bestLen || (len(route.Path) == bestLen && len(bestMatch.Methods) == 0 && len(route.Methods) > 0) {
 			bestMatch = route
 			bestLen = len(route.Path)
 		}
 	}
 
 	if bestLen >= 0 {
-		return bestMatch.Target
+		return bestMatch.PrimaryTarget()
 	}
 
 	// Fall back to default target
@@ -126,6 +420,10 @@ func (c *ServerConfig) Validate() error {
 		return fmt.Errorf("both tls_cert and tls_key must be set, or neither")
 	}
 
+	if len(c.ACMEDomains) > 0 && c.TLSCert != "" {
+		return fmt.Errorf("acme_domains and tls_cert/tls_key are mutually exclusive")
+	}
+
 	// If TLS files are specified, verify they exist
 	if c.TLSCert != "" {
 		if _, err := os.Stat(c.TLSCert); err != nil {
@@ -142,6 +440,144 @@ func (c *ServerConfig) Validate() error {
 		return fmt.Errorf("invalid max_requests: %d (must be >= 0)", c.MaxRequests)
 	}
 
+	if c.RegisterRateLimit < 0 {
+		return fmt.Errorf("invalid register_rate_limit: %d (must be >= 0)", c.RegisterRateLimit)
+	}
+	if c.MaxTunnels < 0 {
+		return fmt.Errorf("invalid max_tunnels: %d (must be >= 0)", c.MaxTunnels)
+	}
+	if c.RateLimit < 0 || c.RateLimitBurst < 0 {
+		return fmt.Errorf("rate_limit and rate_limit_burst must be >= 0")
+	}
+
+	if c.NoTunnelStatus != 0 && (c.NoTunnelStatus < 400 || c.NoTunnelStatus > 599) {
+		return fmt.Errorf("invalid no_tunnel_status: %d (must be 400-599)", c.NoTunnelStatus)
+	}
+	if c.NoTunnelRetryAfter < 0 {
+		return fmt.Errorf("invalid no_tunnel_retry_after: %d (must be >= 0)", c.NoTunnelRetryAfter)
+	}
+	if c.NoTunnelBody != "" && c.NoTunnelBodyFile != "" {
+		return fmt.Errorf("no_tunnel_body and no_tunnel_body_file are mutually exclusive")
+	}
+	if c.NoTunnelBodyFile != "" {
+		if _, err := os.Stat(c.NoTunnelBodyFile); err != nil {
+			return fmt.Errorf("no_tunnel_body_file not found: %s", c.NoTunnelBodyFile)
+		}
+	}
+	if c.OfflineBufferSize < 0 {
+		return fmt.Errorf("invalid offline_buffer_size: %d (must be >= 0)", c.OfflineBufferSize)
+	}
+	if c.OfflineBufferAge < 0 {
+		return fmt.Errorf("invalid offline_buffer_age: %s (must be >= 0)", c.OfflineBufferAge)
+	}
+
+	for _, list := range []struct {
+		name    string
+		entries []string
+	}{
+		{"ip_filter.allow", c.IPFilter.Allow},
+		{"ip_filter.deny", c.IPFilter.Deny},
+		{"trusted_proxies", c.TrustedProxies},
+	} {
+		for _, e := range list.entries {
+			if !validIPRange(e) {
+				return fmt.Errorf("invalid %s entry: %q (must be a CIDR or IP)", list.name, e)
+			}
+		}
+	}
+
+	for _, o := range c.CORS.Origins {
+		if o != "*" && !strings.HasPrefix(o, "http://") && !strings.HasPrefix(o, "https://") {
+			return fmt.Errorf("invalid cors origin %q (must be \"*\" or scheme://host[:port])", o)
+		}
+	}
+	if len(c.CORS.Origins) == 0 && (len(c.CORS.Methods) > 0 || len(c.CORS.Headers) > 0) {
+		return fmt.Errorf("cors methods and headers require origins")
+	}
+
+	for i, t := range c.Tokens {
+		if t.Token == "" {
+			return fmt.Errorf("tokens %d (%s): token is required", i, t.Name)
+		}
+	}
+	if c.Dashboard && c.Token == "" && len(c.Tokens) == 0 {
+		return fmt.Errorf("dashboard requires token")
+	}
+	if c.LockTunnels && c.TunnelsFile == "" {
+		return fmt.Errorf("lock_tunnels requires tunnels_file")
+	}
+	if c.TunnelsFile != "" {
+		if _, err := os.Stat(c.TunnelsFile); err != nil {
+			return fmt.Errorf("tunnels_file not found: %s", c.TunnelsFile)
+		}
+	}
+
+	switch c.Balance {
+	case "", "round-robin", "least-in-flight", "failover":
+	default:
+		return fmt.Errorf("invalid balance: %s (must be round-robin, least-in-flight or failover)", c.Balance)
+	}
+
+	if c.TransformScript != "" {
+		if _, err := os.Stat(c.TransformScript); err != nil {
+			return fmt.Errorf("transform_script file not found: %s", c.TransformScript)
+		}
+	}
+	if c.TransformTimeout < 0 {
+		return fmt.Errorf("invalid transform_timeout: %s (must be >= 0)", c.TransformTimeout)
+	}
+
+	if c.Sink != "" {
+		u, err := url.Parse(c.Sink)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid sink: %s (must be an http or https URL)", c.Sink)
+		}
+	}
+	switch c.SinkPayload {
+	case "", "summary", "full":
+	default:
+		return fmt.Errorf("invalid sink_payload: %s (must be summary or full)", c.SinkPayload)
+	}
+	if c.SinkQueueSize < 0 {
+		return fmt.Errorf("invalid sink_queue_size: %d (must be >= 0)", c.SinkQueueSize)
+	}
+	if c.MaxDecompressedBytes < 0 {
+		return fmt.Errorf("invalid max_decompressed_bytes: %d (must be >= 0)", c.MaxDecompressedBytes)
+	}
+	if c.MaxBodySize < 0 {
+		return fmt.Errorf("invalid max_body_size: %d (must be >= 0)", c.MaxBodySize)
+	}
+	if c.ChunkSize < 0 {
+		return fmt.Errorf("invalid chunk_size: %d (must be >= 0)", c.ChunkSize)
+	}
+	if c.SendBuffer < 0 {
+		return fmt.Errorf("invalid send_buffer: %d (must be >= 0)", c.SendBuffer)
+	}
+	if c.DrainTimeout < 0 {
+		return fmt.Errorf("invalid drain_timeout: %s (must be >= 0)", c.DrainTimeout)
+	}
+	if c.TunnelIdleTimeout < 0 {
+		return fmt.Errorf("invalid tunnel_idle_timeout: %s (must be >= 0)", c.TunnelIdleTimeout)
+	}
+	if c.URLTTL != 0 && c.URLTTL < time.Second {
+		return fmt.Errorf("invalid url_ttl: %s (must be at least 1s)", c.URLTTL)
+	}
+	if c.DedupWindow < 0 {
+		return fmt.Errorf("invalid dedup_window: %s (must be >= 0)", c.DedupWindow)
+	}
+	if c.DedupHeader != "" && !validHeaderName(c.DedupHeader) {
+		return fmt.Errorf("invalid dedup_header %q", c.DedupHeader)
+	}
+	if c.AliasPrefix != "" && !strings.HasPrefix(c.AliasPrefix, "/") {
+		return fmt.Errorf("invalid alias_prefix %q (must start with /)", c.AliasPrefix)
+	}
+	if c.ResponseWait != 0 && c.ResponseWait < 2*time.Second {
+		return fmt.Errorf("invalid response_wait: %s (must be at least 2s)", c.ResponseWait)
+	}
+	if err := validKeepalive(c.PingInterval, c.PongTimeout); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -163,22 +599,194 @@ func (c *ClientConfig) Validate() error {
 		}
 	}
 
+	if c.HeartbeatInterval < 0 || c.HeartbeatTimeout < 0 {
+		return fmt.Errorf("heartbeat_interval and heartbeat_timeout must be >= 0")
+	}
+	if c.SummaryInterval != 0 && c.SummaryInterval < time.Second {
+		return fmt.Errorf("invalid summary_interval: %s (must be at least 1s)", c.SummaryInterval)
+	}
+	if c.MaxReconnects < 0 {
+		return fmt.Errorf("invalid max_reconnects: %d (must be >= 0)", c.MaxReconnects)
+	}
+	if err := validKeepalive(c.PingInterval, c.PongTimeout); err != nil {
+		return err
+	}
+
+	if c.BodyDisplayLimit < 0 {
+		return fmt.Errorf("invalid body_display_limit: %d (must be >= 0)", c.BodyDisplayLimit)
+	}
+	if c.AsyncAck != 0 && (c.AsyncAck < 200 || c.AsyncAck > 299) {
+		return fmt.Errorf("invalid async_ack: %d (must be a 2xx status)", c.AsyncAck)
+	}
+	if c.MaxDecompressedBytes < 0 {
+		return fmt.Errorf("invalid max_decompressed_bytes: %d (must be >= 0)", c.MaxDecompressedBytes)
+	}
+	if c.MaxResponseSize < 0 {
+		return fmt.Errorf("invalid max_response_size: %d (must be >= 0)", c.MaxResponseSize)
+	}
+	if err := validHeaderRules(c.InjectHeaders, c.RemoveHeaders); err != nil {
+		return err
+	}
+	for from, to := range c.TargetHeaderMap {
+		if !validHeaderName(from) || !validHeaderName(to) {
+			return fmt.Errorf("invalid target_header_map entry %q: %q", from, to)
+		}
+	}
+	if c.MaxConcurrency < 0 {
+		return fmt.Errorf("invalid max_concurrency: %d (must be >= 0)", c.MaxConcurrency)
+	}
+	if c.TargetTimeout < 0 {
+		return fmt.Errorf("invalid target_timeout: %s (must be >= 0)", c.TargetTimeout)
+	}
+	if c.Retry.MaxAttempts < 0 || c.Retry.BaseDelay < 0 {
+		return fmt.Errorf("retry max_attempts and base_delay must be >= 0")
+	}
+
+	if c.HostHeader != "" {
+		if err := ValidateHostname(c.HostHeader); err != nil {
+			return fmt.Errorf("invalid host_header: %w", err)
+		}
+	}
+	if c.ClientIPHeader != "" && strings.ContainsAny(c.ClientIPHeader, " \t:") {
+		return fmt.Errorf("invalid client_ip_header %q", c.ClientIPHeader)
+	}
+
 	// Validate routes
 	for i, route := range c.Routes {
 		if route.Path == "" {
 			return fmt.Errorf("route %d: path is required", i)
 		}
-		if route.Target == "" {
+		if route.Target == "" && len(route.Targets) == 0 {
 			return fmt.Errorf("route %d: target is required", i)
 		}
-		if _, err := url.Parse(route.Target); err != nil {
-			return fmt.Errorf("route %d: invalid target URL: %w", i, err)
+		if route.Target != "" && len(route.Targets) > 0 {
+			return fmt.Errorf("route %d: target and targets cannot be combined", i)
+		}
+		targets := route.Targets
+		if route.Target != "" {
+			targets = []string{route.Target}
+		}
+		for _, t := range targets {
+			if t == "" {
+				return fmt.Errorf("route %d: empty target", i)
+			}
+			if _, err := url.Parse(t); err != nil {
+				return fmt.Errorf("route %d: invalid target URL: %w", i, err)
+			}
 		}
+		for _, m := range route.Methods {
+			if m == "" || strings.ContainsAny(m, " \t/") {
+				return fmt.Errorf("route %d: invalid method %q", i, m)
+			}
+		}
+		if route.StripPrefix && route.Rewrite != "" {
+			return fmt.Errorf("route %d: strip_prefix and rewrite cannot be combined", i)
+		}
+		if route.Rewrite != "" && !strings.HasPrefix(route.Rewrite, "/") {
+			return fmt.Errorf("route %d: rewrite must start with /", i)
+		}
+		if err := validHeaderRules(route.InjectHeaders, route.RemoveHeaders); err != nil {
+			return fmt.Errorf("route %d: %w", i, err)
+		}
+	}
+
+	// Validate named tunnels
+	names := make(map[string]bool, len(c.Tunnels))
+	for i, t := range c.Tunnels {
+		if t.Name == "" {
+			return fmt.Errorf("tunnel %d: name is required", i)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("tunnel %d: duplicate name %q", i, t.Name)
+		}
+		names[t.Name] = true
+		if t.Target == "" {
+			return fmt.Errorf("tunnel %q: target is required", t.Name)
+		}
+		if _, err := url.Parse(t.Target); err != nil {
+			return fmt.Errorf("tunnel %q: invalid target URL: %w", t.Name, err)
+		}
+	}
+	if len(c.Tunnels) > 0 && len(c.Routes) > 0 {
+		return fmt.Errorf("tunnels and routes cannot be combined")
+	}
+
+	// Validate mocks
+	for i, m := range c.Mocks {
+		if !strings.HasPrefix(m.Path, "/") {
+			return fmt.Errorf("mock %d: path must start with /", i)
+		}
+		if _, err := path.Match(m.Path, ""); err != nil {
+			return fmt.Errorf("mock %d: invalid path pattern %q: %w", i, m.Path, err)
+		}
+		if m.Status != 0 && (m.Status < 100 || m.Status > 599) {
+			return fmt.Errorf("mock %d: status must be between 100 and 599", i)
+		}
+		if m.Delay < 0 {
+			return fmt.Errorf("mock %d: delay must be non-negative", i)
+		}
+	}
+
+	// Validate path filters
+	for _, list := range []struct {
+		name     string
+		patterns []string
+	}{
+		{"accept_paths", c.AcceptPaths},
+		{"ignore_paths", c.IgnorePaths},
+	} {
+		for _, p := range list.patterns {
+			if !strings.HasPrefix(p, "/") {
+				return fmt.Errorf("%s: %q must start with /", list.name, p)
+			}
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %w", list.name, p, err)
+			}
+		}
+	}
+	if c.IgnoreStatus != 0 && (c.IgnoreStatus < 100 || c.IgnoreStatus > 599) {
+		return fmt.Errorf("invalid ignore_status: %d (must be 100-599)", c.IgnoreStatus)
+	}
+	if c.DropRate < 0 || c.DropRate > 1 {
+		return fmt.Errorf("invalid drop_rate: %g (must be 0-1)", c.DropRate)
+	}
+	if c.LogBodies && c.LogFile == "" {
+		return fmt.Errorf("log_bodies requires log_file")
 	}
 
 	return nil
 }
 
+// ValidateHostname checks that h is a plausible host[:port] value for a Host header
+func ValidateHostname(h string) error {
+	host := h
+	if strings.Contains(h, ":") {
+		var port string
+		var err error
+		host, port, err = net.SplitHostPort(h)
+		if err != nil {
+			return fmt.Errorf("%q: %w", h, err)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("%q: invalid port", h)
+		}
+	}
+	if len(host) == 0 || len(host) > 253 {
+		return fmt.Errorf("%q: invalid length", h)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("%q: invalid hostname", h)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("%q: invalid character %q", h, r)
+			}
+		}
+	}
+	return nil
+}
+
 // Example config file content
 const ExampleConfig = `# Hookshot configuration file
 
@@ -188,9 +796,65 @@ server:
   host: 0.0.0.0
   public_url: https://relay.example.com
   max_requests: 100
+  # store_path: /var/lib/hookshot/requests.db  # persist history across restarts
   token: your-secret-token
+  # tokens:                   # more tokens, revocable one by one
+  #   - name: alice
+  #     token: alice-secret
+  #     tunnel_prefix: alice- # only tunnels with IDs starting alice-
+  #   - name: ci
+  #     token: ci-secret
+  #     label: team-a         # sees tunnels opened by other team-a tokens
   # tls_cert: /path/to/cert.pem
   # tls_key: /path/to/key.pem
+  # acme_domains: [relay.example.com]  # or get certificates from Let's Encrypt (needs ports 80 and 443)
+  # acme_email: ops@example.com
+  # register_rate_limit: 30  # max new tunnels per minute (0 = unlimited)
+  # max_tunnels: 50           # max tunnels connected at once (0 = unlimited)
+  # ip_filter:                # only accept webhooks from these sources (403 otherwise)
+  #   allow: [140.82.112.0/20, 192.30.252.0/22]
+  #   deny: [192.30.252.7]
+  # trusted_proxies: [10.0.0.0/8]  # honor X-Forwarded-For from these
+  # cors:                     # let browsers on these origins send webhooks
+  #   origins: [https://app.example.com]
+  #   methods: [POST]         # default GET, POST, PUT, PATCH, DELETE
+  #   headers: [Content-Type] # default: whatever the browser asks for
+  # rate_limit: 50            # webhooks per second per tunnel; excess gets 429
+  # rate_limit_burst: 100
+  # no_tunnel_status: 503     # status when no client is connected (default 404)
+  # no_tunnel_retry_after: 30 # Retry-After seconds sent with no_tunnel_status
+  # no_tunnel_body_file: /etc/hookshot/offline.html  # or no_tunnel_body: "..." inline
+  # buffer_offline: true      # hold webhooks until a disconnected client returns
+  # offline_buffer_size: 100  # per tunnel; oldest dropped beyond this
+  # offline_buffer_age: 10m   # forget tunnels offline longer than this
+  # lock_tunnels: true        # only accept IDs listed in tunnels_file
+  # tunnels_file: /etc/hookshot/tunnels.yaml  # reloaded on SIGHUP
+  # allow_multi_client: true  # clients requesting the same tunnel_id share it
+  # balance: round-robin      # or least-in-flight, or failover (one active client, the rest standby)
+  # dashboard: true           # browser UI at /dashboard (requires token)
+  # transform_script: /etc/hookshot/transform.star  # rewrite or reject webhooks
+  # transform_timeout: 500ms
+  # sink: https://events.example.com/hookshot  # POST an event per webhook
+  # sink_payload: summary     # or full (include headers and body)
+  # sink_queue_size: 1000     # events buffered before dropping
+  # max_decompressed_bytes: 10485760  # cap on gzip-compressed response bodies
+  # max_body_size: 104857600  # cap on webhook and response bodies (default 10MB)
+  # chunk_size: 1048576      # larger bodies cross the tunnel in chunks
+  # send_buffer: 1024         # messages queued per client; webhooks get 503 once 90% full
+  # no_ws_compression: true   # disable permessage-deflate (for proxies that break it)
+  # require_upgrade_auth: true  # refuse clients that don't send the token on the upgrade (older clients)
+  # drain_timeout: 30s        # on shutdown, wait this long for in-flight webhooks
+  # response_wait: 2m         # how long a webhook waits for the client's response
+  # ping_interval: 10s        # WebSocket pings to each client (default 90% of pong_timeout)
+  # pong_timeout: 15s         # drop clients that miss pongs this long (default 60s)
+  # tunnel_idle_timeout: 24h  # close tunnels that get no webhooks for this long
+  # url_ttl: 24h              # signed public URLs that expire (410 Gone afterwards)
+  # dedup_window: 30s         # answer redelivered webhooks from cache instead of forwarding again
+  # dedup_header: X-GitHub-Delivery  # delivery ID header (default: hash of method, path and body)
+  # aliases:                  # memorable webhook URLs: /t/proj-a -> tunnel payments-dev
+  #   proj-a: payments-dev
+  #   proj-b: billing-stage
+  # alias_prefix: /           # also serve aliases at the root: /proj-a/...
 
 # Client configuration (for 'hookshot client')
 client:
@@ -198,6 +862,47 @@ client:
   tunnel_id: my-project
   token: your-secret-token
   verbose: false
+  # echo: true               # answer webhooks with a JSON summary instead of forwarding
+  # heartbeat_interval: 30s  # app-level ping to detect dead connections
+  # summary_interval: 5m      # print forwarded/failed counts and average latency
+  # heartbeat_timeout: 10s
+  # ping_interval: 10s        # WebSocket pings to the server (default 90% of pong_timeout)
+  # pong_timeout: 15s         # reconnect after this long without a pong (default 60s)
+  # max_reconnects: 10        # exit after this many failed reconnects in a row (default: retry forever)
+  # host_header: app.local   # Override the Host header sent to the target
+  # client_ip_header: X-Forwarded-For  # pass the webhook sender's IP (appended to an existing chain)
+  # transform: '{event: .type, id: .data.object.id}'  # jq expression rewriting JSON request bodies
+  # body_display_limit: 2000 # max body chars shown with --verbose (default 500)
+  # expect_status: ["2xx", "404"]  # warn when the target returns anything else
+  # async_ack: 202  # ack senders immediately; target responses are only logged
+  # max_decompressed_bytes: 10485760  # cap on gzip-compressed and chunked request bodies
+  # max_response_size: 10485760       # cap on target responses; larger ones become a 502
+  # ordered: true            # forward one webhook at a time, in arrival order
+  # max_concurrency: 8        # max webhooks forwarded at once; the rest queue
+  # accept_paths: [/github]   # only handle these paths (prefixes, or globs like /hooks/*)
+  # ignore_paths: [/health]   # answer these with ignore_status without forwarding
+  # ignore_status: 404
+  # inject_delay: 100ms-2s    # chaos testing: hold each webhook this long (fixed or a random range)
+  # drop_rate: 0.1            # chaos testing: answer this fraction of webhooks with 500, unforwarded
+  # log_file: ./hookshot-requests.jsonl  # append every forwarded request as a JSON line
+  # log_bodies: true          # include request and response bodies (base64) in log_file
+  # history_file: ./hookshot-history.jsonl  # with --tui, reload the request list on startup
+  # no_ws_compression: true   # disable permessage-deflate (for proxies that break it)
+  # inject_headers:           # added to every forwarded request (replacing any sent)
+  #   X-Internal-Auth: dev-secret
+  # remove_headers: [Authorization]  # stripped before forwarding
+  # target_header_map:        # rename headers before forwarding, keeping their values
+  #   X-Signature: X-Hub-Signature
+  # target_ca: ./certs/dev-ca.pem  # trust this CA for https:// targets
+  # insecure_skip_verify: true     # or skip target certificate checks (self-signed dev certs)
+  # relay_ca: ./certs/relay-ca.pem  # trust this CA for an https:// server
+  # insecure: true                  # or skip the server's certificate check (testing only)
+  # proxy: http://proxy.corp:3128   # reach the server through this proxy (default: HTTPS_PROXY)
+  # target_timeout: 90s      # max time per forward; capped just under the server's response_wait
+  # retry:                   # retry while the target restarts (within target_timeout)
+  #   max_attempts: 5
+  #   base_delay: 500ms      # doubled after each retry
+  #   statuses: ["502", "503", "504"]  # default 5xx; connection refused always retries
 
   # Single target (simple mode)
   target: http://localhost:3000
@@ -208,6 +913,96 @@ client:
   #     target: http://localhost:3000
   #   - path: /webhooks
   #     target: http://localhost:4000
+  #   - path: /github
+  #     target: http://localhost:5000
+  #     strip_prefix: true     # /github/webhook -> /webhook (or rewrite: /hooks)
+  #   - path: /internal
+  #     target: http://localhost:7000
+  #     inject_headers:        # Per-route headers override the client-wide inject_headers
+  #       X-Internal-Auth: dev-secret
+  #     remove_headers: [Cookie]
+  #   - path: /hooks
+  #     methods: [POST, PUT]   # Only these methods; a GET /hooks falls through to /
+  #     target: http://localhost:6000
+  #   - path: /stripe
+  #     targets:               # Fan out: every webhook goes to each target
+  #       - http://localhost:3000  # First is authoritative (its response is returned)
+  #       - http://localhost:9000  # Others get a copy; responses are logged only
   #   - path: /
   #     target: http://localhost:8080
+
+  # OR several named tunnels over one connection, each with its own URL
+  # tunnels:
+  #   - name: api
+  #     target: http://localhost:3000
+  #   - name: billing
+  #     target: http://localhost:4000
+
+  # Canned responses served without hitting a target (first match wins)
+  # mocks:
+  #   - path: /stripe/*        # Glob; * matches one path segment
+  #     method: POST
+  #     status: 503
+  #     headers:
+  #       Retry-After: "5"
+  #     body: '{"error":"unavailable"}'
+  #     delay: 2s              # Emulate a slow endpoint
+
+# Named profiles, picked with --profile or HOOKSHOT_PROFILE. A profile's
+# keys replace the same keys above; everything else is kept.
+# profiles:
+#   local:
+#     client:
+#       server: http://localhost:8080
+#       token: dev-token
+#   prod:
+#     client:
+#       server: https://relay.example.com
+#       verbose: false
 `
+
+// validIPRange reports whether s is a CIDR range or an IP
+func validIPRange(s string) bool {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		_, err := netip.ParsePrefix(s)
+		return err == nil
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// validHeaderRules checks the header names of inject_headers and remove_headers
+func validHeaderRules(inject map[string]string, remove []string) error {
+	for name := range inject {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid inject_headers name %q", name)
+		}
+	}
+	for _, name := range remove {
+		if !validHeaderName(name) {
+			return fmt.Errorf("invalid remove_headers name %q", name)
+		}
+	}
+	return nil
+}
+
+// validHeaderName rejects empty names and ones with whitespace or a colon
+func validHeaderName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t:")
+}
+
+// validKeepalive checks ping_interval against pong_timeout (or its 60s
+// default); pings must come often enough to answer in time
+func validKeepalive(ping, pong time.Duration) error {
+	if ping < 0 || pong < 0 {
+		return fmt.Errorf("ping_interval and pong_timeout must be >= 0")
+	}
+	if pong == 0 {
+		pong = 60 * time.Second
+	}
+	if ping >= pong {
+		return fmt.Errorf("invalid ping_interval: %s (must be shorter than pong_timeout %s)", ping, pong)
+	}
+	return nil
+}
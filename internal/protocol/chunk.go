@@ -0,0 +1,120 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// DefaultChunkSize is the largest body sent inline; bigger bodies are
+	// split into chunks of this size
+	DefaultChunkSize = 1024 * 1024
+
+	// maxPendingBodies caps bodies being reassembled at once per connection
+	maxPendingBodies = 64
+)
+
+// ErrBodyTooLarge is returned when a chunked body grows past the limit
+var ErrBodyTooLarge = errors.New("chunked body exceeds limit")
+
+// BodyChunk carries part of a large request or response body. The request or
+// response itself is sent first with Chunked set and no Body; its chunks
+// follow in order, the last one with Final set.
+type BodyChunk struct {
+	ID    string `json:"id"`  // Request ID
+	Seq   int    `json:"seq"` // Position, starting at 0
+	Data  []byte `json:"data"`
+	Final bool   `json:"final,omitempty"`
+}
+
+// SplitBody splits a body into chunks of at most size bytes
+func SplitBody(id string, body []byte, size int) []BodyChunk {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	chunks := make([]BodyChunk, 0, (len(body)+size-1)/size)
+	for seq := 0; len(body) > 0; seq++ {
+		n := min(size, len(body))
+		chunks = append(chunks, BodyChunk{ID: id, Seq: seq, Data: body[:n]})
+		body = body[n:]
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, BodyChunk{ID: id})
+	}
+	chunks[len(chunks)-1].Final = true
+	return chunks
+}
+
+// Reassembler collects chunked bodies until their final chunk arrives. T is
+// the request or response the chunks belong to. Senders always finish a
+// sequence they start, so bodies are only left incomplete when the
+// connection drops, and the reassembler goes with it.
+type Reassembler[T any] struct {
+	mu      sync.Mutex
+	limit   int64 // Max body size (0 = unlimited)
+	pending map[string]*partialBody[T]
+}
+
+type partialBody[T any] struct {
+	msg    T
+	next   int // Expected Seq
+	body   []byte
+	failed bool // Already reported; the rest of the sequence is discarded
+}
+
+// NewReassembler creates a reassembler for bodies of up to limit bytes
+// (0 = unlimited)
+func NewReassembler[T any](limit int64) *Reassembler[T] {
+	return &Reassembler[T]{limit: limit, pending: make(map[string]*partialBody[T])}
+}
+
+// Expect starts collecting the body of msg
+func (r *Reassembler[T]) Expect(id string, msg T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.pending[id]; dup {
+		return fmt.Errorf("duplicate chunked body for %s", id)
+	}
+	if len(r.pending) >= maxPendingBodies {
+		return fmt.Errorf("too many chunked bodies in progress (max %d)", maxPendingBodies)
+	}
+	r.pending[id] = &partialBody[T]{msg: msg}
+	return nil
+}
+
+// Add appends a chunk. When the final chunk arrives it returns the message
+// and its complete body with done set. The first error for a body returns
+// the message it belonged to (the zero value for an unknown ID), so its
+// sender can be answered; the body's remaining chunks are then discarded.
+func (r *Reassembler[T]) Add(c *BodyChunk) (msg T, body []byte, done bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.pending[c.ID]
+	if !ok {
+		return msg, nil, false, fmt.Errorf("chunk for unknown body %s", c.ID)
+	}
+	if c.Final {
+		delete(r.pending, c.ID)
+	}
+	switch {
+	case p.failed:
+		return msg, nil, false, nil
+	case c.Seq != p.next:
+		err = fmt.Errorf("chunk %d out of order (expected %d)", c.Seq, p.next)
+	case r.limit > 0 && int64(len(p.body)+len(c.Data)) > r.limit:
+		err = ErrBodyTooLarge
+	}
+	if err != nil {
+		p.failed, p.body = true, nil
+		return p.msg, nil, false, err
+	}
+
+	p.body = append(p.body, c.Data...)
+	p.next++
+	if !c.Final {
+		return msg, nil, false, nil
+	}
+	return p.msg, p.body, true, nil
+}
@@ -0,0 +1,113 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Capabilities negotiated at registration
+const (
+	CapGzip    = "gzip"    // Bodies may be gzip-compressed on the wire
+	CapChunked = "chunked" // Large bodies may be split into chunk messages
+)
+
+// Body encodings for HTTPRequest/HTTPResponse
+const (
+	BodyEncodingGzip = "gzip"
+)
+
+const (
+	// CompressThreshold is the smallest body worth compressing
+	CompressThreshold = 1024
+
+	// DefaultMaxDecompressedBytes caps decompressed bodies (matches the default body size limit)
+	DefaultMaxDecompressedBytes = 10 * 1024 * 1024
+)
+
+// ErrDecompressedTooLarge is returned when a body inflates past the limit
+var ErrDecompressedTooLarge = errors.New("decompressed body exceeds limit")
+
+// TruncatedHeader marks a body cut short at the decompression limit; its
+// value is the limit in bytes
+const TruncatedHeader = "X-Hookshot-Truncated"
+
+// MarkTruncated returns a copy of headers with TruncatedHeader set, leaving
+// the original (which may be shared with stored requests) untouched
+func MarkTruncated(headers Headers, limit int64) Headers {
+	out := make(Headers, len(headers)+1)
+	for k, v := range headers {
+		out[k] = v
+	}
+	out[TruncatedHeader] = []string{strconv.FormatInt(limit, 10)}
+	return out
+}
+
+// HasCapability reports whether caps contains c
+func HasCapability(caps []string, c string) bool {
+	for _, v := range caps {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// CompressBody gzips body when it is at least CompressThreshold bytes and
+// compression actually shrinks it. It returns the body to send and its
+// encoding ("" if left uncompressed).
+func CompressBody(body []byte) ([]byte, string) {
+	if len(body) < CompressThreshold {
+		return body, ""
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return body, ""
+	}
+	if err := zw.Close(); err != nil {
+		return body, ""
+	}
+	if buf.Len() >= len(body) {
+		return body, ""
+	}
+	return buf.Bytes(), BodyEncodingGzip
+}
+
+// DecompressBody reverses CompressBody. Output is capped at maxSize bytes
+// (maxSize <= 0 uses DefaultMaxDecompressedBytes); past the cap the body is
+// truncated and ErrDecompressedTooLarge is returned with it.
+func DecompressBody(body []byte, encoding string, maxSize int64) ([]byte, error) {
+	switch encoding {
+	case "":
+		return body, nil
+	case BodyEncodingGzip:
+	default:
+		return nil, fmt.Errorf("unsupported body encoding %q", encoding)
+	}
+
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedBytes
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip body: %w", err)
+	}
+	defer zr.Close()
+
+	// Read one byte past the limit to detect oversized bodies without
+	// inflating them fully
+	out, err := io.ReadAll(io.LimitReader(zr, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip body: %w", err)
+	}
+	if int64(len(out)) > maxSize {
+		return out[:maxSize], ErrDecompressedTooLarge
+	}
+	return out, nil
+}
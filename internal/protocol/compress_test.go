@@ -0,0 +1,72 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+// gzipZeros returns a gzip stream of n zero bytes, which compresses to a
+// tiny fraction of its size
+func gzipZeros(t *testing.T, n int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	chunk := make([]byte, 64*1024)
+	for n > 0 {
+		size := min(n, len(chunk))
+		if _, err := zw.Write(chunk[:size]); err != nil {
+			t.Fatal(err)
+		}
+		n -= size
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressBodyCapsGzipBomb(t *testing.T) {
+	const inflated = 100 << 20 // 100MB
+	const limit = 1 << 20      // 1MB
+	bomb := gzipZeros(t, inflated)
+	if len(bomb) > limit/4 {
+		t.Fatalf("test stream is %d bytes, want a small input", len(bomb))
+	}
+
+	out, err := DecompressBody(bomb, BodyEncodingGzip, limit)
+	if !errors.Is(err, ErrDecompressedTooLarge) {
+		t.Fatalf("err = %v, want ErrDecompressedTooLarge", err)
+	}
+	if len(out) != limit {
+		t.Fatalf("got %d bytes, want output truncated to %d", len(out), limit)
+	}
+}
+
+func TestDecompressBodyRoundTrip(t *testing.T) {
+	body := bytes.Repeat([]byte("hookshot "), 1000)
+	wire, enc := CompressBody(body)
+	if enc != BodyEncodingGzip {
+		t.Fatalf("encoding = %q, want %q", enc, BodyEncodingGzip)
+	}
+
+	out, err := DecompressBody(wire, enc, int64(len(body)))
+	if err != nil {
+		t.Fatalf("DecompressBody: %v", err)
+	}
+	if !bytes.Equal(out, body) {
+		t.Fatal("round trip changed the body")
+	}
+}
+
+func TestMarkTruncatedCopiesHeaders(t *testing.T) {
+	orig := Headers{"Content-Type": {"application/json"}}
+	marked := MarkTruncated(orig, 1024)
+	if got := marked[TruncatedHeader]; len(got) != 1 || got[0] != "1024" {
+		t.Fatalf("%s = %v, want [1024]", TruncatedHeader, got)
+	}
+	if _, ok := orig[TruncatedHeader]; ok {
+		t.Fatal("MarkTruncated modified the original headers")
+	}
+}
@@ -0,0 +1,86 @@
+package protocol
+
+import (
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// textSniffBytes is how much of a body of unknown type is checked for
+// control characters
+const textSniffBytes = 512
+
+// textContentTypes are non-text/* media types whose bodies are readable
+var textContentTypes = map[string]bool{
+	"application/json":                  true,
+	"application/x-ndjson":              true,
+	"application/xml":                   true,
+	"application/x-www-form-urlencoded": true,
+	"application/javascript":            true,
+	"application/graphql":               true,
+	"application/yaml":                  true,
+	"application/x-yaml":                true,
+	"application/toml":                  true,
+}
+
+// binaryContentTypes are media types whose bodies are never shown as text,
+// besides image/, audio/, video/ and font/
+var binaryContentTypes = map[string]bool{
+	"application/octet-stream":        true,
+	"application/pdf":                 true,
+	"application/zip":                 true,
+	"application/gzip":                true,
+	"application/x-gzip":              true,
+	"application/protobuf":            true,
+	"application/x-protobuf":          true,
+	"application/vnd.google.protobuf": true,
+	"application/msgpack":             true,
+	"application/x-msgpack":           true,
+	"application/cbor":                true,
+	"application/wasm":                true,
+}
+
+// IsTextBody reports whether a body can be shown as text. The Content-Type
+// decides when it is a known text type (the body must still be UTF-8) or
+// binary type, and a Content-Encoding such as gzip always means binary.
+// Otherwise the body must be valid UTF-8 with few control characters.
+func IsTextBody(body []byte, contentType, contentEncoding string) bool {
+	if len(body) == 0 {
+		return false
+	}
+	if enc := strings.ToLower(strings.TrimSpace(contentEncoding)); enc != "" && enc != "identity" {
+		return false
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch {
+		case strings.HasPrefix(mediaType, "text/"), textContentTypes[mediaType],
+			strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+			return utf8.Valid(body)
+		case binaryContentTypes[mediaType], strings.HasPrefix(mediaType, "application/grpc"),
+			strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"),
+			strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "font/"):
+			return false
+		}
+	}
+	return looksLikeText(body)
+}
+
+// looksLikeText is the fallback for unknown types: valid UTF-8 with under
+// 10% control characters in the first textSniffBytes
+func looksLikeText(body []byte) bool {
+	if !utf8.Valid(body) {
+		return false
+	}
+	sample := body
+	if len(sample) > textSniffBytes {
+		sample = sample[:textSniffBytes]
+	}
+	controlChars := 0
+	for _, b := range sample {
+		if b < 32 && b != '\n' && b != '\r' && b != '\t' {
+			controlChars++
+		}
+	}
+	return float64(controlChars)/float64(len(sample)) < 0.1
+}
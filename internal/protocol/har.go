@@ -0,0 +1,243 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// HAR 1.2 archive types (http://www.softwareishard.com/blog/har-12-spec/).
+// Only the fields hookshot can fill are included.
+type (
+	HAR struct {
+		Log HARLog `json:"log"`
+	}
+
+	HARLog struct {
+		Version string     `json:"version"`
+		Creator HARCreator `json:"creator"`
+		Entries []HAREntry `json:"entries"`
+	}
+
+	HARCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	HAREntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         HARRequest  `json:"request"`
+		Response        HARResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         HARTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+
+	HARRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []HARNameValue `json:"cookies"`
+		Headers     []HARNameValue `json:"headers"`
+		QueryString []HARNameValue `json:"queryString"`
+		PostData    *HARPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+
+	HARResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Cookies     []HARNameValue `json:"cookies"`
+		Headers     []HARNameValue `json:"headers"`
+		Content     HARContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+
+	HARNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	// HARPostData has no standard encoding field; binary bodies are base64
+	// with the custom "_encoding" field set, as content does for responses
+	HARPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"_encoding,omitempty"`
+	}
+
+	HARContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+
+	HARTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// NewHAR creates an empty HAR 1.2 archive created by hookshot version
+func NewHAR(version string) *HAR {
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "hookshot", Version: version},
+		Entries: []HAREntry{},
+	}}
+}
+
+// NewHAREntry converts a relayed request and its response, which may be
+// nil, into a HAR entry. baseURL is prepended to the request's path.
+func NewHAREntry(baseURL string, req *HTTPRequest, resp *HTTPResponse) HAREntry {
+	entry := HAREntry{
+		StartedDateTime: req.Timestamp.Format(time.RFC3339Nano),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         baseURL + req.Path,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(req.Headers),
+			QueryString: harQuery(req.Path),
+			HeadersSize: -1,
+			BodySize:    len(req.Body),
+		},
+		Comment: "hookshot request " + req.ID,
+	}
+	if len(req.Body) > 0 {
+		text, enc := harBody(req.Body)
+		entry.Request.PostData = &HARPostData{
+			MimeType: req.Headers.Get("Content-Type"),
+			Text:     text,
+			Encoding: enc,
+		}
+	}
+
+	if resp != nil {
+		text, enc := harBody(resp.Body)
+		statusText := resp.StatusText
+		if statusText == "" {
+			statusText = http.StatusText(resp.StatusCode)
+		}
+		entry.Response = HARResponse{
+			Status:      resp.StatusCode,
+			StatusText:  statusText,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(resp.Headers),
+			Content: HARContent{
+				Size:     len(resp.Body),
+				MimeType: resp.Headers.Get("Content-Type"),
+				Text:     text,
+				Encoding: enc,
+			},
+			RedirectURL: resp.Headers.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(resp.Body),
+		}
+		if resp.Error != "" {
+			entry.Comment += ": " + resp.Error
+		}
+	} else {
+		// No response recorded (still pending, timed out, or buffered)
+		entry.Response = HARResponse{
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+	}
+	return entry
+}
+
+// HTTPRequest converts an entry back into a relayed request. The path is
+// the URL's path and query; the scheme and host are dropped.
+func (e HAREntry) HTTPRequest() (*HTTPRequest, error) {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request URL %q: %w", e.Request.URL, err)
+	}
+	req := &HTTPRequest{
+		Method:  e.Request.Method,
+		Path:    u.RequestURI(),
+		Headers: make(Headers),
+	}
+	// Entries written by hookshot are named after the request
+	if id, ok := strings.CutPrefix(e.Comment, "hookshot request "); ok {
+		req.ID, _, _ = strings.Cut(id, ":")
+	}
+	req.Timestamp, _ = time.Parse(time.RFC3339Nano, e.StartedDateTime)
+	for _, h := range e.Request.Headers {
+		req.Headers[h.Name] = append(req.Headers[h.Name], h.Value)
+	}
+	if pd := e.Request.PostData; pd != nil {
+		if pd.Encoding == "base64" {
+			if req.Body, err = base64.StdEncoding.DecodeString(pd.Text); err != nil {
+				return nil, fmt.Errorf("invalid base64 request body: %w", err)
+			}
+		} else {
+			req.Body = []byte(pd.Text)
+		}
+	}
+	return req, nil
+}
+
+// harHeaders flattens headers into sorted name/value pairs
+func harHeaders(h Headers) []HARNameValue {
+	names := make([]string, 0, len(h))
+	for k := range h {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	out := make([]HARNameValue, 0, len(h))
+	for _, k := range names {
+		for _, v := range h[k] {
+			out = append(out, HARNameValue{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+// harQuery parses the query string of a relayed path, keeping parameters
+// in their original order
+func harQuery(path string) []HARNameValue {
+	out := []HARNameValue{}
+	_, raw, _ := strings.Cut(path, "?")
+	for _, part := range strings.Split(raw, "&") {
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		if uk, err := url.QueryUnescape(k); err == nil {
+			k = uk
+		}
+		if uv, err := url.QueryUnescape(v); err == nil {
+			v = uv
+		}
+		out = append(out, HARNameValue{Name: k, Value: v})
+	}
+	return out
+}
+
+// harBody returns a body as text, or base64 with encoding "base64" when it
+// isn't valid UTF-8
+func harBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
@@ -1,9 +1,14 @@
 package protocol
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
 // Message types for WebSocket communication
@@ -12,11 +17,47 @@ const (
 	TypeRegistered = "registered"
 	TypeRequest    = "request"
 	TypeResponse   = "response"
+	TypeChunk      = "chunk" // Part of a large request or response body
 	TypePing       = "ping"
 	TypePong       = "pong"
 	TypeError      = "error"
 )
 
+// ErrCodeIdleTimeout is sent in an error message when the server closes a
+// connection whose tunnels saw no webhooks for too long. Clients should not
+// reconnect.
+const ErrCodeIdleTimeout = "idle_timeout"
+
+// Codes the server rejects or closes a connection with, in an error message
+// or as the reason of the WebSocket close frame
+const (
+	ErrCodeUnauthorized   = "unauthorized"     // Missing, wrong or revoked token
+	ErrCodeForbidden      = "forbidden"        // The token may not register the requested ID
+	ErrCodeUnknownTunnel  = "unknown_tunnel"   // The relay only accepts pre-registered IDs, and this one isn't (or no longer is)
+	ErrCodeRateLimited    = "rate_limited"     // Too many registrations; try again later
+	ErrCodeTooManyTunnels = "too_many_tunnels" // The relay is at its tunnel limit; try again later
+	ErrCodeRegisterFailed = "register_failed"  // The registration was invalid or couldn't be completed
+	ErrCodeShutdown       = "server_shutdown"  // The server is restarting or stopping; reconnect
+)
+
+// FatalErrorCode reports whether a client rejected or closed with code should
+// stop instead of reconnecting, since trying again would fail the same way
+func FatalErrorCode(code string) bool {
+	switch code {
+	case ErrCodeUnauthorized, ErrCodeForbidden, ErrCodeUnknownTunnel, ErrCodeIdleTimeout:
+		return true
+	}
+	return false
+}
+
+// Subprotocol is the WebSocket subprotocol clients offer when connecting. A
+// server that selects it checked any Authorization header on the upgrade.
+const Subprotocol = "hookshot.v1"
+
+// ResponseTimeout is how long the server waits by default for a client's
+// response to a forwarded request
+const ResponseTimeout = 30 * time.Second
+
 // Message is the envelope for all WebSocket messages
 type Message struct {
 	Type    string          `json:"type"`
@@ -27,31 +68,95 @@ type Message struct {
 type RegisterPayload struct {
 	TunnelID string `json:"tunnel_id,omitempty"` // Optional: client-requested ID
 	Token    string `json:"token,omitempty"`     // Optional: auth token
+	AsyncAck int    `json:"async_ack,omitempty"` // Optional: 2xx status the server acks webhooks with before forwarding
+
+	ResumeToken string `json:"resume_token,omitempty"` // Optional: reclaims TunnelID after a disconnect
+
+	Tunnels []TunnelSpec `json:"tunnels,omitempty"` // Optional: register several tunnels on this connection
+
+	Capabilities []string `json:"capabilities,omitempty"` // Optional protocol features the client supports (e.g., "gzip")
+}
+
+// TunnelSpec requests one tunnel in a multi-tunnel registration
+type TunnelSpec struct {
+	Name        string `json:"name"`                   // Client-side label, echoed back
+	TunnelID    string `json:"tunnel_id,omitempty"`    // Optional: requested ID
+	ResumeToken string `json:"resume_token,omitempty"` // Optional: reclaims TunnelID after a disconnect
 }
 
 // RegisteredPayload is sent by server to confirm registration
 type RegisteredPayload struct {
-	TunnelID  string `json:"tunnel_id"`
-	PublicURL string `json:"public_url"`
+	TunnelID     string `json:"tunnel_id"`
+	PublicURL    string `json:"public_url"`
+	ConnectionID string `json:"connection_id,omitempty"` // Server-side connection ID for log correlation
+	AsyncAck     int    `json:"async_ack,omitempty"`     // Echoes the accepted async ack status
+	ResumeToken  string `json:"resume_token,omitempty"`  // Send on reconnect to keep this tunnel ID
+	Buffered     int    `json:"buffered,omitempty"`      // Webhooks buffered while offline, delivered next
+	URLExpires   int64  `json:"url_expires,omitempty"`   // Unix time PublicURL's signature expires (0 = never)
+
+	Tunnels []RegisteredTunnel `json:"tunnels,omitempty"` // One per requested TunnelSpec, in order
+
+	Capabilities []string `json:"capabilities,omitempty"` // Client capabilities the server accepted
+	ChunkSize    int      `json:"chunk_size,omitempty"`   // With CapChunked: split bodies larger than this
+
+	ResponseWaitMs int64 `json:"response_wait_ms,omitempty"` // How long the server waits for each response (0 = ResponseTimeout)
+}
+
+// RegisteredTunnel describes one tunnel assigned in a multi-tunnel registration
+type RegisteredTunnel struct {
+	Name        string `json:"name"`
+	TunnelID    string `json:"tunnel_id"`
+	PublicURL   string `json:"public_url"`
+	ResumeToken string `json:"resume_token,omitempty"`
+	Buffered    int    `json:"buffered,omitempty"`
+	URLExpires  int64  `json:"url_expires,omitempty"`
 }
 
 // HTTPRequest represents an incoming webhook request to be forwarded
 type HTTPRequest struct {
-	ID        string            `json:"id"`
-	TunnelID  string            `json:"tunnel_id,omitempty"` // For ownership verification
-	Method    string            `json:"method"`
-	Path      string            `json:"path"`
-	Headers   map[string]string `json:"headers"`
-	Body      []byte            `json:"body"`
-	Timestamp time.Time         `json:"timestamp"`
+	ID        string    `json:"id"`
+	TunnelID  string    `json:"tunnel_id,omitempty"` // For ownership verification
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Headers   Headers   `json:"headers"`
+	Body      []byte    `json:"body"`
+	Timestamp time.Time `json:"timestamp"`
+	Verified  bool      `json:"verified,omitempty"`  // Server checked the webhook's HMAC signature
+	RemoteIP  string    `json:"remote_ip,omitempty"` // Webhook sender's address (behind trusted proxies, the original client)
+
+	BodyEncoding string `json:"body_encoding,omitempty"` // "gzip" if Body is compressed on the wire
+	Chunked      bool   `json:"chunked,omitempty"`       // Body follows in chunk messages
 }
 
 // HTTPResponse represents the response from the local server
 type HTTPResponse struct {
-	RequestID  string            `json:"request_id"`
-	StatusCode int               `json:"status_code"`
-	Headers    map[string]string `json:"headers"`
-	Body       []byte            `json:"body"`
+	RequestID  string  `json:"request_id"`
+	StatusCode int     `json:"status_code"`
+	StatusText string  `json:"status_text,omitempty"` // Reason phrase the target sent (e.g., "Not Found")
+	Headers    Headers `json:"headers"`
+	Body       []byte  `json:"body"`
+	Target     string  `json:"target,omitempty"` // Local target the client forwarded to
+	Error      string  `json:"error,omitempty"`  // Why the client couldn't forward (with a 502)
+
+	BodyEncoding string `json:"body_encoding,omitempty"` // "gzip" if Body is compressed on the wire
+	Chunked      bool   `json:"chunked,omitempty"`       // Body follows in chunk messages
+}
+
+// Status returns the status code with its reason phrase, e.g. "404 Not Found"
+func (r *HTTPResponse) Status() string {
+	return StatusLine(r.StatusCode, r.StatusText)
+}
+
+// StatusLine formats a status code with a reason phrase, using the standard
+// one when text is empty
+func StatusLine(code int, text string) string {
+	if text == "" {
+		text = http.StatusText(code)
+	}
+	if text == "" {
+		return strconv.Itoa(code)
+	}
+	return strconv.Itoa(code) + " " + text
 }
 
 // ErrorPayload represents an error message
@@ -77,22 +182,111 @@ func (m *Message) ParsePayload(v interface{}) error {
 	return json.Unmarshal(m.Payload, v)
 }
 
-// HeadersFromHTTP converts http.Header to a simple map
-func HeadersFromHTTP(h http.Header) map[string]string {
-	result := make(map[string]string)
+// Headers maps header names to all of their values. On the wire a header
+// with a single value is encoded as a string and a repeated header as an
+// array, so peers that predate multi-value headers still decode the common
+// case; both shapes are accepted when decoding.
+type Headers map[string][]string
+
+// Get returns the first value for a header name (exact match)
+func (h Headers) Get(name string) string {
+	if v := h[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// MarshalJSON encodes single values as strings and repeated values as arrays
+func (h Headers) MarshalJSON() ([]byte, error) {
+	if h == nil {
+		return []byte("null"), nil
+	}
+	out := make(map[string]interface{}, len(h))
+	for k, v := range h {
+		if len(v) == 1 {
+			out[k] = v[0]
+		} else {
+			out[k] = v
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON accepts both string and array header values
+func (h *Headers) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*h = nil
+		return nil
+	}
+	result := make(Headers, len(raw))
+	for k, v := range raw {
+		var single string
+		if err := json.Unmarshal(v, &single); err == nil {
+			result[k] = []string{single}
+			continue
+		}
+		var multi []string
+		if err := json.Unmarshal(v, &multi); err != nil {
+			return fmt.Errorf("header %q: expected string or array of strings", k)
+		}
+		result[k] = multi
+	}
+	*h = result
+	return nil
+}
+
+// HeadersFromHTTP converts http.Header to Headers, keeping every value
+func HeadersFromHTTP(h http.Header) Headers {
+	result := make(Headers, len(h))
 	for k, v := range h {
 		if len(v) > 0 {
-			result[k] = v[0]
+			result[k] = append([]string(nil), v...)
 		}
 	}
 	return result
 }
 
-// HeadersToHTTP converts a simple map back to http.Header
-func HeadersToHTTP(h map[string]string) http.Header {
-	result := make(http.Header)
+// HeadersToHTTP converts Headers back to http.Header
+func HeadersToHTTP(h Headers) http.Header {
+	result := make(http.Header, len(h))
 	for k, v := range h {
-		result.Set(k, v)
+		for _, value := range v {
+			result.Add(k, value)
+		}
 	}
 	return result
 }
+
+// sensitiveField matches JSON string fields that may carry credentials
+var sensitiveField = regexp.MustCompile(`(?i)"([^"]*(token|authorization|cookie|secret|password|api[-_]?key)[^"]*)"\s*:\s*(?:"(?:[^"\\]|\\.)*"|\[(?:\s*"(?:[^"\\]|\\.)*"\s*,?)*\s*\])`)
+
+// DumpFrame renders a raw WebSocket frame for debug logs: credential-like
+// fields are redacted and the output is truncated to maxLen bytes.
+// Non-UTF-8 frames are hex encoded.
+func DumpFrame(data []byte, maxLen int) string {
+	truncated := len(data) > maxLen
+	if utf8.Valid(data) {
+		redacted := sensitiveField.ReplaceAll(data, []byte(`"$1":"[REDACTED]"`))
+		if len(redacted) > maxLen {
+			redacted = redacted[:maxLen]
+		}
+		out := fmt.Sprintf("%q", redacted)
+		if truncated {
+			out += fmt.Sprintf(" ... (%d bytes total)", len(data))
+		}
+		return out
+	}
+
+	if len(data) > maxLen/2 {
+		data = data[:maxLen/2]
+	}
+	out := "hex:" + hex.EncodeToString(data)
+	if truncated {
+		out += " ..."
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeChallengePort is where ACME CAs send HTTP-01 challenges
+const acmeChallengePort = 80
+
+// validateACME checks the ACME settings and fills in the public URL from the
+// first domain when none is set
+func (s *Server) validateACME() error {
+	domains := s.config.ACMEDomains
+	if len(domains) == 0 {
+		return nil
+	}
+	if s.config.TLSCert != "" || s.config.TLSKey != "" {
+		return errors.New("ACME domains and a TLS cert/key are mutually exclusive")
+	}
+	for _, d := range domains {
+		if d == "" || strings.ContainsAny(d, "/:*") {
+			return fmt.Errorf("invalid ACME domain %q (a plain hostname, without scheme, port or wildcard)", d)
+		}
+	}
+
+	if s.config.PublicURL == "" {
+		u := url.URL{Scheme: "https", Host: domains[0]}
+		if s.config.Port != 443 {
+			u.Host = net.JoinHostPort(domains[0], strconv.Itoa(s.config.Port))
+		}
+		s.config.PublicURL = u.String()
+		return nil
+	}
+	u, err := url.Parse(s.config.PublicURL)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("public URL %q must be https:// with ACME", s.config.PublicURL)
+	}
+	if !slices.Contains(domains, u.Hostname()) {
+		return fmt.Errorf("public URL host %q is not one of the ACME domains", u.Hostname())
+	}
+	return nil
+}
+
+// acmeManager creates the certificate manager for the configured domains.
+// Certificates are cached on disk so restarts don't hit the CA's rate limits.
+func (s *Server) acmeManager() (*autocert.Manager, error) {
+	dir := s.config.ACMECacheDir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("no ACME cache dir: %w", err)
+		}
+		dir = filepath.Join(cache, "hookshot", "acme")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create ACME cache dir: %w", err)
+	}
+	log.Printf("obtaining certificates for %s via ACME (cache: %s)", strings.Join(s.config.ACMEDomains, ", "), dir)
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(dir),
+		HostPolicy: autocert.HostWhitelist(s.config.ACMEDomains...),
+		Email:      s.config.ACMEEmail,
+	}, nil
+}
+
+// acmeChallengeServer answers HTTP-01 challenges on port 80 and redirects
+// everything else to HTTPS
+func (s *Server) acmeChallengeServer(m *autocert.Manager) *http.Server {
+	return &http.Server{
+		Addr:              net.JoinHostPort(s.config.Host, strconv.Itoa(acmeChallengePort)),
+		Handler:           m.HTTPHandler(nil),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// reservedAliases are taken by the server's own routes, so they can't be
+// aliases served at the root
+var reservedAliases = []string{"t", "api", "ws", "health", "ready", "dashboard"}
+
+// validAlias reports whether name can be an alias: 1-64 chars of letters,
+// digits, - or _
+func validAlias(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateAliases checks the aliases and their prefix, normalizing the
+// prefix to have no trailing slash ("" for the root)
+func (s *Server) validateAliases() error {
+	for name, id := range s.config.Aliases {
+		if !validAlias(name) {
+			return fmt.Errorf("invalid alias %q (1-64 chars of letters, digits, - or _)", name)
+		}
+		if !validTunnelID(id) {
+			return fmt.Errorf("alias %s: invalid tunnel ID %q", name, id)
+		}
+	}
+
+	prefix := s.config.AliasPrefix
+	if prefix == "" {
+		return nil
+	}
+	if len(s.config.Aliases) == 0 {
+		return fmt.Errorf("alias prefix %q set without any aliases", prefix)
+	}
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "{}?#") {
+		return fmt.Errorf("invalid alias prefix %q (a path such as / or /hooks)", prefix)
+	}
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "/t" || prefix == "/api" {
+		return fmt.Errorf("alias prefix %q overlaps the server's own routes", s.config.AliasPrefix)
+	}
+	if prefix == "" {
+		for name := range s.config.Aliases {
+			if slices.Contains(reservedAliases, name) {
+				return fmt.Errorf("alias %q can't be served at / (the server uses that path)", name)
+			}
+		}
+	}
+	s.aliasPrefix = &prefix
+	return nil
+}
+
+// resolveTunnel returns the tunnel ID an alias stands for, or name itself
+// when it isn't an alias
+func (s *Server) resolveTunnel(name string) string {
+	if id, ok := s.config.Aliases[name]; ok {
+		return id
+	}
+	return name
+}
+
+// aliased reports whether an alias points at tunnelID, which clients may
+// then request like a bound ID
+func (s *Server) aliased(tunnelID string) bool {
+	for _, id := range s.config.Aliases {
+		if id == tunnelID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAliasWebhook serves webhooks under AliasPrefix, at
+// {AliasPrefix}/{alias}/...
+func (s *Server) handleAliasWebhook(w http.ResponseWriter, r *http.Request) {
+	alias := mux.Vars(r)["alias"]
+	s.serveWebhook(w, r, alias, *s.aliasPrefix+"/"+alias)
+}
+
+// isAlias matches requests whose {alias} is a configured alias, so paths
+// under the alias prefix that aren't aliases fall through to a 404
+func (s *Server) isAlias(r *http.Request, m *mux.RouteMatch) bool {
+	target := strings.TrimPrefix(r.URL.Path, *s.aliasPrefix+"/")
+	name, _, _ := strings.Cut(target, "/")
+	_, ok := s.config.Aliases[name]
+	return ok
+}
+
+// aliasURLs lists each alias's public URL, for the startup log
+func (s *Server) aliasURLs() []string {
+	names := make([]string, 0, len(s.config.Aliases))
+	for name := range s.config.Aliases {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	base := strings.TrimRight(s.publicBaseURL(), "/")
+	urls := make([]string, 0, len(names))
+	for _, name := range names {
+		u := base + "/t/" + name
+		if s.aliasPrefix != nil {
+			u = base + *s.aliasPrefix + "/" + name
+		}
+		urls = append(urls, fmt.Sprintf("%s -> tunnel %s", u, s.config.Aliases[name]))
+	}
+	return urls
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxTunnelPrefix keeps prefixed UUIDs within the 64-char tunnel ID limit
+const maxTunnelPrefix = 64 - 36
+
+// AuthToken is one accepted auth token. A token with a TunnelPrefix or Label
+// is scoped: it can only register tunnels within its scope, and API calls
+// made with it only see those tunnels.
+type AuthToken struct {
+	Name         string // Shown in logs (e.g., "alice")
+	Token        string
+	TunnelPrefix string // Optional: tunnel IDs must start with this (generated IDs get it prepended)
+	Label        string // Optional: tunnels registered with this token are labeled; the API shows tunnels with the same label
+}
+
+// validate checks a token's settings
+func (t *AuthToken) validate() error {
+	if t.Token == "" {
+		return fmt.Errorf("token %q: token is required", t.Name)
+	}
+	if len(t.TunnelPrefix) > maxTunnelPrefix {
+		return fmt.Errorf("token %q: tunnel prefix longer than %d characters", t.Name, maxTunnelPrefix)
+	}
+	for _, c := range t.TunnelPrefix {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("token %q: tunnel prefix may only contain letters, digits, '-' and '_'", t.Name)
+		}
+	}
+	return nil
+}
+
+// scoped reports whether the token is restricted to some tunnels. A nil
+// token (auth disabled, or a tunnel's own bound token) is unrestricted.
+func (t *AuthToken) scoped() bool {
+	return t != nil && (t.TunnelPrefix != "" || t.Label != "")
+}
+
+// canRegister reports whether the token may register the given requested ID
+func (t *AuthToken) canRegister(tunnelID string) bool {
+	return t == nil || t.TunnelPrefix == "" || strings.HasPrefix(tunnelID, t.TunnelPrefix)
+}
+
+// allows reports whether the token may access a tunnel. label is the label of
+// the token the tunnel registered with ("" if unknown, e.g., once the tunnel
+// is disconnected).
+func (t *AuthToken) allows(tunnelID, label string) bool {
+	if !t.scoped() {
+		return true
+	}
+	if t.TunnelPrefix != "" && strings.HasPrefix(tunnelID, t.TunnelPrefix) {
+		return true
+	}
+	return t.Label != "" && t.Label == label
+}
+
+// displayName names the token in logs
+func (t *AuthToken) displayName() string {
+	if t == nil {
+		return ""
+	}
+	if t.Name != "" {
+		return t.Name
+	}
+	return "unnamed"
+}
+
+// authEnabled reports whether clients and the API need a token
+func (s *Server) authEnabled() bool {
+	return len(s.tokens) > 0
+}
+
+// matchToken returns the configured token equal to tok, or nil
+func (s *Server) matchToken(tok string) *AuthToken {
+	if tok == "" {
+		return nil
+	}
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(tok), []byte(t.Token)) == 1 {
+			return t
+		}
+	}
+	return nil
+}
+
+// upgradeToken returns the bearer token sent with a WebSocket upgrade and
+// whether the upgrade may go ahead. With auth enabled, a token matching
+// neither a server token nor a bound ID's token is refused, as is a missing
+// one with RequireUpgradeAuth. Clients without the header still send their
+// token in the register message.
+func (s *Server) upgradeToken(r *http.Request) (string, bool) {
+	tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || tok == "" {
+		return "", !s.config.RequireUpgradeAuth
+	}
+	if !s.authEnabled() && !s.config.RequireUpgradeAuth {
+		return tok, true
+	}
+	if s.matchToken(tok) != nil || s.bindings.HasToken(tok) {
+		return tok, true
+	}
+	return "", false
+}
+
+type authTokenKey struct{}
+
+// requestToken returns the token that authenticated an API request (nil when
+// auth is disabled)
+func requestToken(r *http.Request) *AuthToken {
+	t, _ := r.Context().Value(authTokenKey{}).(*AuthToken)
+	return t
+}
+
+// tunnelScopeMiddleware rejects API calls for tunnels outside the caller's
+// token scope
+func (s *Server) tunnelScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tunnelID := mux.Vars(r)["tunnel_id"]
+		if !requestToken(r).allows(tunnelID, s.registry.Label(tunnelID)) {
+			http.Error(w, "token not authorized for this tunnel", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withToken stores the authenticating token in the request context
+func withToken(r *http.Request, t *AuthToken) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), authTokenKey{}, t))
+}
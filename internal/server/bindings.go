@@ -0,0 +1,131 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TunnelBinding pre-registers a stable tunnel ID. On a locked relay only
+// bound IDs may register.
+type TunnelBinding struct {
+	ID          string            `yaml:"id"`
+	Token       string            `yaml:"token,omitempty"`       // Optional: token required for this ID instead of the server token
+	Description string            `yaml:"description,omitempty"` // Optional: what the tunnel is for
+	Metadata    map[string]string `yaml:"metadata,omitempty"`
+
+	Signature *SignatureConfig `yaml:"signature,omitempty"`  // Optional: reject webhooks without a valid HMAC signature
+	IPFilter  *IPFilter        `yaml:"ip_filter,omitempty"`  // Optional: source addresses allowed to send webhooks
+	BasicAuth *BasicAuth       `yaml:"basic_auth,omitempty"` // Optional: HTTP Basic credentials required on webhooks
+}
+
+// tunnelBindings is the reloadable set of allowed tunnel IDs
+type tunnelBindings struct {
+	mu   sync.RWMutex
+	path string
+	byID map[string]TunnelBinding
+}
+
+// loadTunnelBindings reads a bindings file of the form:
+//
+//	tunnels:
+//	  - id: payments-dev
+//	    token: optional-per-id-token
+//	    description: Payments webhooks
+//	    signature:
+//	      header: X-Hub-Signature-256
+//	      secret: webhook-secret
+//	    ip_filter:
+//	      allow: [140.82.112.0/20]
+//	    basic_auth:
+//	      username: sender
+//	      password: webhook-password
+func loadTunnelBindings(path string) (*tunnelBindings, error) {
+	b := &tunnelBindings{path: path}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reload re-reads the bindings file, keeping the old bindings on error
+func (b *tunnelBindings) Reload() error {
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return fmt.Errorf("failed to read tunnels file: %w", err)
+	}
+
+	var file struct {
+		Tunnels []TunnelBinding `yaml:"tunnels"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse tunnels file: %w", err)
+	}
+
+	byID := make(map[string]TunnelBinding, len(file.Tunnels))
+	for i, t := range file.Tunnels {
+		if !validTunnelID(t.ID) {
+			return fmt.Errorf("tunnels file entry %d: invalid id %q (8-64 chars of letters, digits, - or _)", i, t.ID)
+		}
+		if _, dup := byID[t.ID]; dup {
+			return fmt.Errorf("tunnels file entry %d: duplicate id %q", i, t.ID)
+		}
+		if t.Signature != nil {
+			if err := t.Signature.validate(); err != nil {
+				return fmt.Errorf("tunnels file entry %d (%s): %w", i, t.ID, err)
+			}
+		}
+		if t.IPFilter != nil {
+			if err := t.IPFilter.validate(); err != nil {
+				return fmt.Errorf("tunnels file entry %d (%s): %w", i, t.ID, err)
+			}
+		}
+		if t.BasicAuth != nil {
+			if err := t.BasicAuth.validate(); err != nil {
+				return fmt.Errorf("tunnels file entry %d (%s): %w", i, t.ID, err)
+			}
+		}
+		byID[t.ID] = t
+	}
+
+	b.mu.Lock()
+	b.byID = byID
+	b.mu.Unlock()
+	return nil
+}
+
+// Lookup returns the binding for a tunnel ID
+func (b *tunnelBindings) Lookup(id string) (TunnelBinding, bool) {
+	if b == nil {
+		return TunnelBinding{}, false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	t, ok := b.byID[id]
+	return t, ok
+}
+
+// HasToken reports whether tok is the token of any bound ID
+func (b *tunnelBindings) HasToken(tok string) bool {
+	if b == nil || tok == "" {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, t := range b.byID {
+		if t.Token != "" && subtle.ConstantTimeCompare([]byte(tok), []byte(t.Token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of bound tunnel IDs
+func (b *tunnelBindings) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.byID)
+}
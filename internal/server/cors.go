@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache a preflight answer, in seconds
+const corsMaxAge = "600"
+
+// defaultCORSMethods are allowed in preflights when CORS.Methods is empty
+var defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// CORS lets browsers send webhooks from other origins. It is off unless
+// Origins is set.
+type CORS struct {
+	Origins []string `yaml:"origins,omitempty"` // Allowed origins (e.g., "https://app.example.com"), or "*" for any
+	Methods []string `yaml:"methods,omitempty"` // Methods allowed in preflights (default GET, POST, PUT, PATCH, DELETE)
+	Headers []string `yaml:"headers,omitempty"` // Request headers allowed in preflights (default: whatever the browser asks for)
+}
+
+// enabled reports whether any origin is allowed
+func (c *CORS) enabled() bool {
+	return len(c.Origins) > 0
+}
+
+// validate checks that each origin is "*" or a bare scheme://host[:port]
+func (c *CORS) validate() error {
+	for _, o := range c.Origins {
+		if o == "*" {
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("invalid CORS origin %q (want \"*\" or scheme://host[:port])", o)
+		}
+	}
+	return nil
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or
+// "" if it isn't allowed
+func (c *CORS) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	if slices.Contains(c.Origins, "*") {
+		return "*"
+	}
+	for _, o := range c.Origins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// corsMiddleware adds CORS headers to webhook responses and answers
+// preflights itself with 204; preflights are never stored or forwarded
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	c := &s.config.CORS
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := c.allowOrigin(r.Header.Get("Origin"))
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if allowed != "" {
+			h.Set("Access-Control-Allow-Origin", allowed)
+		}
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if allowed != "" {
+			methods := c.Methods
+			if len(methods) == 0 {
+				methods = defaultCORSMethods
+			}
+			h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			if len(c.Headers) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(c.Headers, ", "))
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+			h.Set("Access-Control-Max-Age", corsMaxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// maxDedupKeys bounds the deliveries remembered across all tunnels; past it,
+// new webhooks are forwarded without being remembered
+const maxDedupKeys = 10000
+
+// dedupCache remembers each tunnel's recent deliveries, so a webhook that
+// is redelivered within the window is answered with the first delivery's
+// response instead of being forwarded again
+type dedupCache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	header    string // Key header (e.g., X-GitHub-Delivery); the body hash is used without it
+	entries   map[string]*dedupEntry
+	lastSweep time.Time
+}
+
+// dedupEntry is one remembered delivery. done is closed once the first
+// delivery is answered; resp is nil if it failed.
+type dedupEntry struct {
+	requestID string
+	expires   time.Time
+	done      chan struct{}
+	resp      *protocol.HTTPResponse
+}
+
+// newDedupCache creates a dedup cache for the given window and key header
+func newDedupCache(window time.Duration, header string) *dedupCache {
+	return &dedupCache{
+		window:  window,
+		header:  header,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+// key identifies a delivery: the key header's value if the webhook has one,
+// else a hash of its method, path and body
+func (d *dedupCache) key(tunnelID string, r *http.Request, path string, body []byte) string {
+	if d.header != "" {
+		if v := r.Header.Get(d.header); v != "" {
+			return tunnelID + "\x00h:" + v
+		}
+	}
+	h := sha256.New()
+	h.Write([]byte(r.Method + "\n" + path + "\n"))
+	h.Write(body)
+	return tunnelID + "\x00b:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// claim returns the live entry for key and false if the delivery was seen
+// within the window. Otherwise it remembers a new entry for requestID and
+// returns it and true; the caller must finish it. The entry is nil when the
+// cache is full.
+func (d *dedupCache) claim(key, requestID string) (*dedupEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if e, ok := d.entries[key]; ok && now.Before(e.expires) {
+		return e, false
+	}
+	if now.Sub(d.lastSweep) >= d.window || len(d.entries) >= maxDedupKeys {
+		d.sweep(now)
+	}
+	if len(d.entries) >= maxDedupKeys {
+		return nil, true
+	}
+	e := &dedupEntry{requestID: requestID, expires: now.Add(d.window), done: make(chan struct{})}
+	d.entries[key] = e
+	return e, true
+}
+
+// finish records the first delivery's response and wakes duplicates waiting
+// on it. A failed delivery (nil resp) is forgotten so redeliveries retry it.
+func (d *dedupCache) finish(key string, e *dedupEntry, resp *protocol.HTTPResponse) {
+	if e == nil {
+		return
+	}
+	if resp == nil {
+		d.mu.Lock()
+		if d.entries[key] == e {
+			delete(d.entries, key)
+		}
+		d.mu.Unlock()
+	}
+	e.resp = resp
+	close(e.done)
+}
+
+// wait returns the first delivery's response, or nil if it failed or ctx
+// ended first
+func (e *dedupEntry) wait(ctx context.Context) *protocol.HTTPResponse {
+	select {
+	case <-e.done:
+		return e.resp
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// sweep drops expired entries. Callers hold d.mu.
+func (d *dedupCache) sweep(now time.Time) {
+	for k, e := range d.entries {
+		if !now.Before(e.expires) {
+			delete(d.entries, k)
+		}
+	}
+	d.lastSweep = now
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// buildHAR converts a tunnel's stored requests (oldest first) into a HAR
+// archive. baseURL is the tunnel's public URL.
+func buildHAR(baseURL, version string, reqs []*protocol.HTTPRequest, responses map[string]*protocol.HTTPResponse) *protocol.HAR {
+	har := protocol.NewHAR(version)
+	for _, req := range reqs {
+		har.Log.Entries = append(har.Log.Entries, protocol.NewHAREntry(baseURL, req, responses[req.ID]))
+	}
+	return har
+}
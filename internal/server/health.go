@@ -0,0 +1,43 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Readiness states
+const (
+	ReadyStatus    = "ready"
+	DrainingStatus = "draining"
+)
+
+// Readiness is returned by the readiness endpoint
+type Readiness struct {
+	Status        string  `json:"status"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	Tunnels       int     `json:"tunnels"`
+	MaxTunnels    int     `json:"max_tunnels,omitempty"`
+}
+
+// handleReady reports whether this instance should receive traffic. While
+// draining it answers 503, so load balancers stop routing to it before the
+// tunnels close.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	ready := Readiness{
+		Status:        ReadyStatus,
+		UptimeSeconds: time.Since(s.startedAt).Round(time.Millisecond).Seconds(),
+		Tunnels:       len(s.registry.List()),
+		MaxTunnels:    s.config.MaxTunnels,
+	}
+	status := http.StatusOK
+	if s.draining.Load() {
+		ready.Status = DrainingStatus
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ready)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// maxIdleSweep is the longest wait between idle tunnel sweeps
+const maxIdleSweep = time.Minute
+
+// lastActivity returns when the session last forwarded a webhook for any of
+// its tunnels, or when it connected if it never has
+func (s *session) lastActivity() time.Time {
+	var last time.Time
+	for _, t := range s.tunnels {
+		at := t.ConnectedAt
+		if n := t.lastActive.Load(); n > 0 {
+			at = time.Unix(0, n)
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	return last
+}
+
+// expire tells the client its tunnels were closed for inactivity and ends
+// the connection. The client stops instead of reconnecting.
+func (s *session) expire(idle time.Duration) {
+	errMsg, _ := protocol.NewMessage(protocol.TypeError, protocol.ErrorPayload{
+		Code:    protocol.ErrCodeIdleTimeout,
+		Message: fmt.Sprintf("tunnel closed after %s without webhooks", idle.Round(time.Second)),
+	})
+	data, _ := json.Marshal(errMsg)
+	select {
+	case s.final <- data:
+	default:
+	}
+}
+
+// IdleSessions returns connections with no webhook activity since cutoff and
+// none in flight
+func (r *TunnelRegistry) IdleSessions(cutoff time.Time) []*session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[*session]bool)
+	var idle []*session
+	for _, group := range r.tunnels {
+		for _, t := range group.conns {
+			s := t.session
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+			if s.inFlight.Load() == 0 && s.lastActivity().Before(cutoff) {
+				idle = append(idle, s)
+			}
+		}
+	}
+	return idle
+}
+
+// expireIdle periodically closes connections whose tunnels have gone
+// TunnelIdleTimeout without a webhook
+func (s *Server) expireIdle(ctx context.Context) {
+	timeout := s.config.TunnelIdleTimeout
+	ticker := time.NewTicker(min(timeout/2, maxIdleSweep))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, sess := range s.registry.IdleSessions(time.Now().Add(-timeout)) {
+				log.Printf("tunnel %s (conn=%s): idle for %s, closing", sess.shortIDs(), sess.ConnID, timeout)
+				sess.expire(timeout)
+			}
+		}
+	}
+}
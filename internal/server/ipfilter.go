@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// IPFilter restricts which source addresses may send webhooks. Entries are
+// CIDR ranges or single IPs. Deny wins over Allow; with a non-empty Allow,
+// only listed addresses get through.
+type IPFilter struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// validate parses the ranges
+func (f *IPFilter) validate() error {
+	var err error
+	if f.allow, err = parsePrefixes(f.Allow); err != nil {
+		return fmt.Errorf("ip allow list: %w", err)
+	}
+	if f.deny, err = parsePrefixes(f.Deny); err != nil {
+		return fmt.Errorf("ip deny list: %w", err)
+	}
+	return nil
+}
+
+// empty reports whether the filter lets every address through
+func (f *IPFilter) empty() bool {
+	return f == nil || len(f.allow) == 0 && len(f.deny) == 0
+}
+
+// check returns why addr is rejected, or nil
+func (f *IPFilter) check(addr netip.Addr) error {
+	if f.empty() {
+		return nil
+	}
+	if !addr.IsValid() {
+		return fmt.Errorf("unknown source address")
+	}
+	if containsAddr(f.deny, addr) {
+		return fmt.Errorf("%s is denied", addr)
+	}
+	if len(f.allow) > 0 && !containsAddr(f.allow, addr) {
+		return fmt.Errorf("%s is not allowed", addr)
+	}
+	return nil
+}
+
+// parsePrefixes parses CIDR ranges, treating a bare IP as a single address
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", e)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP %q", e)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddr returns the address a webhook came from. When the connection is
+// from a trusted proxy, X-Forwarded-For is walked from the right, skipping
+// further trusted proxies, so a sender can't spoof its address by adding
+// entries of its own.
+func (s *Server) clientAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+	if !containsAddr(s.trustedProxies, addr) {
+		return addr
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Can't see past a malformed entry
+			return addr
+		}
+		addr = hop.Unmap()
+		if !containsAddr(s.trustedProxies, addr) {
+			return addr
+		}
+	}
+	return addr
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"slices"
+	"time"
+)
+
+// LatencyStats summarizes how a tunnel's stored requests were answered.
+// Latency is measured from forwarding a request to receiving its response,
+// so it covers the tunnel round trip and the client's target.
+type LatencyStats struct {
+	Count     int     `json:"count"`      // Responses with a recorded duration
+	Errors    int     `json:"errors"`     // Of those, 5xx responses
+	ErrorRate float64 `json:"error_rate"` // Errors / Count
+
+	MinMs float64 `json:"min_ms"`
+	AvgMs float64 `json:"avg_ms"`
+	MaxMs float64 `json:"max_ms"`
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+}
+
+// timedResponse is one response's status and duration
+type timedResponse struct {
+	status   int
+	duration time.Duration
+}
+
+// latencyStats computes stats over responses
+func latencyStats(samples []timedResponse) LatencyStats {
+	var stats LatencyStats
+	if len(samples) == 0 {
+		return stats
+	}
+
+	durations := make([]time.Duration, len(samples))
+	var total time.Duration
+	for i, s := range samples {
+		durations[i] = s.duration
+		total += s.duration
+		if s.status >= 500 {
+			stats.Errors++
+		}
+	}
+	slices.Sort(durations)
+
+	stats.Count = len(samples)
+	stats.ErrorRate = float64(stats.Errors) / float64(stats.Count)
+	stats.MinMs = ms(durations[0])
+	stats.MaxMs = ms(durations[len(durations)-1])
+	stats.AvgMs = ms(total / time.Duration(len(durations)))
+	stats.P50Ms = ms(percentile(durations, 50))
+	stats.P95Ms = ms(percentile(durations, 95))
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	return sorted[max(rank, 1)-1]
+}
+
+// ms converts a duration to fractional milliseconds
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
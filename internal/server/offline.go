@@ -0,0 +1,149 @@
+package server
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+const (
+	defaultOfflineBufferSize = 100
+	defaultOfflineBufferAge  = 10 * time.Minute
+)
+
+// offlineBuffer holds webhooks for recently disconnected tunnels until a
+// client resumes the tunnel with its resume token
+type offlineBuffer struct {
+	mu      sync.Mutex
+	maxSize int           // Buffered requests per tunnel; the oldest is dropped beyond this
+	maxAge  time.Duration // How long a disconnected tunnel is remembered
+	tunnels map[string]*offlineTunnel
+}
+
+// offlineTunnel is a disconnected tunnel awaiting resumption
+type offlineTunnel struct {
+	resumeToken string
+	since       time.Time
+	requests    []*protocol.HTTPRequest
+	dropped     int
+}
+
+// newOfflineBuffer creates an offline buffer, applying defaults for zero limits
+func newOfflineBuffer(maxSize int, maxAge time.Duration) *offlineBuffer {
+	if maxSize <= 0 {
+		maxSize = defaultOfflineBufferSize
+	}
+	if maxAge <= 0 {
+		maxAge = defaultOfflineBufferAge
+	}
+	return &offlineBuffer{
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		tunnels: make(map[string]*offlineTunnel),
+	}
+}
+
+// Remember starts buffering for a tunnel whose last client disconnected. An
+// entry that was never taken (a failed resume) is kept with its buffer.
+func (b *offlineBuffer) Remember(tunnelID, resumeToken string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.tunnels[tunnelID]; ok {
+		return
+	}
+	b.tunnels[tunnelID] = &offlineTunnel{resumeToken: resumeToken, since: time.Now()}
+}
+
+// Has reports whether a tunnel is offline and still being buffered for
+func (b *offlineBuffer) Has(tunnelID string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.tunnels[tunnelID]
+	return ok && time.Since(t.since) <= b.maxAge
+}
+
+// Add buffers a request for an offline tunnel. It returns false if the
+// tunnel is unknown or has expired.
+func (b *offlineBuffer) Add(tunnelID string, req *protocol.HTTPRequest) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.tunnels[tunnelID]
+	if !ok || time.Since(t.since) > b.maxAge {
+		return false
+	}
+	t.requests = append(t.requests, req)
+	if len(t.requests) > b.maxSize {
+		t.requests = t.requests[1:]
+		t.dropped++
+	}
+	return true
+}
+
+// CanResume reports whether token resumes an offline tunnel
+func (b *offlineBuffer) CanResume(tunnelID, token string) bool {
+	if b == nil || token == "" {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.tunnels[tunnelID]
+	return ok && t.resumeToken == token && time.Since(t.since) <= b.maxAge
+}
+
+// Take removes an offline tunnel and returns its buffered requests that are
+// still within the age limit, oldest first
+func (b *offlineBuffer) Take(tunnelID string) []*protocol.HTTPRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.tunnels[tunnelID]
+	if !ok {
+		return nil
+	}
+	delete(b.tunnels, tunnelID)
+
+	if t.dropped > 0 {
+		log.Printf("tunnel %s: %d buffered webhooks were dropped (buffer limit %d)", shortID(tunnelID), t.dropped, b.maxSize)
+	}
+	fresh := t.requests[:0]
+	for _, req := range t.requests {
+		if time.Since(req.Timestamp) <= b.maxAge {
+			fresh = append(fresh, req)
+		}
+	}
+	return fresh
+}
+
+// sweep forgets tunnels that have been offline longer than maxAge
+func (b *offlineBuffer) sweep() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, t := range b.tunnels {
+		if time.Since(t.since) > b.maxAge {
+			if len(t.requests) > 0 {
+				log.Printf("tunnel %s expired with %d undelivered webhooks", shortID(id), len(t.requests))
+			}
+			delete(b.tunnels, id)
+		}
+	}
+}
+
+// shortID returns the first 8 characters of a tunnel ID for display
+func shortID(tunnelID string) string {
+	if len(tunnelID) >= 8 {
+		return tunnelID[:8]
+	}
+	return tunnelID
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// replayEdit is the optional body of a replay call. Each field that is set
+// replaces that part of the stored request; the rest is replayed as stored.
+type replayEdit struct {
+	Method  string           `json:"method,omitempty"`
+	Path    string           `json:"path,omitempty"`    // Including any query string
+	Headers protocol.Headers `json:"headers,omitempty"` // Replace these headers; an empty list removes one
+	Body    *string          `json:"body,omitempty"`
+}
+
+// readReplayEdit decodes a replay call's body, returning nil when it has none
+func (s *Server) readReplayEdit(w http.ResponseWriter, r *http.Request) (*replayEdit, error) {
+	// Leave room for JSON escaping of a body up to the webhook limit
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 2*s.config.MaxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+
+	var edit replayEdit
+	if err := json.Unmarshal(data, &edit); err != nil {
+		return nil, fmt.Errorf("invalid replay edit: %w", err)
+	}
+	if edit.Method != "" && strings.ContainsAny(edit.Method, " \t\r\n/") {
+		return nil, fmt.Errorf("invalid method %q", edit.Method)
+	}
+	if edit.Path != "" && !strings.HasPrefix(edit.Path, "/") {
+		return nil, errors.New("path must start with /")
+	}
+	if edit.Body != nil && int64(len(*edit.Body)) > s.config.MaxBodySize {
+		return nil, errors.New("body too large")
+	}
+	return &edit, nil
+}
+
+// apply edits a copy of the stored request. An edited request no longer
+// matches what the sender signed, so the copy is no longer verified.
+func (e *replayEdit) apply(req *protocol.HTTPRequest) {
+	if e.Method != "" || e.Path != "" || len(e.Headers) > 0 || e.Body != nil {
+		req.Verified = false
+	}
+	if e.Method != "" {
+		req.Method = strings.ToUpper(e.Method)
+	}
+	if e.Path != "" {
+		req.Path = e.Path
+	}
+	if len(e.Headers) == 0 && e.Body == nil {
+		return
+	}
+
+	// Copy the headers: the stored request shares them
+	headers := make(protocol.Headers, len(req.Headers)+len(e.Headers))
+	for k, v := range req.Headers {
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	for k, v := range e.Headers {
+		if len(v) == 0 {
+			delete(headers, http.CanonicalHeaderKey(k))
+			continue
+		}
+		headers[http.CanonicalHeaderKey(k)] = v
+	}
+	if e.Body != nil {
+		req.Body = []byte(*e.Body)
+		if _, ok := headers["Content-Length"]; ok {
+			headers["Content-Length"] = []string{strconv.Itoa(len(req.Body))}
+		}
+	}
+	req.Headers = headers
+}
@@ -1,12 +1,20 @@
 package server
 
 import (
+	"compress/flate"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,25 +29,128 @@ type Config struct {
 	Host           string
 	PublicURL      string
 	MaxRequests    int
-	Token          string   // Optional: require this token for auth
-	TLSCert        string   // Optional: path to TLS certificate
-	TLSKey         string   // Optional: path to TLS key
-	MaxBodySize    int64    // Max webhook body size in bytes (default 10MB)
-	MaxMessageSize int64    // Max WebSocket message size in bytes (default 10MB)
-	AllowedOrigins []string // Optional: allowed WebSocket origins (empty = allow all for CLI clients)
+	StorePath      string      // Optional: SQLite file to persist request history (default in-memory)
+	Token          string      // Optional: require this token for auth
+	Tokens         []AuthToken // Optional: further accepted tokens, each optionally scoped to some tunnels
+	TLSCert        string      // Optional: path to TLS certificate
+	TLSKey         string      // Optional: path to TLS key
+	ACMEDomains    []string    // Optional: get certificates for these domains from Let's Encrypt (instead of TLSCert/TLSKey)
+	ACMEEmail      string      // Optional: contact address for the ACME account
+	ACMECacheDir   string      // Where ACME certificates are kept (default in the user cache dir)
+	MaxBodySize    int64       // Max webhook body size in bytes (default 10MB)
+	MaxMessageSize int64       // Max WebSocket message size in bytes (default 10MB)
+	ChunkSize      int         // Bodies larger than this are sent in chunk messages to clients that support it (default 1MB)
+	SendBuffer     int         // Messages queued per client connection; webhooks get 503 once it is 90% full (default 256)
+
+	ResponseWait time.Duration // How long a webhook waits for the client's response (default 30s)
+
+	PingInterval time.Duration // How often to ping each client (default 90% of PongTimeout)
+	PongTimeout  time.Duration // Drop a client after this long without a pong (default 60s)
+
+	DisableWSCompression bool // Don't negotiate permessage-deflate on tunnel connections
+
+	RequireUpgradeAuth bool // Refuse WebSocket upgrades without a valid Authorization header (older clients only send the token after connecting)
+
+	MaxDecompressedBytes int64    // Max size of a gzip-decoded response body (default 10MB)
+	AllowedOrigins       []string // Optional: allowed WebSocket origins (empty = allow all for CLI clients)
+
+	WebhookIPs     IPFilter // Optional: source addresses allowed to send webhooks to any tunnel
+	TrustedProxies []string // Proxies whose X-Forwarded-For is believed for WebhookIPs (CIDRs or IPs)
+
+	CORS CORS // Optional: CORS headers for browsers sending webhooks from other origins (off by default)
+
+	RegisterRateLimit int // Max new tunnel registrations per minute across all clients (0 = unlimited)
+	MaxTunnels        int // Max tunnels connected at once (0 = unlimited)
+
+	RateLimit      float64 // Max webhooks per second per tunnel (0 = unlimited)
+	RateLimitBurst int     // Webhooks allowed in a burst above RateLimit (default: RateLimit rounded up)
+
+	NoTunnelStatus     int    // Status returned for webhooks to an unknown/disconnected tunnel (default 404)
+	NoTunnelRetryAfter int    // Optional: Retry-After seconds sent with NoTunnelStatus (0 = omit)
+	NoTunnelBody       string // Optional: response body for NoTunnelStatus, plain text or HTML (default explains the tunnel is offline)
+
+	BufferOffline     bool          // Buffer webhooks for recently disconnected tunnels until they resume
+	OfflineBufferSize int           // Max buffered webhooks per tunnel (default 100)
+	OfflineBufferAge  time.Duration // How long a disconnected tunnel is remembered (default 10m)
+
+	LockTunnels bool   // Only accept tunnel IDs listed in TunnelsFile
+	TunnelsFile string // YAML file of allowed tunnel IDs (reload with ReloadTunnels)
+
+	AllowMultiClient bool   // Honor client-requested tunnel IDs and let several clients share one
+	Balance          string // How webhooks are spread across shared clients: round-robin (default), least-in-flight or failover
+
+	Debug bool // Log redacted dumps of malformed protocol frames
+
+	Dashboard bool // Serve the browser dashboard at /dashboard (requires a token)
+
+	TransformScript  string        // Optional: Starlark script run against each webhook
+	TransformTimeout time.Duration // Max execution time per transform (default 500ms)
+
+	SinkURL       string    // Optional: publish an event for every webhook to this URL
+	SinkPayload   string    // summary (default) or full (include headers and body)
+	SinkQueueSize int       // Max events buffered for the sink before dropping (default 1000)
+	Sink          Publisher // Optional: custom publisher for embedders (overrides SinkURL)
+
+	Version string // Reported in exports (e.g., HAR creator)
+
+	DrainTimeout time.Duration // How long shutdown waits for in-flight webhooks before closing tunnels (default 10s)
+
+	TunnelIdleTimeout time.Duration // Optional: close connections whose tunnels get no webhooks for this long (0 = never)
+
+	URLTTL time.Duration // Optional: sign public URLs so they expire after this long (0 = plain URLs that never expire)
+
+	Aliases     map[string]string // Optional: memorable names for tunnel IDs; webhooks to /t/{alias} go to the tunnel
+	AliasPrefix string            // Optional: also serve aliases at {AliasPrefix}/{alias} ("/" for the root, e.g., /proj-a/...)
+
+	DedupWindow time.Duration // Optional: answer a webhook redelivered within this long with the first delivery's response (0 = off)
+	DedupHeader string        // Optional: header identifying a delivery for DedupWindow (e.g., X-GitHub-Delivery; default: a hash of method, path and body)
+
+	// OnTunnelOpen and OnTunnelClose are optional lifecycle callbacks for
+	// embedders. They are called after the registry lock is released, from
+	// the goroutine serving the tunnel's connection (or the shutdown path for
+	// CloseAll), so they may run concurrently for different tunnels and must
+	// be safe for concurrent use. A slow callback delays that tunnel's
+	// registration or teardown; hand long-running work off to a goroutine.
+	// Panics are recovered and logged.
+	OnTunnelOpen  func(tunnelID string)
+	OnTunnelClose func(tunnelID string)
 }
 
 const (
 	defaultMaxBodySize    = 10 * 1024 * 1024 // 10MB
 	defaultMaxMessageSize = 10 * 1024 * 1024 // 10MB
+	maxTunnelsPerConn     = 16
+	eventKeepalive        = 15 * time.Second // Comment sent on idle event streams
+	defaultDrainTimeout   = 10 * time.Second
+	drainRetryAfter       = 5 // Retry-After seconds for webhooks refused while draining
+	busyRetryAfter        = 1 // Retry-After seconds for webhooks refused while a send buffer is full
+	defaultSendBuffer     = 256
+	closeTimeout          = 5 * time.Second // After draining, how long shutdown waits for handlers to answer
 )
 
 // Server is the hookshot relay server
 type Server struct {
 	config   Config
 	registry *TunnelRegistry
-	store    *RequestStore
+	store    RequestStorage
 	upgrader websocket.Upgrader
+
+	registerLimiter *tokenBucket    // nil when registrations are unlimited
+	transformer     *Transformer    // nil when no transform script is configured
+	bindings        *tunnelBindings // nil unless TunnelsFile is set
+	sink            *eventSink      // nil when no sink is configured
+	dedup           *dedupCache     // nil unless DedupWindow is set
+
+	tokens      []*AuthToken // Token and Tokens; empty when auth is disabled
+	aliasPrefix *string      // Normalized AliasPrefix ("" for the root); nil when unset
+	urlKey      []byte       // Signs public URLs when URLTTL is set
+
+	trustedProxies []netip.Prefix // Parsed TrustedProxies
+
+	shutdown chan struct{} // Closed on shutdown to end event streams
+	draining atomic.Bool   // Shutting down: new webhooks and tunnels are refused
+
+	startedAt time.Time // When Run started, for uptime
 }
 
 // New creates a new server
@@ -51,18 +162,82 @@ func New(cfg Config) *Server {
 	if cfg.MaxMessageSize == 0 {
 		cfg.MaxMessageSize = defaultMaxMessageSize
 	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = protocol.DefaultChunkSize
+	}
+	if cfg.SendBuffer == 0 {
+		cfg.SendBuffer = defaultSendBuffer
+	}
+	if cfg.ResponseWait == 0 {
+		cfg.ResponseWait = protocol.ResponseTimeout
+	}
+	if cfg.PongTimeout == 0 {
+		cfg.PongTimeout = defaultPongTimeout
+	}
+	if cfg.PingInterval == 0 {
+		cfg.PingInterval = cfg.PongTimeout * 9 / 10
+	}
+	if cfg.NoTunnelStatus == 0 {
+		cfg.NoTunnelStatus = http.StatusNotFound
+	}
+	if cfg.DrainTimeout == 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
+	}
 
 	store := NewRequestStore(cfg.MaxRequests)
+	registry := NewTunnelRegistry(store)
+	registry.multiClient = cfg.AllowMultiClient
+	registry.debug = cfg.Debug
+	registry.maxDecompressed = cfg.MaxDecompressedBytes
+	registry.maxTunnels = cfg.MaxTunnels
+	if cfg.RateLimit > 0 {
+		registry.rateLimit = cfg.RateLimit
+		registry.rateBurst = cfg.RateLimitBurst
+		if registry.rateBurst <= 0 {
+			registry.rateBurst = int(math.Ceil(cfg.RateLimit))
+		}
+	}
+	if cfg.Balance != "" {
+		registry.balance = cfg.Balance
+	}
+	if cfg.BufferOffline {
+		registry.offline = newOfflineBuffer(cfg.OfflineBufferSize, cfg.OfflineBufferAge)
+	}
+	registry.onOpen = cfg.OnTunnelOpen
+	registry.onClose = cfg.OnTunnelClose
+
 	s := &Server{
 		config:   cfg,
-		registry: NewTunnelRegistry(store),
+		registry: registry,
 		store:    store,
+		shutdown: make(chan struct{}),
+	}
+
+	if cfg.Token != "" {
+		s.tokens = append(s.tokens, &AuthToken{Name: "default", Token: cfg.Token})
+	}
+	for i := range cfg.Tokens {
+		t := cfg.Tokens[i]
+		s.tokens = append(s.tokens, &t)
+	}
+	if cfg.URLTTL > 0 {
+		s.urlKey = urlSigningKey(s.tokens)
+	}
+	if cfg.DedupWindow > 0 {
+		s.dedup = newDedupCache(cfg.DedupWindow, cfg.DedupHeader)
 	}
 
 	s.upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
 		CheckOrigin:     s.checkOrigin,
+		Subprotocols:    []string{protocol.Subprotocol},
+
+		EnableCompression: !cfg.DisableWSCompression,
+	}
+
+	if cfg.RegisterRateLimit > 0 {
+		s.registerLimiter = newTokenBucket(float64(cfg.RegisterRateLimit)/60, cfg.RegisterRateLimit)
 	}
 
 	return s
@@ -94,46 +269,246 @@ func (s *Server) checkOrigin(r *http.Request) bool {
 
 // Run starts the server with graceful shutdown support
 func (s *Server) Run(ctx context.Context) error {
+	s.startedAt = time.Now()
+	if s.config.StorePath != "" {
+		store, err := OpenSQLiteStore(s.config.StorePath, s.config.MaxRequests)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		s.store = store
+		s.registry.store = store
+		log.Printf("request history stored in %s", s.config.StorePath)
+	}
+	seen := make(map[string]bool, len(s.tokens))
+	for _, t := range s.tokens {
+		if err := t.validate(); err != nil {
+			return err
+		}
+		if seen[t.Token] {
+			return fmt.Errorf("token %q: duplicate token", t.Name)
+		}
+		seen[t.Token] = true
+	}
+	// Leave room for base64 and the JSON envelope
+	if int64(s.config.ChunkSize) > s.config.MaxMessageSize/2 {
+		return fmt.Errorf("chunk size %d must be at most half the max message size (%d)", s.config.ChunkSize, s.config.MaxMessageSize)
+	}
+	if s.config.SendBuffer < 1 {
+		return fmt.Errorf("send buffer %d must be at least 1", s.config.SendBuffer)
+	}
+	// Clients end their forwards a second before this
+	if s.config.ResponseWait < 2*time.Second {
+		return fmt.Errorf("response wait %s must be at least 2s", s.config.ResponseWait)
+	}
+	if s.config.URLTTL != 0 && s.config.URLTTL < time.Second {
+		return fmt.Errorf("URL TTL %s must be at least 1s", s.config.URLTTL)
+	}
+	if s.config.DedupWindow < 0 {
+		return fmt.Errorf("dedup window %s must be >= 0", s.config.DedupWindow)
+	}
+	if s.config.DedupHeader != "" && s.config.DedupWindow == 0 {
+		return errors.New("a dedup header requires a dedup window")
+	}
+	if err := s.validateACME(); err != nil {
+		return err
+	}
+	if err := s.config.WebhookIPs.validate(); err != nil {
+		return err
+	}
+	if err := s.config.CORS.validate(); err != nil {
+		return err
+	}
+	if err := s.validateAliases(); err != nil {
+		return err
+	}
+	proxies, err := parsePrefixes(s.config.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("trusted proxies: %w", err)
+	}
+	s.trustedProxies = proxies
+	if s.config.Dashboard && !s.authEnabled() {
+		return fmt.Errorf("the dashboard requires a token")
+	}
+	if s.config.LockTunnels && s.config.TunnelsFile == "" {
+		return fmt.Errorf("lock_tunnels requires a tunnels_file")
+	}
+	if s.config.RequireUpgradeAuth && !s.authEnabled() && s.config.TunnelsFile == "" {
+		return fmt.Errorf("require_upgrade_auth requires a token or a tunnels_file")
+	}
+	if s.config.TunnelsFile != "" {
+		b, err := loadTunnelBindings(s.config.TunnelsFile)
+		if err != nil {
+			return err
+		}
+		s.bindings = b
+		if s.config.LockTunnels {
+			log.Printf("tunnel registration locked to %d IDs from %s", b.Len(), s.config.TunnelsFile)
+		} else {
+			log.Printf("loaded %d tunnel IDs from %s", b.Len(), s.config.TunnelsFile)
+		}
+	}
+	if s.config.TransformScript != "" {
+		t, err := LoadTransformer(s.config.TransformScript, s.config.TransformTimeout)
+		if err != nil {
+			return err
+		}
+		s.transformer = t
+		log.Printf("transform script: %s", s.config.TransformScript)
+	}
+	if pub := s.config.Sink; pub != nil || s.config.SinkURL != "" {
+		if pub == nil {
+			var err error
+			if pub, err = NewPublisher(s.config.SinkURL); err != nil {
+				return err
+			}
+			log.Printf("publishing webhook events to %s", s.config.SinkURL)
+		}
+		s.sink = newEventSink(pub, s.config.SinkQueueSize, s.config.SinkPayload)
+		go s.sink.run(ctx)
+	}
+
 	r := mux.NewRouter()
 
 	// WebSocket endpoint for clients
 	r.HandleFunc("/ws", s.handleWebSocket)
 
-	// API endpoints (protected by auth if token is set)
+	// API endpoints (protected by auth if a token is set)
 	api := r.PathPrefix("/api").Subrouter()
-	if s.config.Token != "" {
+	if s.authEnabled() {
 		api.Use(s.authMiddleware)
 	}
-	api.HandleFunc("/tunnels/{tunnel_id}/requests", s.handleListRequests).Methods("GET")
-	api.HandleFunc("/tunnels/{tunnel_id}/requests/{request_id}/replay", s.handleReplay).Methods("POST")
+	api.HandleFunc("/stats", s.handleStats).Methods("GET")
+	api.HandleFunc("/tunnels", s.handleListTunnels).Methods("GET")
+
+	// Per-tunnel endpoints, limited to the caller's token scope
+	tunnelAPI := api.PathPrefix("/tunnels/{tunnel_id}").Subrouter()
+	tunnelAPI.Use(s.tunnelScopeMiddleware)
+	tunnelAPI.HandleFunc("/requests", s.handleListRequests).Methods("GET")
+	tunnelAPI.HandleFunc("/requests", s.handleClearRequests).Methods("DELETE")
+	tunnelAPI.HandleFunc("/events", s.handleEvents).Methods("GET")
+	tunnelAPI.HandleFunc("/har", s.handleHAR).Methods("GET")
+	tunnelAPI.HandleFunc("/stats", s.handleTunnelStats).Methods("GET")
+	tunnelAPI.HandleFunc("/requests/{request_id}", s.handleGetRequest).Methods("GET")
+	tunnelAPI.HandleFunc("/requests/{request_id}/replay", s.handleReplay).Methods("POST")
 
 	// Webhook endpoints - catch all methods and paths under /t/{tunnel_id}
 	// Note: webhooks are NOT auth-protected (external services need to reach them)
-	r.PathPrefix("/t/{tunnel_id}").HandlerFunc(s.handleWebhook)
+	var webhooks http.Handler = http.HandlerFunc(s.handleWebhook)
+	if s.config.CORS.enabled() {
+		webhooks = s.corsMiddleware(webhooks)
+	}
+	r.PathPrefix("/t/{tunnel_id}").Handler(webhooks)
+
+	if s.config.Dashboard {
+		r.HandleFunc("/dashboard", s.handleDashboard).Methods("GET")
+	}
 
 	// Health check
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ok"))
 	})
+	r.HandleFunc("/ready", s.handleReady).Methods("GET")
+
+	if s.aliasPrefix != nil {
+		// Last, so the server's own routes win at the root
+		var aliased http.Handler = http.HandlerFunc(s.handleAliasWebhook)
+		if s.config.CORS.enabled() {
+			aliased = s.corsMiddleware(aliased)
+		}
+		r.PathPrefix(*s.aliasPrefix + "/{alias}").MatcherFunc(s.isAlias).Handler(aliased)
+	}
 
 	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
 	if s.config.PublicURL != "" {
 		log.Printf("public URL: %s", s.config.PublicURL)
 	}
-	if s.config.Token != "" {
-		log.Printf("auth token required for connections")
+	if s.authEnabled() {
+		log.Printf("auth token required for connections (%d accepted)", len(s.tokens))
+	}
+	if s.config.RegisterRateLimit > 0 {
+		log.Printf("tunnel registrations limited to %d/min", s.config.RegisterRateLimit)
+	}
+	if !s.config.WebhookIPs.empty() {
+		log.Printf("webhooks filtered by source IP (%d allowed, %d denied ranges)", len(s.config.WebhookIPs.allow), len(s.config.WebhookIPs.deny))
+	}
+	if s.config.CORS.enabled() {
+		log.Printf("webhooks answer CORS requests from %s", strings.Join(s.config.CORS.Origins, ", "))
+	}
+	if s.config.MaxTunnels > 0 {
+		log.Printf("at most %d tunnels connected at once", s.config.MaxTunnels)
+	}
+	if s.registry.rateLimit > 0 {
+		log.Printf("webhooks limited to %g/s per tunnel (burst %d)", s.registry.rateLimit, s.registry.rateBurst)
+	}
+	if s.config.AllowMultiClient {
+		log.Printf("multi-client tunnels enabled (balance=%s)", s.registry.balance)
+		if !s.authEnabled() {
+			log.Printf("warning: no --token; any client that knows a tunnel ID can join it and receive its webhooks")
+		}
+	}
+	if s.config.Dashboard {
+		log.Printf("dashboard enabled at /dashboard")
+	}
+	if b := s.registry.offline; b != nil {
+		log.Printf("buffering up to %d webhooks per disconnected tunnel for %s", b.maxSize, b.maxAge)
+		go s.sweepOffline(ctx)
+	}
+	if s.config.URLTTL > 0 {
+		log.Printf("public URLs are signed and expire after %s", s.config.URLTTL)
+		if !s.authEnabled() {
+			log.Printf("warning: no --token to derive the URL signing key from; signed URLs stop working on restart")
+		}
+	}
+	for _, u := range s.aliasURLs() {
+		log.Printf("alias %s", u)
+	}
+	if s.dedup != nil {
+		key := "a hash of method, path and body"
+		if s.config.DedupHeader != "" {
+			key = s.config.DedupHeader + " (or a body hash without it)"
+		}
+		log.Printf("webhooks redelivered within %s are answered from cache, keyed on %s", s.config.DedupWindow, key)
+	}
+	if s.config.TunnelIdleTimeout > 0 {
+		log.Printf("closing tunnels idle for %s", s.config.TunnelIdleTimeout)
+		go s.expireIdle(ctx)
 	}
 
 	srv := &http.Server{
 		Addr:    addr,
 		Handler: r,
 	}
+	srv.RegisterOnShutdown(func() { close(s.shutdown) })
+
+	// With ACME, certificates come from the manager, and port 80 answers
+	// the CA's challenges
+	var challengeSrv *http.Server
+	if len(s.config.ACMEDomains) > 0 {
+		m, err := s.acmeManager()
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = m.TLSConfig()
+		challengeSrv = s.acmeChallengeServer(m)
+	}
 
 	// Start server in goroutine
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
+	if challengeSrv != nil {
+		go func() {
+			log.Printf("answering ACME challenges on %s", challengeSrv.Addr)
+			if err := challengeSrv.ListenAndServe(); err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("ACME challenge listener: %w", err)
+			}
+		}()
+	}
 	go func() {
-		if s.config.TLSCert != "" && s.config.TLSKey != "" {
+		if srv.TLSConfig != nil {
+			log.Printf("hookshot server listening on %s (TLS via ACME)", addr)
+			errCh <- srv.ListenAndServeTLS("", "")
+		} else if s.config.TLSCert != "" && s.config.TLSKey != "" {
 			log.Printf("hookshot server listening on %s (TLS)", addr)
 			errCh <- srv.ListenAndServeTLS(s.config.TLSCert, s.config.TLSKey)
 		} else {
@@ -146,52 +521,100 @@ func (s *Server) Run(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		log.Printf("shutting down server...")
-		// Give 10 seconds to drain connections
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		drainCtx, cancel := context.WithTimeout(context.Background(), s.config.DrainTimeout)
 		defer cancel()
 
-		// Close all tunnels gracefully
+		// Refuse new webhooks and let forwarded ones finish before the
+		// tunnels they are waiting on go away
+		s.draining.Store(true)
+		s.drain(drainCtx)
 		s.registry.CloseAll()
 
-		return srv.Shutdown(shutdownCtx)
+		// A fresh deadline, so webhooks failed by CloseAll can still be
+		// answered even when the drain used up its own
+		closeCtx, cancelClose := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancelClose()
+		if challengeSrv != nil {
+			challengeSrv.Shutdown(closeCtx)
+		}
+		if err := srv.Shutdown(closeCtx); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				log.Printf("closed with requests still open after %s", closeTimeout)
+				return nil
+			}
+			return err
+		}
+		return nil
 	case err := <-errCh:
 		return err
 	}
 }
 
+// drain waits until no webhooks are in flight or ctx expires
+func (s *Server) drain(ctx context.Context) {
+	n := s.registry.InFlight()
+	if n == 0 {
+		return
+	}
+	log.Printf("waiting up to %s for %d in-flight webhooks", s.config.DrainTimeout, n)
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.registry.InFlight() == 0 {
+				log.Printf("in-flight webhooks drained")
+				return
+			}
+		case <-ctx.Done():
+			log.Printf("drain timeout: closing tunnels with %d webhooks still in flight", s.registry.InFlight())
+			return
+		}
+	}
+}
+
 // authMiddleware checks for valid auth token
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !s.checkAuth(r) {
+		t := s.checkAuth(r)
+		if t == nil {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withToken(r, t))
 	})
 }
 
-// checkAuth validates the auth token from Authorization header only
-func (s *Server) checkAuth(r *http.Request) bool {
-	if s.config.Token == "" {
-		return true
-	}
-
+// checkAuth validates the auth token from Authorization header only and
+// returns the matching token, or nil
+func (s *Server) checkAuth(r *http.Request) *AuthToken {
 	// Check Authorization header (Bearer token)
 	auth := r.Header.Get("Authorization")
-	if auth != "" {
-		if len(auth) > 7 && auth[:7] == "Bearer " {
-			if auth[7:] == s.config.Token {
-				return true
-			}
-		}
+	if len(auth) > 7 && auth[:7] == "Bearer " {
+		return s.matchToken(auth[7:])
 	}
 
 	// Query param tokens removed for security (leak risk in logs/proxies)
-	return false
+	return nil
 }
 
 // handleWebSocket handles client WebSocket connections
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// A token sent with the upgrade is checked before the connection is
+	// accepted, so bad clients cost no more than a request
+	headerToken, ok := s.upgradeToken(r)
+	if !ok {
+		log.Printf("unauthorized websocket upgrade from %s", r.RemoteAddr)
+		http.Error(w, "invalid or missing auth token", http.StatusUnauthorized)
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("websocket upgrade failed: %v", err)
@@ -200,9 +623,11 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	// Set message size limit
 	conn.SetReadLimit(s.config.MaxMessageSize)
+	// Favor latency over ratio; no-op unless the client negotiated compression
+	conn.SetCompressionLevel(flate.BestSpeed)
 
 	// Wait for register message
-	_, message, err := conn.ReadMessage()
+	message, err := readMessage(conn, s.config.MaxMessageSize)
 	if err != nil {
 		log.Printf("failed to read register message: %v", err)
 		conn.Close()
@@ -223,56 +648,313 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check auth token if required
-	if s.config.Token != "" && regPayload.Token != s.config.Token {
-		log.Printf("unauthorized connection attempt")
-		errMsg, _ := protocol.NewMessage(protocol.TypeError, protocol.ErrorPayload{
-			Code:    "unauthorized",
-			Message: "invalid or missing auth token",
-		})
-		data, _ := json.Marshal(errMsg)
-		conn.WriteMessage(websocket.TextMessage, data)
-		conn.Close()
+	// A multi-tunnel client lists its tunnels; older clients register one
+	multi := len(regPayload.Tunnels) > 0
+	specs := regPayload.Tunnels
+	if !multi {
+		specs = []protocol.TunnelSpec{{TunnelID: regPayload.TunnelID, ResumeToken: regPayload.ResumeToken}}
+	}
+	if len(specs) > maxTunnelsPerConn {
+		rejectConn(conn, websocket.ClosePolicyViolation, protocol.ErrCodeRegisterFailed, fmt.Sprintf("too many tunnels (max %d per connection)", maxTunnelsPerConn))
 		return
 	}
 
-	tunnel, err := s.registry.Register(conn, regPayload.TunnelID)
-	if err != nil {
-		log.Printf("failed to register tunnel: %v", err)
-		conn.Close()
+	// The register message's token wins; clients may send it only on the upgrade
+	token := regPayload.Token
+	if token == "" {
+		token = headerToken
+	}
+	authToken := s.matchToken(token)
+	for _, spec := range specs {
+		// On a locked relay only pre-bound IDs may register
+		binding, bound := s.bindings.Lookup(spec.TunnelID)
+		if s.config.LockTunnels && !bound {
+			log.Printf("rejected registration for unknown tunnel ID %q", spec.TunnelID)
+			rejectConn(conn, websocket.ClosePolicyViolation, protocol.ErrCodeUnknownTunnel, "this relay only accepts pre-registered tunnel IDs; pass a bound ID with --id")
+			return
+		}
+
+		// Check auth token if required (a bound ID's own token takes precedence)
+		authorized := true
+		if binding.Token != "" {
+			authorized = subtle.ConstantTimeCompare([]byte(token), []byte(binding.Token)) == 1
+		} else if s.authEnabled() {
+			authorized = authToken != nil
+		}
+		if !authorized {
+			log.Printf("unauthorized connection attempt")
+			rejectConn(conn, websocket.ClosePolicyViolation, protocol.ErrCodeUnauthorized, "invalid or missing auth token")
+			return
+		}
+	}
+
+	// Enforce global registration rate limit
+	if s.registerLimiter != nil && !s.registerLimiter.Allow() {
+		log.Printf("register rate limit exceeded (%d/min), rejecting tunnel registration", s.config.RegisterRateLimit)
+		rejectConn(conn, websocket.CloseTryAgainLater, protocol.ErrCodeRateLimited, "too many tunnel registrations, try again later")
 		return
 	}
 
-	// Send registered confirmation
-	publicURL := s.config.PublicURL
-	if publicURL == "" {
-		publicURL = fmt.Sprintf("http://%s:%d", s.config.Host, s.config.Port)
+	if ack := regPayload.AsyncAck; ack != 0 && (ack < 200 || ack > 299) {
+		rejectConn(conn, websocket.ClosePolicyViolation, protocol.ErrCodeRegisterFailed, fmt.Sprintf("invalid async ack status %d (must be 2xx)", ack))
+		return
 	}
 
-	registeredMsg, _ := protocol.NewMessage(protocol.TypeRegistered, protocol.RegisteredPayload{
-		TunnelID:  tunnel.ID,
-		PublicURL: fmt.Sprintf("%s/t/%s", publicURL, tunnel.ID),
-	})
+	opts := tunnelOptions{
+		AsyncAck:  regPayload.AsyncAck,
+		Gzip:      protocol.HasCapability(regPayload.Capabilities, protocol.CapGzip),
+		ReadLimit: s.config.MaxMessageSize,
+		Token:     authToken,
+		BodyLimit: s.config.MaxBodySize,
+
+		SendBuffer: s.config.SendBuffer,
+
+		PingInterval: s.config.PingInterval,
+		PongTimeout:  s.config.PongTimeout,
+	}
+	if protocol.HasCapability(regPayload.Capabilities, protocol.CapChunked) {
+		opts.ChunkSize = s.config.ChunkSize
+	}
+	sess := newSession(conn, opts)
+	resumed := make([]bool, 0, len(specs))
+	for _, spec := range specs {
+		// Client-requested IDs are only honored for shared, pre-bound or
+		// aliased tunnels
+		requestedID := ""
+		if _, bound := s.bindings.Lookup(spec.TunnelID); s.config.AllowMultiClient || bound || s.aliased(spec.TunnelID) {
+			requestedID = spec.TunnelID
+		}
+		// A client holding the resume token of a recently disconnected tunnel gets
+		// its ID back and its buffered webhooks. Of the other stable IDs, only
+		// pre-bound ones (whose own token was checked above) pick them up too.
+		canResume := s.registry.offline.CanResume(spec.TunnelID, spec.ResumeToken)
+		if canResume {
+			requestedID = spec.TunnelID
+		}
+		_, bound := s.bindings.Lookup(requestedID)
+		resumed = append(resumed, requestedID != "" && (canResume || bound) && s.registry.offline.Has(requestedID))
+
+		if requestedID != "" && !authToken.canRegister(requestedID) {
+			log.Printf("rejected tunnel ID %q outside token %s's prefix %q", requestedID, authToken.displayName(), authToken.TunnelPrefix)
+			for _, t := range sess.tunnels {
+				s.registry.Unregister(t)
+			}
+			rejectConn(conn, websocket.ClosePolicyViolation, protocol.ErrCodeForbidden, fmt.Sprintf("this token may only register tunnel IDs starting with %q", authToken.TunnelPrefix))
+			return
+		}
+
+		_, err := s.registry.Register(sess, requestedID)
+		if err != nil {
+			log.Printf("failed to register tunnel: %v", err)
+			for _, t := range sess.tunnels {
+				s.registry.Unregister(t)
+			}
+			if errors.Is(err, ErrTooManyTunnels) {
+				rejectConn(conn, websocket.CloseTryAgainLater, protocol.ErrCodeTooManyTunnels, err.Error())
+				return
+			}
+			if errors.Is(err, ErrTunnelForbidden) {
+				rejectConn(conn, websocket.ClosePolicyViolation, protocol.ErrCodeForbidden, fmt.Sprintf("this token may not join tunnel %s", requestedID))
+				return
+			}
+			rejectConn(conn, websocket.ClosePolicyViolation, protocol.ErrCodeRegisterFailed, err.Error())
+			return
+		}
+	}
+
+	// Claim webhooks buffered while resumed tunnels were offline
+	registered := make([]protocol.RegisteredTunnel, len(specs))
+	buffered := make([][]*protocol.HTTPRequest, len(specs))
+	for i, tunnel := range sess.tunnels {
+		if resumed[i] {
+			buffered[i] = s.registry.offline.Take(tunnel.ID)
+		}
+		registered[i] = protocol.RegisteredTunnel{
+			Name:        specs[i].Name,
+			TunnelID:    tunnel.ID,
+			PublicURL:   s.tunnelURL(tunnel.ID),
+			ResumeToken: tunnel.resumeTok,
+			Buffered:    len(buffered[i]),
+		}
+		if s.config.URLTTL > 0 {
+			signed, expires := s.signedTunnelURL(tunnel.ID)
+			registered[i].PublicURL = signed
+			registered[i].URLExpires = expires.Unix()
+		}
+	}
+
+	// Send registered confirmation. The top-level fields describe the first
+	// tunnel for single-tunnel clients.
+	payload := protocol.RegisteredPayload{
+		TunnelID:     registered[0].TunnelID,
+		PublicURL:    registered[0].PublicURL,
+		ConnectionID: sess.ConnID,
+		AsyncAck:     sess.asyncAck,
+		ResumeToken:  registered[0].ResumeToken,
+		Buffered:     registered[0].Buffered,
+		URLExpires:   registered[0].URLExpires,
+
+		ResponseWaitMs: s.config.ResponseWait.Milliseconds(),
+	}
+	if sess.gzip {
+		payload.Capabilities = append(payload.Capabilities, protocol.CapGzip)
+	}
+	if sess.chunkSize > 0 {
+		payload.Capabilities = append(payload.Capabilities, protocol.CapChunked)
+		payload.ChunkSize = sess.chunkSize
+	}
+	if multi {
+		payload.Tunnels = registered
+	}
+	registeredMsg, _ := protocol.NewMessage(protocol.TypeRegistered, payload)
 	data, _ := json.Marshal(registeredMsg)
 	conn.WriteMessage(websocket.TextMessage, data)
 
-	log.Printf("tunnel registered: %s", tunnel.ShortID())
+	for _, tunnel := range sess.tunnels {
+		if len(s.config.Tokens) > 0 && sess.token != nil {
+			// Name the token so a leaked one can be traced and revoked
+			log.Printf("tunnel registered: %s (conn=%s, remote=%s, token=%s)", tunnel.ShortID(), sess.ConnID, r.RemoteAddr, sess.token.displayName())
+			continue
+		}
+		log.Printf("tunnel registered: %s (conn=%s, remote=%s)", tunnel.ShortID(), sess.ConnID, r.RemoteAddr)
+	}
+	if sess.asyncAck != 0 {
+		log.Printf("tunnel %s acks webhooks with %d before forwarding", sess.shortIDs(), sess.asyncAck)
+	}
 
 	// Start read/write pumps
-	go tunnel.WritePump()
-	tunnel.ReadPump(s.registry)
+	go sess.WritePump()
+	for i, reqs := range buffered {
+		if len(reqs) > 0 {
+			go s.flushBuffered(sess.tunnels[i], reqs)
+		}
+	}
+	sess.ReadPump(s.registry)
 
-	log.Printf("tunnel disconnected: %s", tunnel.ShortID())
+	log.Printf("tunnel disconnected: %s (conn=%s)", sess.shortIDs(), sess.ConnID)
 }
 
-// handleWebhook handles incoming webhook requests
+// tunnelURL returns the public webhook URL for a tunnel
+func (s *Server) tunnelURL(tunnelID string) string {
+	return fmt.Sprintf("%s/t/%s", s.publicBaseURL(), tunnelID)
+}
+
+// publicBaseURL is the public URL, or the listen address without one
+func (s *Server) publicBaseURL() string {
+	if s.config.PublicURL != "" {
+		return s.config.PublicURL
+	}
+	return fmt.Sprintf("http://%s:%d", s.config.Host, s.config.Port)
+}
+
+// rejectConn sends an error message and a close frame, then closes the connection
+func rejectConn(conn *websocket.Conn, closeCode int, code, message string) {
+	errMsg, _ := protocol.NewMessage(protocol.TypeError, protocol.ErrorPayload{
+		Code:    code,
+		Message: message,
+	})
+	data, _ := json.Marshal(errMsg)
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	conn.WriteMessage(websocket.TextMessage, data)
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, code), time.Now().Add(writeWait))
+	conn.Close()
+}
+
+// ReloadTunnels re-reads the tunnels file and, on a locked relay,
+// disconnects tunnels whose IDs are no longer listed
+func (s *Server) ReloadTunnels() error {
+	if s.bindings == nil {
+		return nil
+	}
+	if err := s.bindings.Reload(); err != nil {
+		return err
+	}
+	if s.config.LockTunnels {
+		for _, info := range s.registry.List() {
+			if _, ok := s.bindings.Lookup(info.ID); !ok {
+				log.Printf("tunnel %s no longer bound, disconnecting", info.ShortID)
+				s.registry.Disconnect(info.ID, protocol.ErrCodeUnknownTunnel)
+			}
+		}
+	}
+	log.Printf("reloaded %d tunnel IDs from %s", s.bindings.Len(), s.config.TunnelsFile)
+	return nil
+}
+
+// handleWebhook serves webhooks at /t/{tunnel_id}/..., where the ID may
+// also be an alias
 func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	tunnelID := vars["tunnel_id"]
+	name := mux.Vars(r)["tunnel_id"]
+	s.serveWebhook(w, r, name, "/t/"+name)
+}
 
+// serveWebhook relays a webhook for the tunnel or alias name. mount is the
+// part of the URL path naming the tunnel; the rest is forwarded.
+func (s *Server) serveWebhook(w http.ResponseWriter, r *http.Request, name, mount string) {
+	tunnelID := s.resolveTunnel(name)
+
+	if s.draining.Load() {
+		w.Header().Set("Retry-After", strconv.Itoa(drainRetryAfter))
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Reject senders outside the global or per-tunnel IP ranges before
+	// anything is stored or forwarded
+	binding, _ := s.bindings.Lookup(tunnelID)
+	if !s.config.WebhookIPs.empty() || !binding.IPFilter.empty() {
+		addr := s.clientAddr(r)
+		err := s.config.WebhookIPs.check(addr)
+		if err == nil {
+			err = binding.IPFilter.check(addr)
+		}
+		if err != nil {
+			log.Printf("tunnel %s: rejected webhook %s %s: %v", shortID(tunnelID), r.Method, r.URL.Path, err)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	// The relay consumes a tunnel's Basic credentials; they aren't forwarded
+	if binding.BasicAuth != nil {
+		if !binding.BasicAuth.check(r) {
+			log.Printf("tunnel %s: rejected webhook %s %s: missing or wrong basic auth", shortID(tunnelID), r.Method, r.URL.Path)
+			binding.BasicAuth.challenge(w)
+			return
+		}
+		r.Header.Del("Authorization")
+	}
+
+	// Signed URLs expire; the signature is stripped before forwarding
+	if s.config.URLTTL > 0 {
+		if err := s.checkURLSignature(tunnelID, r.URL); err != nil {
+			log.Printf("tunnel %s: rejected webhook %s %s: %v", shortID(tunnelID), r.Method, r.URL.Path, err)
+			if errors.Is(err, errURLExpired) {
+				http.Error(w, "tunnel URL expired", http.StatusGone)
+				return
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	// Webhooks for a recently disconnected tunnel are buffered when enabled
 	tunnel, ok := s.registry.Get(tunnelID)
-	if !ok {
-		http.Error(w, "tunnel not found", http.StatusNotFound)
+	if !ok && !s.registry.offline.Has(tunnelID) {
+		s.writeNoTunnel(w)
+		return
+	}
+	connID := "offline"
+	if tunnel != nil {
+		connID = tunnel.ConnID
+	}
+
+	if ok, wait, rejected := s.registry.Allow(tunnelID); !ok {
+		if rejected == 1 || rejected%100 == 0 {
+			log.Printf("tunnel %s: webhook rate limit exceeded (%d rejected total)", shortID(tunnelID), rejected)
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
 		return
 	}
 
@@ -288,8 +970,19 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build the path (everything after /t/{tunnel_id})
-	path := r.URL.Path[len("/t/"+tunnelID):]
+	// Reject forged webhooks for tunnels with a signing secret
+	verified := false
+	if binding.Signature != nil {
+		if err := binding.Signature.Verify(r.Header, body); err != nil {
+			log.Printf("tunnel %s: rejected webhook %s %s: %v", shortID(tunnelID), r.Method, r.URL.Path, err)
+			http.Error(w, "invalid webhook signature", http.StatusUnauthorized)
+			return
+		}
+		verified = true
+	}
+
+	// Build the path (everything after /t/{tunnel_id} or the alias)
+	path := r.URL.Path[len(mount):]
 	if path == "" {
 		path = "/"
 	}
@@ -306,40 +999,416 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		Headers:   protocol.HeadersFromHTTP(r.Header),
 		Body:      body,
 		Timestamp: time.Now(),
+		Verified:  verified,
+	}
+	if addr := s.clientAddr(r); addr.IsValid() {
+		req.RemoteIP = addr.String()
+	}
+
+	// Run the transform script, which may rewrite or reject the request
+	if s.transformer != nil {
+		rejection, err := s.transformer.Apply(req)
+		if err != nil {
+			log.Printf("[%s] transform error (tunnel=%s, conn=%s): %v", req.ID, shortID(tunnelID), connID, err)
+			http.Error(w, fmt.Sprintf("transform failed (id=%s)", req.ID), http.StatusInternalServerError)
+			return
+		}
+		if rejection != nil {
+			log.Printf("[%s] rejected by transform (tunnel=%s, conn=%s, status=%d)", req.ID, shortID(tunnelID), connID, rejection.Status)
+			http.Error(w, rejection.Body, rejection.Status)
+			return
+		}
+	}
+
+	// A redelivery of a webhook seen within the dedup window gets the first
+	// delivery's response. If the first delivery failed, it is forwarded.
+	var dedupKey string
+	var dedupFirst *dedupEntry
+	var dedupResp *protocol.HTTPResponse
+	if s.dedup != nil {
+		dedupKey = s.dedup.key(tunnelID, r, path, body)
+		entry, first := s.dedup.claim(dedupKey, req.ID)
+		if !first {
+			ctx, cancel := context.WithTimeout(r.Context(), s.config.ResponseWait)
+			resp := entry.wait(ctx)
+			cancel()
+			if resp != nil {
+				log.Printf("[%s] %s %s is a duplicate of %s, answered from cache (tunnel=%s)", req.ID, req.Method, req.Path, entry.requestID, shortID(tunnelID))
+				w.Header().Set("X-Hookshot-Duplicate-Of", entry.requestID)
+				s.writeResponse(w, resp)
+				return
+			}
+		} else {
+			dedupFirst = entry
+			defer func() { s.dedup.finish(dedupKey, dedupFirst, dedupResp) }()
+		}
 	}
 
 	// Store the request
 	s.store.Store(tunnelID, req)
 
+	if s.sink != nil {
+		s.sink.Emit(req)
+	}
+
+	if tunnel == nil {
+		if !s.registry.offline.Add(tunnelID, req) {
+			s.writeNoTunnel(w)
+			return
+		}
+		log.Printf("[%s] %s %s buffered for offline tunnel %s", req.ID, req.Method, req.Path, shortID(tunnelID))
+		dedupResp = ackResponse(req.ID, http.StatusAccepted)
+		s.writeResponse(w, dedupResp)
+		return
+	}
+
+	// Async tunnels ack the sender now; the eventual response is only logged and stored
+	if tunnel.asyncAck != 0 {
+		if tunnel.saturated() {
+			s.writeBusy(w, tunnel, req)
+			return
+		}
+		dedupResp = ackResponse(req.ID, tunnel.asyncAck)
+		s.writeResponse(w, dedupResp)
+		go s.forwardAsync(tunnel, req)
+		return
+	}
+
 	// Forward to client
-	ctx, cancel := context.WithTimeout(r.Context(), responseWait)
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.ResponseWait)
 	defer cancel()
 
-	resp, err := tunnel.ForwardRequest(ctx, req)
+	start := time.Now()
+	tunnel, resp, err := s.forward(ctx, tunnel, req)
+	if errors.Is(err, errSendBufferFull) {
+		s.writeBusy(w, tunnel, req)
+		return
+	}
 	if err != nil {
-		log.Printf("[%s] forward error (tunnel=%s, method=%s, path=%s): %v",
-			req.ID, tunnel.ShortID(), req.Method, req.Path, err)
+		log.Printf("[%s] forward error (tunnel=%s, conn=%s, method=%s, path=%s): %v",
+			req.ID, tunnel.ShortID(), tunnel.ConnID, req.Method, req.Path, err)
 		http.Error(w, fmt.Sprintf("failed to forward request (id=%s)", req.ID), http.StatusBadGateway)
 		return
 	}
+	tunnel.recordTraffic(len(req.Body), len(resp.Body))
+	log.Printf("[%s] %s %s -> %d (tunnel=%s, conn=%s, %s)",
+		req.ID, req.Method, req.Path, resp.StatusCode, tunnel.ShortID(), tunnel.ConnID, time.Since(start).Round(time.Millisecond))
+
+	// Failures (the client's own 502, or a 5xx from the target) aren't
+	// remembered, so a redelivery is forwarded again
+	if resp.Error == "" && resp.StatusCode < 500 {
+		dedupResp = resp
+	}
+	s.writeResponse(w, resp)
+}
+
+// writeBusy refuses a webhook because tunnel's client isn't keeping up,
+// telling the sender to retry shortly instead of holding the connection
+func (s *Server) writeBusy(w http.ResponseWriter, tunnel *Tunnel, req *protocol.HTTPRequest) {
+	if n := tunnel.busy.Add(1); n == 1 || n%100 == 0 {
+		log.Printf("[%s] tunnel %s: send buffer full (conn=%s, %d refused total)", req.ID, tunnel.ShortID(), tunnel.ConnID, n)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(busyRetryAfter))
+	http.Error(w, "tunnel busy", http.StatusServiceUnavailable)
+}
 
-	// Write response back
-	for k, v := range resp.Headers {
-		w.Header().Set(k, v)
+// writeResponse writes a client's response back to the webhook sender. With
+// CORS on, the relay's headers replace the target's so browsers don't see
+// conflicting ones.
+func (s *Server) writeResponse(w http.ResponseWriter, resp *protocol.HTTPResponse) {
+	for k, values := range resp.Headers {
+		if s.config.CORS.enabled() && strings.HasPrefix(http.CanonicalHeaderKey(k), "Access-Control-") {
+			continue
+		}
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
 	}
 	w.WriteHeader(resp.StatusCode)
 	w.Write(resp.Body)
 }
 
+// ackResponse is the empty response acknowledging a webhook that is
+// buffered or forwarded in the background
+func ackResponse(requestID string, status int) *protocol.HTTPResponse {
+	return &protocol.HTTPResponse{
+		StatusCode: status,
+		Headers:    protocol.Headers{"X-Hookshot-Request-Id": {requestID}},
+	}
+}
+
+// forward sends req down tunnel and returns the connection that answered.
+// On a shared tunnel, a request whose connection closed or was too backed up
+// to take it is retried on another of the tunnel's clients.
+func (s *Server) forward(ctx context.Context, tunnel *Tunnel, req *protocol.HTTPRequest) (*Tunnel, *protocol.HTTPResponse, error) {
+	tried := map[*Tunnel]bool{}
+	for {
+		resp, err := tunnel.ForwardRequest(ctx, req)
+		if !errors.Is(err, errNotSent) && !errors.Is(err, errSendBufferFull) {
+			return tunnel, resp, err
+		}
+		tried[tunnel] = true
+		next, ok := s.registry.Get(req.TunnelID)
+		if !ok || tried[next] {
+			return tunnel, resp, err
+		}
+		reason := "closed"
+		if errors.Is(err, errSendBufferFull) {
+			reason = "busy"
+		}
+		log.Printf("[%s] tunnel %s: conn=%s %s, retrying on conn=%s", req.ID, tunnel.ShortID(), tunnel.ConnID, reason, next.ConnID)
+		tunnel = next
+	}
+}
+
+// forwardAsync forwards an already-acked webhook in the background
+func (s *Server) forwardAsync(tunnel *Tunnel, req *protocol.HTTPRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.ResponseWait)
+	defer cancel()
+
+	start := time.Now()
+	tunnel, resp, err := s.forward(ctx, tunnel, req)
+	if err != nil {
+		log.Printf("[%s] async forward error (tunnel=%s, conn=%s, method=%s, path=%s): %v",
+			req.ID, tunnel.ShortID(), tunnel.ConnID, req.Method, req.Path, err)
+		return
+	}
+	tunnel.recordTraffic(len(req.Body), len(resp.Body))
+	log.Printf("[%s] %s %s -> %d (tunnel=%s, conn=%s, %s, async ack %d)",
+		req.ID, req.Method, req.Path, resp.StatusCode, tunnel.ShortID(), tunnel.ConnID,
+		time.Since(start).Round(time.Millisecond), tunnel.asyncAck)
+}
+
+// flushBuffered delivers webhooks buffered while the tunnel was offline, in order
+func (s *Server) flushBuffered(tunnel *Tunnel, reqs []*protocol.HTTPRequest) {
+	log.Printf("tunnel %s: delivering %d buffered webhooks (conn=%s)", tunnel.ShortID(), len(reqs), tunnel.ConnID)
+	for _, req := range reqs {
+		ctx, cancel := context.WithTimeout(context.Background(), s.config.ResponseWait)
+		resp, err := tunnel.ForwardRequest(ctx, req)
+		cancel()
+		if err != nil {
+			log.Printf("[%s] buffered forward error (tunnel=%s, conn=%s): %v", req.ID, tunnel.ShortID(), tunnel.ConnID, err)
+			continue
+		}
+		tunnel.recordTraffic(len(req.Body), len(resp.Body))
+		log.Printf("[%s] %s %s -> %d (tunnel=%s, conn=%s, buffered)",
+			req.ID, req.Method, req.Path, resp.StatusCode, tunnel.ShortID(), tunnel.ConnID)
+	}
+}
+
+// sweepOffline periodically forgets tunnels that stayed offline too long
+func (s *Server) sweepOffline(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.registry.offline.sweep()
+		}
+	}
+}
+
+// defaultNoTunnelBody is sent for webhooks to a tunnel that isn't connected.
+// Providers often show it in their delivery logs, so it says what went wrong.
+const defaultNoTunnelBody = "tunnel not found: no hookshot client is connected for this URL. " +
+	"The receiver may be offline or restarting; retry the delivery later.\n"
+
+// writeNoTunnel responds to a webhook whose tunnel is not connected
+func (s *Server) writeNoTunnel(w http.ResponseWriter) {
+	if s.config.NoTunnelRetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(s.config.NoTunnelRetryAfter))
+	}
+	body := s.config.NoTunnelBody
+	if body == "" {
+		body = defaultNoTunnelBody
+	}
+	// Custom bodies may be HTML
+	w.Header().Set("Content-Type", http.DetectContentType([]byte(body)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(s.config.NoTunnelStatus)
+	io.WriteString(w, body)
+}
+
+// handleListTunnels lists active tunnels. Tunnel IDs are the only secret
+// protecting webhook endpoints, so listing requires a server token.
+func (s *Server) handleListTunnels(w http.ResponseWriter, r *http.Request) {
+	if !s.authEnabled() {
+		http.Error(w, "tunnel listing requires the server to be started with --token", http.StatusForbidden)
+		return
+	}
+
+	// Scoped tokens only see their own tunnels
+	token := requestToken(r)
+	tunnels := make([]TunnelInfo, 0)
+	for _, t := range s.registry.List() {
+		if token.allows(t.ID, t.Label) {
+			t.PublicURL = s.tunnelURL(t.ID)
+			if s.config.URLTTL > 0 {
+				t.PublicURL, _ = s.signedTunnelURL(t.ID)
+			}
+			tunnels = append(tunnels, t)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tunnels)
+}
+
+// Stats is returned by the stats endpoint
+type Stats struct {
+	Tunnels int        `json:"tunnels"`
+	Sink    *SinkStats `json:"sink,omitempty"`
+}
+
+// handleStats reports server counters
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats := Stats{Tunnels: len(s.registry.List())}
+	if s.sink != nil {
+		sinkStats := s.sink.Stats()
+		stats.Sink = &sinkStats
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleTunnelStats reports response latency over a tunnel's stored requests
+func (s *Server) handleTunnelStats(w http.ResponseWriter, r *http.Request) {
+	tunnelID := mux.Vars(r)["tunnel_id"]
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.store.Stats(tunnelID))
+}
+
 // handleListRequests lists recent requests for a tunnel
 func (s *Server) handleListRequests(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tunnelID := vars["tunnel_id"]
 
-	requests := s.store.List(tunnelID)
+	// ?q= searches paths and bodies, ?method= filters exactly. ?before=,
+	// ?since= (request IDs or RFC 3339 times) and ?limit= page through them.
+	params := r.URL.Query()
+	q := RequestQuery{Text: params.Get("q"), Method: params.Get("method")}
+	if v := params.Get("before"); v != "" {
+		q.Before = ParseRequestCursor(v)
+	}
+	if v := params.Get("since"); v != "" {
+		q.Since = ParseRequestCursor(v)
+	}
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.store.Search(tunnelID, q))
+}
+
+// handleClearRequests deletes a tunnel's stored requests
+func (s *Server) handleClearRequests(w http.ResponseWriter, r *http.Request) {
+	tunnelID := mux.Vars(r)["tunnel_id"]
+	n := s.store.Clear(tunnelID)
+	log.Printf("tunnel %s: cleared %d stored requests", shortID(tunnelID), n)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"tunnel_id": tunnelID,
+		"cleared":   n,
+	})
+}
+
+// handleEvents streams a tunnel's new requests and their responses as
+// Server-Sent Events until the client disconnects
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	tunnelID := mux.Vars(r)["tunnel_id"]
+
+	events, cancel := s.store.Subscribe(tunnelID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case event := <-events:
+			data, _ := json.Marshal(event.Summary)
+			if _, err := fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", event.Type, event.Summary.ID, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// handleHAR exports a tunnel's stored requests and responses as a HAR archive
+func (s *Server) handleHAR(w http.ResponseWriter, r *http.Request) {
+	tunnelID := mux.Vars(r)["tunnel_id"]
+
+	// List is newest first; HAR entries are in chronological order
+	summaries := s.store.List(tunnelID)
+	reqs := make([]*protocol.HTTPRequest, 0, len(summaries))
+	responses := make(map[string]*protocol.HTTPResponse, len(summaries))
+	for i := len(summaries) - 1; i >= 0; i-- {
+		req, ok := s.store.Get(summaries[i].ID)
+		if !ok {
+			continue // Evicted since listing
+		}
+		reqs = append(reqs, req)
+		if resp, ok := s.store.GetResponse(req.ID); ok {
+			responses[req.ID] = resp
+		}
+	}
+
+	har := buildHAR(s.tunnelURL(tunnelID), s.config.Version, reqs, responses)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "hookshot-"+shortID(tunnelID)+".har"))
+	json.NewEncoder(w).Encode(har)
+}
+
+// handleGetRequest returns a stored request with its headers, body and response
+func (s *Server) handleGetRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tunnelID := vars["tunnel_id"]
+	requestID := vars["request_id"]
+
+	req, ok := s.store.Get(requestID)
+	if !ok || req.TunnelID != tunnelID {
+		http.Error(w, "request not found", http.StatusNotFound)
+		return
+	}
+
+	detail := RequestDetail{Request: req}
+	if resp, ok := s.store.GetResponse(requestID); ok {
+		detail.Response = resp
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(requests)
+	json.NewEncoder(w).Encode(detail)
 }
 
 // handleReplay replays a request
@@ -366,6 +1435,12 @@ func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	edit, err := s.readReplayEdit(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Create a new request with a new ID for replay
 	replayReq := &protocol.HTTPRequest{
 		ID:        uuid.New().String()[:8],
@@ -375,19 +1450,23 @@ func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
 		Headers:   req.Headers,
 		Body:      req.Body,
 		Timestamp: time.Now(),
+		Verified:  req.Verified,
+	}
+	if edit != nil {
+		edit.apply(replayReq)
 	}
 
 	// Store the replay request
 	s.store.Store(tunnelID, replayReq)
 
 	// Forward to client
-	ctx, cancel := context.WithTimeout(r.Context(), responseWait)
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.ResponseWait)
 	defer cancel()
 
-	resp, err := tunnel.ForwardRequest(ctx, replayReq)
+	tunnel, resp, err := s.forward(ctx, tunnel, replayReq)
 	if err != nil {
-		log.Printf("[%s] replay error (tunnel=%s, original=%s): %v",
-			replayReq.ID, tunnel.ShortID(), requestID, err)
+		log.Printf("[%s] replay error (tunnel=%s, conn=%s, original=%s): %v",
+			replayReq.ID, tunnel.ShortID(), tunnel.ConnID, requestID, err)
 		http.Error(w, fmt.Sprintf("failed to replay request (id=%s)", replayReq.ID), http.StatusBadGateway)
 		return
 	}
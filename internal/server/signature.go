@@ -0,0 +1,150 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signature algorithms
+const (
+	SignatureSHA1   = "sha1"
+	SignatureSHA256 = "sha256"
+	SignatureSHA512 = "sha512"
+	SignatureStripe = "stripe" // Stripe-Signature: t=<ts>,v1=<hex>, signed over "<ts>.<body>"
+)
+
+var errBadSignature = errors.New("signature mismatch")
+
+// defaultStripeTolerance is how old a Stripe signature's timestamp may be,
+// matching Stripe's own libraries
+const defaultStripeTolerance = 5 * time.Minute
+
+// SignatureConfig describes how a tunnel's webhooks are signed. The signature
+// is an HMAC of the raw body, sent hex-encoded (optionally as "sha256=<hex>")
+// or base64-encoded in Header.
+type SignatureConfig struct {
+	Header    string `yaml:"header"`              // e.g., X-Hub-Signature-256 (default Stripe-Signature for stripe)
+	Algorithm string `yaml:"algorithm,omitempty"` // sha1, sha256 (default), sha512 or stripe
+	Secret    string `yaml:"secret"`
+
+	Tolerance time.Duration `yaml:"tolerance,omitempty"` // stripe: max age of the signed timestamp, against replays (default 5m)
+}
+
+// validate checks the config and fills in defaults
+func (c *SignatureConfig) validate() error {
+	if c.Algorithm == "" {
+		c.Algorithm = SignatureSHA256
+	}
+	switch c.Algorithm {
+	case SignatureSHA1, SignatureSHA256, SignatureSHA512:
+		if c.Header == "" {
+			return fmt.Errorf("signature header is required")
+		}
+	case SignatureStripe:
+		if c.Header == "" {
+			c.Header = "Stripe-Signature"
+		}
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q (supported: sha1, sha256, sha512, stripe)", c.Algorithm)
+	}
+	if c.Tolerance < 0 {
+		return fmt.Errorf("signature tolerance %s must be >= 0", c.Tolerance)
+	}
+	if c.Algorithm == SignatureStripe && c.Tolerance == 0 {
+		c.Tolerance = defaultStripeTolerance
+	}
+	if c.Secret == "" {
+		return fmt.Errorf("signature secret is required")
+	}
+	return nil
+}
+
+// Verify checks the signature header against the raw body
+func (c *SignatureConfig) Verify(header http.Header, body []byte) error {
+	value := strings.TrimSpace(header.Get(c.Header))
+	if value == "" {
+		return fmt.Errorf("missing %s header", c.Header)
+	}
+
+	if c.Algorithm == SignatureStripe {
+		return c.verifyStripe(value, body)
+	}
+
+	expected := c.mac(body)
+	// GitHub style: "sha256=<hex>"
+	value = strings.TrimPrefix(value, c.Algorithm+"=")
+	if sig, err := hex.DecodeString(value); err == nil && hmac.Equal(sig, expected) {
+		return nil
+	}
+	if sig, err := base64.StdEncoding.DecodeString(value); err == nil && hmac.Equal(sig, expected) {
+		return nil
+	}
+	return errBadSignature
+}
+
+// verifyStripe checks a Stripe-Signature header, accepting any v1 signature.
+// A timestamp further than Tolerance from now is rejected, so a captured
+// payload can't be replayed later.
+func (c *SignatureConfig) verifyStripe(value string, body []byte) error {
+	var timestamp string
+	var sigs []string
+	for _, part := range strings.Split(value, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if timestamp == "" || len(sigs) == 0 {
+		return fmt.Errorf("malformed %s header", c.Header)
+	}
+
+	expected := c.mac([]byte(timestamp + "." + string(body)))
+	for _, s := range sigs {
+		if sig, err := hex.DecodeString(s); err == nil && hmac.Equal(sig, expected) {
+			return c.checkTimestamp(timestamp)
+		}
+	}
+	return errBadSignature
+}
+
+// checkTimestamp rejects a signed Unix timestamp outside Tolerance of now
+func (c *SignatureConfig) checkTimestamp(timestamp string) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed %s timestamp", c.Header)
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > c.Tolerance || age < -c.Tolerance {
+		return fmt.Errorf("%s timestamp outside the %s tolerance", c.Header, c.Tolerance)
+	}
+	return nil
+}
+
+// mac computes the HMAC of data with the configured secret
+func (c *SignatureConfig) mac(data []byte) []byte {
+	var h func() hash.Hash
+	switch c.Algorithm {
+	case SignatureSHA1:
+		h = sha1.New
+	case SignatureSHA512:
+		h = sha512.New
+	default:
+		h = sha256.New
+	}
+	m := hmac.New(h, []byte(c.Secret))
+	m.Write(data)
+	return m.Sum(nil)
+}
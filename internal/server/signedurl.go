@@ -0,0 +1,81 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// urlSigParam is the query parameter carrying a signed URL's expiry and
+// signature, as "<unix expiry>.<hex HMAC>"
+const urlSigParam = "hookshot_sig"
+
+var (
+	errURLUnsigned = errors.New("missing URL signature")
+	errURLBadSig   = errors.New("invalid URL signature")
+	errURLExpired  = errors.New("URL expired")
+)
+
+// urlSigningKey derives the key for signed tunnel URLs from the server
+// token, so URLs stay valid across restarts. Without a token the key is
+// random and URLs last only as long as the process.
+func urlSigningKey(tokens []*AuthToken) []byte {
+	if len(tokens) == 0 {
+		key := make([]byte, 32)
+		rand.Read(key)
+		return key
+	}
+	mac := hmac.New(sha256.New, []byte(tokens[0].Token))
+	mac.Write([]byte("hookshot signed tunnel URLs"))
+	return mac.Sum(nil)
+}
+
+// urlSignature is the HMAC over a tunnel ID and expiry
+func (s *Server) urlSignature(tunnelID string, expires int64) string {
+	mac := hmac.New(sha256.New, s.urlKey)
+	mac.Write([]byte(tunnelID + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedTunnelURL returns the tunnel's public URL with a signature valid
+// for URLTTL, and when it expires
+func (s *Server) signedTunnelURL(tunnelID string) (string, time.Time) {
+	expires := time.Now().Add(s.config.URLTTL).Truncate(time.Second)
+	sig := strconv.FormatInt(expires.Unix(), 10) + "." + s.urlSignature(tunnelID, expires.Unix())
+	return s.tunnelURL(tunnelID) + "?" + urlSigParam + "=" + sig, expires
+}
+
+// checkURLSignature verifies the signature in a webhook's query and removes
+// it, so the target never sees it
+func (s *Server) checkURLSignature(tunnelID string, u *url.URL) error {
+	// Drop the parameter in place; re-encoding would reorder the rest
+	var raw string
+	var rest []string
+	for part := range strings.SplitSeq(u.RawQuery, "&") {
+		if v, ok := strings.CutPrefix(part, urlSigParam+"="); ok {
+			raw = v
+		} else if part != "" {
+			rest = append(rest, part)
+		}
+	}
+	u.RawQuery = strings.Join(rest, "&")
+	if raw == "" {
+		return errURLUnsigned
+	}
+
+	exp, sig, ok := strings.Cut(raw, ".")
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if !ok || err != nil || !hmac.Equal([]byte(sig), []byte(s.urlSignature(tunnelID, expires))) {
+		return errURLBadSig
+	}
+	if time.Now().Unix() >= expires {
+		return errURLExpired
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+const (
+	defaultSinkQueueSize = 1000
+	sinkPublishTimeout   = 10 * time.Second
+	sinkMaxAttempts      = 3
+)
+
+// Sink payload modes
+const (
+	SinkPayloadSummary = "summary"
+	SinkPayloadFull    = "full"
+)
+
+// WebhookEvent is published to the event sink for every received webhook
+type WebhookEvent struct {
+	ID         string           `json:"id"`
+	TunnelID   string           `json:"tunnel_id"`
+	Method     string           `json:"method"`
+	Path       string           `json:"path"`
+	Headers    protocol.Headers `json:"headers,omitempty"`
+	Body       []byte           `json:"body,omitempty"`
+	BodySize   int              `json:"body_size"`
+	ReceivedAt time.Time        `json:"received_at"`
+}
+
+// Publisher delivers webhook events to an external system. Implementations
+// must be safe for concurrent use.
+type Publisher interface {
+	Publish(ctx context.Context, event *WebhookEvent) error
+}
+
+// NewPublisher creates a publisher for a sink URL. Only http and https sinks
+// are currently supported.
+func NewPublisher(sinkURL string) (Publisher, error) {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &HTTPPublisher{
+			URL:    sinkURL,
+			Client: &http.Client{Timeout: sinkPublishTimeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported sink scheme %q (supported: http, https)", u.Scheme)
+	}
+}
+
+// HTTPPublisher POSTs each event as JSON to a URL
+type HTTPPublisher struct {
+	URL    string
+	Client *http.Client
+}
+
+// Publish sends the event, treating any non-2xx response as a failure
+func (p *HTTPPublisher) Publish(ctx context.Context, event *WebhookEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sink returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SinkStats holds event sink counters
+type SinkStats struct {
+	Published uint64 `json:"published"`
+	Failed    uint64 `json:"failed"`
+	Dropped   uint64 `json:"dropped"`
+	Queued    int    `json:"queued"`
+}
+
+// eventSink queues webhook events and publishes them in the background so
+// a slow or unavailable sink never delays forwarding
+type eventSink struct {
+	pub   Publisher
+	queue chan *WebhookEvent
+	full  bool // include headers and body
+
+	published atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// newEventSink creates a sink with a bounded queue
+func newEventSink(pub Publisher, queueSize int, payload string) *eventSink {
+	if queueSize <= 0 {
+		queueSize = defaultSinkQueueSize
+	}
+	return &eventSink{
+		pub:   pub,
+		queue: make(chan *WebhookEvent, queueSize),
+		full:  payload == SinkPayloadFull,
+	}
+}
+
+// Emit queues an event for req, dropping it if the queue is full
+func (s *eventSink) Emit(req *protocol.HTTPRequest) {
+	event := &WebhookEvent{
+		ID:         req.ID,
+		TunnelID:   req.TunnelID,
+		Method:     req.Method,
+		Path:       req.Path,
+		BodySize:   len(req.Body),
+		ReceivedAt: req.Timestamp,
+	}
+	if s.full {
+		event.Headers = req.Headers
+		event.Body = req.Body
+	}
+
+	select {
+	case s.queue <- event:
+	default:
+		if n := s.dropped.Add(1); n == 1 || n%100 == 0 {
+			log.Printf("[%s] sink queue full, dropped event (%d dropped total)", req.ID, n)
+		}
+	}
+}
+
+// run publishes queued events until ctx is cancelled
+func (s *eventSink) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.queue:
+			s.publish(ctx, event)
+		}
+	}
+}
+
+// publish delivers one event, retrying with backoff
+func (s *eventSink) publish(ctx context.Context, event *WebhookEvent) {
+	backoff := 250 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= sinkMaxAttempts; attempt++ {
+		pubCtx, cancel := context.WithTimeout(ctx, sinkPublishTimeout)
+		err = s.pub.Publish(pubCtx, event)
+		cancel()
+		if err == nil {
+			s.published.Add(1)
+			return
+		}
+		if attempt == sinkMaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	n := s.failed.Add(1)
+	log.Printf("[%s] sink publish failed after %d attempts (%d failed total): %v", event.ID, sinkMaxAttempts, n, err)
+}
+
+// Stats returns a snapshot of the sink counters
+func (s *eventSink) Stats() SinkStats {
+	return SinkStats{
+		Published: s.published.Load(),
+		Failed:    s.failed.Load(),
+		Dropped:   s.dropped.Load(),
+		Queued:    len(s.queue),
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS requests (
+	seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+	id          TEXT NOT NULL UNIQUE,
+	tunnel_id   TEXT NOT NULL,
+	request     BLOB NOT NULL,
+	response    BLOB,
+	duration_us INTEGER
+);
+CREATE INDEX IF NOT EXISTS requests_tunnel ON requests (tunnel_id, seq);
+`
+
+// SQLiteStore persists request history to a SQLite database so it survives
+// restarts. Reads are served from an in-memory copy loaded on open; writes
+// go to both.
+type SQLiteStore struct {
+	mem         *RequestStore
+	db          *sql.DB
+	maxRequests int
+}
+
+// OpenSQLiteStore opens (or creates) the database at path and loads its history
+func OpenSQLiteStore(path string, maxRequests int) (*SQLiteStore, error) {
+	if maxRequests <= 0 {
+		maxRequests = defaultMaxRequests
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	// SQLite allows a single writer; serialize access instead of retrying on SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize store %s: %w", path, err)
+	}
+	// Stores created before durations were recorded lack the column
+	if _, err := db.Exec(`ALTER TABLE requests ADD COLUMN duration_us INTEGER`); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate store %s: %w", path, err)
+	}
+
+	s := &SQLiteStore{
+		mem:         NewRequestStore(maxRequests),
+		db:          db,
+		maxRequests: maxRequests,
+	}
+	if err := s.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads existing rows into memory, oldest first, and drops rows beyond
+// the current history limit
+func (s *SQLiteStore) load() error {
+	rows, err := s.db.Query(`SELECT tunnel_id, request, response, duration_us FROM requests ORDER BY seq`)
+	if err != nil {
+		return fmt.Errorf("failed to load store: %w", err)
+	}
+	defer rows.Close()
+
+	tunnels := make(map[string]bool)
+	count := 0
+	for rows.Next() {
+		var tunnelID string
+		var reqData, respData []byte
+		var durationUs sql.NullInt64
+		if err := rows.Scan(&tunnelID, &reqData, &respData, &durationUs); err != nil {
+			return fmt.Errorf("failed to load store: %w", err)
+		}
+
+		var req protocol.HTTPRequest
+		if err := json.Unmarshal(reqData, &req); err != nil {
+			log.Printf("store: skipping unreadable request: %v", err)
+			continue
+		}
+		s.mem.Store(tunnelID, &req)
+		if respData != nil {
+			var resp protocol.HTTPResponse
+			if err := json.Unmarshal(respData, &resp); err == nil {
+				s.mem.StoreResponse(&resp, time.Duration(durationUs.Int64)*time.Microsecond)
+			}
+		}
+		tunnels[tunnelID] = true
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load store: %w", err)
+	}
+
+	for tunnelID := range tunnels {
+		s.evict(tunnelID)
+	}
+	if count > 0 {
+		log.Printf("loaded %d stored requests across %d tunnels", count, len(tunnels))
+	}
+	return nil
+}
+
+// Store stores a request for a tunnel
+func (s *SQLiteStore) Store(tunnelID string, req *protocol.HTTPRequest) {
+	s.mem.Store(tunnelID, req)
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		log.Printf("[%s] store: failed to encode request: %v", req.ID, err)
+		return
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO requests (id, tunnel_id, request) VALUES (?, ?, ?)`,
+		req.ID, tunnelID, data); err != nil {
+		log.Printf("[%s] store: failed to persist request: %v", req.ID, err)
+		return
+	}
+	s.evict(tunnelID)
+}
+
+// evict deletes a tunnel's rows beyond maxRequests
+func (s *SQLiteStore) evict(tunnelID string) {
+	_, err := s.db.Exec(`DELETE FROM requests WHERE tunnel_id = ? AND seq NOT IN (
+		SELECT seq FROM requests WHERE tunnel_id = ? ORDER BY seq DESC LIMIT ?)`,
+		tunnelID, tunnelID, s.maxRequests)
+	if err != nil {
+		log.Printf("store: failed to evict old requests: %v", err)
+	}
+}
+
+// StoreResponse stores the response for a request and how long it took
+func (s *SQLiteStore) StoreResponse(resp *protocol.HTTPResponse, duration time.Duration) {
+	s.mem.StoreResponse(resp, duration)
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("[%s] store: failed to encode response: %v", resp.RequestID, err)
+		return
+	}
+	var durationUs any
+	if duration > 0 {
+		durationUs = duration.Microseconds()
+	}
+	if _, err := s.db.Exec(`UPDATE requests SET response = ?, duration_us = ? WHERE id = ?`, data, durationUs, resp.RequestID); err != nil {
+		log.Printf("[%s] store: failed to persist response: %v", resp.RequestID, err)
+	}
+}
+
+// Get retrieves a request by ID
+func (s *SQLiteStore) Get(requestID string) (*protocol.HTTPRequest, bool) {
+	return s.mem.Get(requestID)
+}
+
+// GetResponse retrieves a response by request ID
+func (s *SQLiteStore) GetResponse(requestID string) (*protocol.HTTPResponse, bool) {
+	return s.mem.GetResponse(requestID)
+}
+
+// List returns summaries of requests for a tunnel (newest first)
+func (s *SQLiteStore) List(tunnelID string) []RequestSummary {
+	return s.mem.List(tunnelID)
+}
+
+// Search returns summaries of a tunnel's requests matching q (newest first)
+func (s *SQLiteStore) Search(tunnelID string, q RequestQuery) []RequestSummary {
+	return s.mem.Search(tunnelID, q)
+}
+
+// Stats summarizes response latency over a tunnel's stored requests
+func (s *SQLiteStore) Stats(tunnelID string) LatencyStats {
+	return s.mem.Stats(tunnelID)
+}
+
+// Clear removes all requests for a tunnel and returns how many there were
+func (s *SQLiteStore) Clear(tunnelID string) int {
+	n := s.mem.Clear(tunnelID)
+	if _, err := s.db.Exec(`DELETE FROM requests WHERE tunnel_id = ?`, tunnelID); err != nil {
+		log.Printf("store: failed to clear requests: %v", err)
+	}
+	return n
+}
+
+// Subscribe streams events for a tunnel's new requests and responses
+func (s *SQLiteStore) Subscribe(tunnelID string) (<-chan RequestEvent, func()) {
+	return s.mem.Subscribe(tunnelID)
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
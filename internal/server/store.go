@@ -1,20 +1,62 @@
 package server
 
 import (
+	"bytes"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/lance0/hookshot/internal/protocol"
 )
 
 const defaultMaxRequests = 100
 
-// RequestStore stores request history for replay functionality
+// RequestStorage is implemented by request history backends
+type RequestStorage interface {
+	Store(tunnelID string, req *protocol.HTTPRequest)
+	StoreResponse(resp *protocol.HTTPResponse, duration time.Duration) // duration: forward to response (0 = unknown)
+	Get(requestID string) (*protocol.HTTPRequest, bool)
+	GetResponse(requestID string) (*protocol.HTTPResponse, bool)
+	List(tunnelID string) []RequestSummary
+	Search(tunnelID string, q RequestQuery) []RequestSummary
+	Stats(tunnelID string) LatencyStats
+	Clear(tunnelID string) int // Returns how many requests were removed
+	Close() error
+
+	// Subscribe streams events for a tunnel's new requests and responses
+	// until the returned cancel function is called
+	Subscribe(tunnelID string) (<-chan RequestEvent, func())
+}
+
+// Request event types
+const (
+	EventRequest  = "request"
+	EventResponse = "response"
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag behind
+// before events are dropped for it
+const subscriberBuffer = 64
+
+// RequestEvent reports a stored request (EventRequest) or the arrival of its
+// response (EventResponse, with StatusCode set)
+type RequestEvent struct {
+	Type    string
+	Summary RequestSummary
+}
+
+// RequestStore stores request history in memory for replay functionality
 type RequestStore struct {
 	mu          sync.RWMutex
 	requests    map[string]*protocol.HTTPRequest    // requestID -> request
 	byTunnel    map[string][]string                 // tunnelID -> []requestID (ordered)
 	responses   map[string]*protocol.HTTPResponse   // requestID -> response
+	durations   map[string]time.Duration            // requestID -> time to response, when known
 	maxRequests int
+
+	subMu       sync.Mutex
+	subscribers map[string]map[chan RequestEvent]struct{} // tunnelID -> subscriber channels
 }
 
 // NewRequestStore creates a new request store
@@ -26,15 +68,15 @@ func NewRequestStore(maxRequests int) *RequestStore {
 		requests:    make(map[string]*protocol.HTTPRequest),
 		byTunnel:    make(map[string][]string),
 		responses:   make(map[string]*protocol.HTTPResponse),
+		durations:   make(map[string]time.Duration),
 		maxRequests: maxRequests,
+		subscribers: make(map[string]map[chan RequestEvent]struct{}),
 	}
 }
 
 // Store stores a request for a tunnel
 func (s *RequestStore) Store(tunnelID string, req *protocol.HTTPRequest) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	s.requests[req.ID] = req
 	s.byTunnel[tunnelID] = append(s.byTunnel[tunnelID], req.ID)
 
@@ -44,14 +86,85 @@ func (s *RequestStore) Store(tunnelID string, req *protocol.HTTPRequest) {
 		s.byTunnel[tunnelID] = s.byTunnel[tunnelID][1:]
 		delete(s.requests, oldID)
 		delete(s.responses, oldID)
+		delete(s.durations, oldID)
 	}
+	s.mu.Unlock()
+
+	s.publish(tunnelID, RequestEvent{Type: EventRequest, Summary: summarize(req, nil, 0)})
 }
 
-// StoreResponse stores the response for a request
-func (s *RequestStore) StoreResponse(resp *protocol.HTTPResponse) {
+// StoreResponse stores the response for a request and how long it took.
+// Responses to requests not in the store are ignored.
+func (s *RequestStore) StoreResponse(resp *protocol.HTTPResponse, duration time.Duration) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	req := s.requests[resp.RequestID]
+	if req == nil {
+		s.mu.Unlock()
+		return
+	}
 	s.responses[resp.RequestID] = resp
+	if duration > 0 {
+		s.durations[resp.RequestID] = duration
+	}
+	s.mu.Unlock()
+
+	s.publish(req.TunnelID, RequestEvent{Type: EventResponse, Summary: summarize(req, resp, duration)})
+}
+
+// Stats summarizes response latency over a tunnel's stored requests
+func (s *RequestStore) Stats(tunnelID string) LatencyStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := s.byTunnel[tunnelID]
+	samples := make([]timedResponse, 0, len(ids))
+	for _, id := range ids {
+		resp, d := s.responses[id], s.durations[id]
+		if resp == nil || d == 0 {
+			continue
+		}
+		samples = append(samples, timedResponse{status: resp.StatusCode, duration: d})
+	}
+	return latencyStats(samples)
+}
+
+// Subscribe streams events for a tunnel's new requests and responses. Events
+// are dropped for subscribers that fall too far behind.
+func (s *RequestStore) Subscribe(tunnelID string) (<-chan RequestEvent, func()) {
+	ch := make(chan RequestEvent, subscriberBuffer)
+
+	s.subMu.Lock()
+	if s.subscribers[tunnelID] == nil {
+		s.subscribers[tunnelID] = make(map[chan RequestEvent]struct{})
+	}
+	s.subscribers[tunnelID][ch] = struct{}{}
+	s.subMu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			s.subMu.Lock()
+			delete(s.subscribers[tunnelID], ch)
+			if len(s.subscribers[tunnelID]) == 0 {
+				delete(s.subscribers, tunnelID)
+			}
+			s.subMu.Unlock()
+		})
+	}
+	return ch, cancel
+}
+
+// publish delivers an event to a tunnel's subscribers without blocking
+func (s *RequestStore) publish(tunnelID string, event RequestEvent) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for ch := range s.subscribers[tunnelID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
 }
 
 // Get retrieves a request by ID
@@ -77,6 +190,39 @@ type RequestSummary struct {
 	Path       string `json:"path"`
 	Timestamp  string `json:"timestamp"`
 	StatusCode int    `json:"status_code,omitempty"`
+	StatusText string `json:"status_text,omitempty"` // Reason phrase the target sent
+	DurationMs int64  `json:"duration_ms,omitempty"` // Time from forwarding to the response
+	Verified   bool   `json:"verified,omitempty"`
+	Error      string `json:"error,omitempty"` // Why the client couldn't forward the request
+
+	BodySize    int `json:"body_size"`                    // Request body bytes
+	ResBodySize int `json:"response_body_size,omitempty"` // Response body bytes
+}
+
+// RequestDetail is a stored request with its response, if one was received
+type RequestDetail struct {
+	Request  *protocol.HTTPRequest  `json:"request"`
+	Response *protocol.HTTPResponse `json:"response,omitempty"`
+}
+
+// summarize builds a request's summary; resp may be nil and duration 0
+func summarize(req *protocol.HTTPRequest, resp *protocol.HTTPResponse, duration time.Duration) RequestSummary {
+	summary := RequestSummary{
+		ID:        req.ID,
+		Method:    req.Method,
+		Path:      req.Path,
+		Timestamp: req.Timestamp.Format("2006-01-02T15:04:05Z"),
+		Verified:  req.Verified,
+		BodySize:  len(req.Body),
+	}
+	if resp != nil {
+		summary.StatusCode = resp.StatusCode
+		summary.StatusText = resp.StatusText
+		summary.DurationMs = duration.Milliseconds()
+		summary.ResBodySize = len(resp.Body)
+		summary.Error = resp.Error
+	}
+	return summary
 }
 
 // List returns summaries of requests for a tunnel (newest first)
@@ -93,28 +239,115 @@ func (s *RequestStore) List(tunnelID string) []RequestSummary {
 		if req == nil {
 			continue
 		}
-		summary := RequestSummary{
-			ID:        req.ID,
-			Method:    req.Method,
-			Path:      req.Path,
-			Timestamp: req.Timestamp.Format("2006-01-02T15:04:05Z"),
+		result = append(result, summarize(req, s.responses[req.ID], s.durations[req.ID]))
+	}
+	return result
+}
+
+// RequestQuery filters a tunnel's request history
+type RequestQuery struct {
+	Text   string // Case-insensitive substring of the path or request body
+	Method string // Exact method (case-insensitive)
+
+	Before RequestCursor // Only requests older than this
+	Since  RequestCursor // Only requests newer than this
+	Limit  int           // At most this many, newest first (0 = no limit)
+}
+
+// RequestCursor marks a point in a tunnel's history by request ID or, when
+// ID is empty, by time. The zero cursor doesn't filter.
+type RequestCursor struct {
+	ID   string
+	Time time.Time
+}
+
+// ParseRequestCursor parses an RFC 3339 timestamp, or takes anything else
+// as a request ID
+func ParseRequestCursor(s string) RequestCursor {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return RequestCursor{Time: t}
+	}
+	return RequestCursor{ID: s}
+}
+
+// bounds returns the range of ids, as [lo, hi), that lies between the
+// query's ID cursors. An ID no longer in history was evicted, so it is
+// older than everything left.
+func (q RequestQuery) bounds(ids []string) (int, int) {
+	lo, hi := 0, len(ids)
+	if q.Since.ID != "" {
+		if i := slices.Index(ids, q.Since.ID); i >= 0 {
+			lo = i + 1
+		}
+	}
+	if q.Before.ID != "" {
+		hi = max(slices.Index(ids, q.Before.ID), 0)
+	}
+	return lo, hi
+}
+
+// inTimeRange reports whether req falls between the query's time cursors
+func (q RequestQuery) inTimeRange(req *protocol.HTTPRequest) bool {
+	if !q.Since.Time.IsZero() && !req.Timestamp.After(q.Since.Time) {
+		return false
+	}
+	if !q.Before.Time.IsZero() && !req.Timestamp.Before(q.Before.Time) {
+		return false
+	}
+	return true
+}
+
+// matches reports whether req satisfies the query. text is the lowercased
+// Text.
+func (q RequestQuery) matches(req *protocol.HTTPRequest, text string) bool {
+	if q.Method != "" && !strings.EqualFold(req.Method, q.Method) {
+		return false
+	}
+	if text == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(req.Path), text) ||
+		bytes.Contains(bytes.ToLower(req.Body), []byte(text))
+}
+
+// Search returns summaries of a tunnel's requests matching q (newest first)
+func (s *RequestStore) Search(tunnelID string, q RequestQuery) []RequestSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	text := strings.ToLower(q.Text)
+	ids := s.byTunnel[tunnelID]
+	lo, hi := q.bounds(ids)
+	result := make([]RequestSummary, 0)
+	for i := hi - 1; i >= lo; i-- {
+		if q.Limit > 0 && len(result) == q.Limit {
+			break
 		}
-		if resp, ok := s.responses[req.ID]; ok {
-			summary.StatusCode = resp.StatusCode
+		req := s.requests[ids[i]]
+		if req == nil || !q.inTimeRange(req) || !q.matches(req, text) {
+			continue
 		}
-		result = append(result, summary)
+		result = append(result, summarize(req, s.responses[req.ID], s.durations[req.ID]))
 	}
 	return result
 }
 
-// Clear removes all requests for a tunnel
-func (s *RequestStore) Clear(tunnelID string) {
+// Clear removes all requests for a tunnel and returns how many there were
+func (s *RequestStore) Clear(tunnelID string) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	n := len(s.byTunnel[tunnelID])
 	for _, id := range s.byTunnel[tunnelID] {
 		delete(s.requests, id)
 		delete(s.responses, id)
+		delete(s.durations, id)
 	}
 	delete(s.byTunnel, tunnelID)
+	return n
+}
+
+// Close is a no-op for the in-memory store
+func (s *RequestStore) Close() error {
+	return nil
 }
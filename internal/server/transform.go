@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/lance0/hookshot/internal/protocol"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const (
+	defaultTransformTimeout = 500 * time.Millisecond
+	transformMaxSteps       = 10_000_000
+)
+
+// Transformer runs a sandboxed Starlark script against each incoming webhook.
+//
+// The script must define a function:
+//
+//	def transform(req):
+//	    # req is a dict: method, path, headers (dict), body (bytes)
+//	    # repeated headers are lists of strings, others are plain strings
+//	    return None                          # forward unchanged
+//	    return req                           # forward with modifications
+//	    return {"reject": 403, "body": "no"} # reject without forwarding
+//
+// Any of method, path, headers, or body present in the returned dict replace
+// the request's values. Scripts have no file, network, or process access.
+type Transformer struct {
+	path    string
+	fn      starlark.Callable
+	timeout time.Duration
+}
+
+// transformRejection is returned when the script rejects a request
+type transformRejection struct {
+	Status int
+	Body   string
+}
+
+// LoadTransformer loads and validates a transform script
+func LoadTransformer(path string, timeout time.Duration) (*Transformer, error) {
+	if timeout <= 0 {
+		timeout = defaultTransformTimeout
+	}
+
+	thread := &starlark.Thread{Name: "load", Print: transformPrint}
+	thread.SetMaxExecutionSteps(transformMaxSteps)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transform script: %w", err)
+	}
+
+	fn, ok := globals["transform"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("transform script %s must define a transform(req) function", path)
+	}
+
+	// Freeze globals so concurrent requests can share them safely
+	globals.Freeze()
+
+	return &Transformer{path: path, fn: fn, timeout: timeout}, nil
+}
+
+// Apply runs the script against req, modifying it in place. A non-nil
+// rejection means the request must not be forwarded.
+func (t *Transformer) Apply(req *protocol.HTTPRequest) (*transformRejection, error) {
+	thread := &starlark.Thread{Name: "transform:" + req.ID, Print: transformPrint}
+	thread.SetMaxExecutionSteps(transformMaxSteps)
+
+	timer := time.AfterFunc(t.timeout, func() {
+		thread.Cancel(fmt.Sprintf("exceeded %s time limit", t.timeout))
+	})
+	defer timer.Stop()
+
+	result, err := starlark.Call(thread, t.fn, starlark.Tuple{requestToStarlark(req)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := result.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case *starlark.Dict:
+		return applyTransformResult(req, v)
+	default:
+		return nil, fmt.Errorf("transform returned %s, expected dict or None", result.Type())
+	}
+}
+
+// requestToStarlark builds the mutable dict passed to the script
+func requestToStarlark(req *protocol.HTTPRequest) *starlark.Dict {
+	headers := starlark.NewDict(len(req.Headers))
+	for k, values := range req.Headers {
+		if len(values) == 1 {
+			headers.SetKey(starlark.String(k), starlark.String(values[0]))
+			continue
+		}
+		list := make([]starlark.Value, len(values))
+		for i, v := range values {
+			list[i] = starlark.String(v)
+		}
+		headers.SetKey(starlark.String(k), starlark.NewList(list))
+	}
+
+	d := starlark.NewDict(5)
+	d.SetKey(starlark.String("id"), starlark.String(req.ID))
+	d.SetKey(starlark.String("method"), starlark.String(req.Method))
+	d.SetKey(starlark.String("path"), starlark.String(req.Path))
+	d.SetKey(starlark.String("headers"), headers)
+	d.SetKey(starlark.String("body"), starlark.Bytes(req.Body))
+	return d
+}
+
+// applyTransformResult copies script output back onto the request. A
+// rewritten request no longer matches what the sender signed, so it is no
+// longer verified.
+func applyTransformResult(req *protocol.HTTPRequest, d *starlark.Dict) (*transformRejection, error) {
+	if v, found, _ := d.Get(starlark.String("reject")); found {
+		status, err := starlark.AsInt32(v)
+		if err != nil || status < 400 || status > 599 {
+			return nil, fmt.Errorf("reject must be a 4xx/5xx status code, got %s", v)
+		}
+		rejection := &transformRejection{Status: status, Body: http.StatusText(status)}
+		if b, found, _ := d.Get(starlark.String("body")); found {
+			rejection.Body, _ = starlarkText(b)
+		}
+		return rejection, nil
+	}
+
+	orig := *req
+	if v, found, _ := d.Get(starlark.String("method")); found {
+		s, ok := starlark.AsString(v)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("method must be a non-empty string")
+		}
+		req.Method = s
+	}
+	if v, found, _ := d.Get(starlark.String("path")); found {
+		s, ok := starlark.AsString(v)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("path must be a non-empty string")
+		}
+		req.Path = s
+	}
+	if v, found, _ := d.Get(starlark.String("headers")); found {
+		hd, ok := v.(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("headers must be a dict")
+		}
+		headers := make(protocol.Headers, hd.Len())
+		for _, item := range hd.Items() {
+			k, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("header names must be strings")
+			}
+			if list, ok := item[1].(*starlark.List); ok {
+				for i := 0; i < list.Len(); i++ {
+					v, _ := starlarkText(list.Index(i))
+					headers[k] = append(headers[k], v)
+				}
+				continue
+			}
+			v, _ := starlarkText(item[1])
+			headers[k] = []string{v}
+		}
+		req.Headers = headers
+	}
+	if v, found, _ := d.Get(starlark.String("body")); found {
+		s, ok := starlarkText(v)
+		if !ok {
+			return nil, fmt.Errorf("body must be bytes or string")
+		}
+		req.Body = []byte(s)
+	}
+
+	if req.Method != orig.Method || req.Path != orig.Path || !bytes.Equal(req.Body, orig.Body) ||
+		!maps.EqualFunc(req.Headers, orig.Headers, slices.Equal[[]string]) {
+		req.Verified = false
+	}
+	return nil, nil
+}
+
+// starlarkText returns the contents of a string or bytes value
+func starlarkText(v starlark.Value) (string, bool) {
+	switch x := v.(type) {
+	case starlark.String:
+		return string(x), true
+	case starlark.Bytes:
+		return string(x), true
+	}
+	return v.String(), false
+}
+
+func transformPrint(thread *starlark.Thread, msg string) {
+	log.Printf("[%s] %s", thread.Name, msg)
+}
@@ -3,9 +3,15 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,122 +20,572 @@ import (
 )
 
 const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	responseWait   = 30 * time.Second
+	writeWait          = 10 * time.Second
+	defaultPongTimeout = 60 * time.Second
 )
 
-// Tunnel represents a connected client tunnel
-type Tunnel struct {
-	ID        string // Full UUID for security
+// Load-balancing strategies for tunnels with multiple clients
+const (
+	BalanceRoundRobin    = "round-robin"
+	BalanceLeastInFlight = "least-in-flight"
+	BalanceFailover      = "failover" // Oldest connection is primary; the rest are standbys in join order
+)
+
+// session is a client's WebSocket connection. It carries one or more
+// tunnels; a multi-tunnel client registers several over one connection.
+type session struct {
+	ConnID    string // Short per-connection ID for log correlation
 	conn      *websocket.Conn
 	send      chan []byte
-	pending   map[string]chan *protocol.HTTPResponse // requestID -> response channel
+	busyAt    int                        // Queued messages at which new webhooks are refused
+	final     chan []byte                // Last message before the server closes the connection (e.g., idle expiry)
+	pending   map[string]pendingResponse // requestID -> waiting forward
 	pendingMu sync.Mutex
 	done      chan struct{}
 	closeOnce sync.Once
+	inFlight  atomic.Int64 // Requests awaiting a response on this connection
+	asyncAck  int          // Status to ack webhooks with before forwarding (0 = wait for the response)
+	gzip      bool         // Client accepts gzip-compressed bodies
+	readLimit int64        // Max message size after permessage-deflate
+	token     *AuthToken   // Token the client registered with (nil = unrestricted)
+	chunkSize int          // Split request bodies larger than this (0 = client can't reassemble)
+
+	pingInterval time.Duration // How often to ping the client
+	pongTimeout  time.Duration // Drop the connection after this long without a pong
+
+	responses *protocol.Reassembler[*protocol.HTTPResponse] // Chunked response bodies in progress
+
+	tunnels     []*Tunnel    // Registered over this connection; unregistered when it ends
+	parseErrors atomic.Int64 // Malformed frames received from the client
 }
 
-// ShortID returns the first 8 characters for display purposes
-func (t *Tunnel) ShortID() string {
-	if len(t.ID) >= 8 {
-		return t.ID[:8]
+// newSession wraps a client connection
+func newSession(conn *websocket.Conn, opts tunnelOptions) *session {
+	size := opts.SendBuffer
+	if size <= 0 {
+		size = defaultSendBuffer
+	}
+	return &session{
+		ConnID:    uuid.New().String()[:8],
+		conn:      conn,
+		send:      make(chan []byte, size),
+		busyAt:    max(1, size*9/10),
+		final:     make(chan []byte, 1),
+		pending:   make(map[string]pendingResponse),
+		done:      make(chan struct{}),
+		asyncAck:  opts.AsyncAck,
+		gzip:      opts.Gzip,
+		readLimit: opts.ReadLimit,
+		token:     opts.Token,
+		chunkSize: opts.ChunkSize,
+
+		pingInterval: opts.PingInterval,
+		pongTimeout:  opts.PongTimeout,
+		responses:    protocol.NewReassembler[*protocol.HTTPResponse](opts.BodyLimit),
 	}
-	return t.ID
 }
 
-// Close signals the tunnel to shut down (safe to call multiple times)
-func (t *Tunnel) Close() {
-	t.closeOnce.Do(func() {
-		close(t.done)
+// Close signals the connection to shut down (safe to call multiple times)
+func (s *session) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
 	})
 }
 
+// closeWith sends a close frame whose reason is code (a protocol.ErrCode),
+// so the client knows whether to reconnect, and closes the connection.
+// WriteControl may run alongside WritePump.
+func (s *session) closeWith(closeCode int, code string) {
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, code), time.Now().Add(writeWait))
+	s.conn.Close()
+}
+
+// closing reports whether the connection is shutting down but may not be
+// unregistered yet
+func (s *session) closing() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// shortIDs lists the session's tunnels for log lines
+func (s *session) shortIDs() string {
+	ids := make([]string, len(s.tunnels))
+	for i, t := range s.tunnels {
+		ids[i] = t.ShortID()
+	}
+	return strings.Join(ids, ",")
+}
+
+// Tunnel is one tunnel ID served over a client session
+type Tunnel struct {
+	*session
+	ID        string // Full UUID for security
+	resumeTok string // Lets a reconnecting client reclaim this ID (offline buffering only)
+
+	ConnectedAt time.Time
+	requests    atomic.Int64 // Webhooks forwarded to this tunnel over this connection
+	bytes       atomic.Int64 // Request + response body bytes relayed
+	lastActive  atomic.Int64 // Unix nanoseconds of the last forwarded webhook (0 = none)
+	busy        atomic.Int64 // Webhooks refused because the send buffer was full
+}
+
+// recordTraffic updates the tunnel's request and byte counters
+func (t *Tunnel) recordTraffic(reqBytes, respBytes int) {
+	t.requests.Add(1)
+	t.bytes.Add(int64(reqBytes + respBytes))
+	t.lastActive.Store(time.Now().UnixNano())
+}
+
+// ShortID returns the first 8 characters for display purposes
+func (t *Tunnel) ShortID() string {
+	return shortID(t.ID)
+}
+
+// tunnelGroup holds the client connections sharing one tunnel ID
+type tunnelGroup struct {
+	conns       []*Tunnel     // In registration order
+	next        atomic.Uint64 // Round-robin cursor
+	resumeToken string        // Shared by the group's connections when offline buffering is on
+	label       string        // Label of the token the tunnel was opened with
+	token       *AuthToken    // Token the tunnel was opened with; joining clients must use it too
+
+	limiter *tokenBucket  // Webhook rate limit; nil when unlimited
+	limited atomic.Uint64 // Webhooks rejected by limiter
+}
+
 // TunnelRegistry manages active tunnels
 type TunnelRegistry struct {
 	mu      sync.RWMutex
-	tunnels map[string]*Tunnel
-	store   *RequestStore
+	tunnels map[string]*tunnelGroup // tunnelID -> connected clients
+	store   RequestStorage
+
+	multiClient bool   // Allow several clients to share a requested tunnel ID
+	balance     string // Strategy for picking a client within a group
+	debug       bool   // Log redacted dumps of malformed frames
+
+	maxDecompressed int64 // Cap on decompressed response bodies
+	maxTunnels      int   // Max tunnels registered at once (0 = unlimited)
+
+	rateLimit float64 // Webhooks per second per tunnel (0 = unlimited)
+	rateBurst int     // Bucket size for rateLimit
+
+	offline *offlineBuffer // nil unless offline buffering is enabled
+
+	// Lifecycle callbacks (optional, invoked outside mu)
+	onOpen  func(tunnelID string)
+	onClose func(tunnelID string)
 }
 
 // NewTunnelRegistry creates a new tunnel registry
-func NewTunnelRegistry(store *RequestStore) *TunnelRegistry {
+func NewTunnelRegistry(store RequestStorage) *TunnelRegistry {
 	return &TunnelRegistry{
-		tunnels: make(map[string]*Tunnel),
+		tunnels: make(map[string]*tunnelGroup),
 		store:   store,
+		balance: BalanceRoundRobin,
 	}
 }
 
-// Register registers a new tunnel (always generates server-side ID for security)
-func (r *TunnelRegistry) Register(conn *websocket.Conn, requestedID string) (*Tunnel, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// ErrTooManyTunnels is returned by Register when the server's tunnel limit
+// is reached
+var ErrTooManyTunnels = errors.New("server tunnel limit reached")
+
+// ErrTunnelForbidden is returned by Register when a client tries to join a
+// connected tunnel with a different token than the one that opened it
+var ErrTunnelForbidden = errors.New("token not authorized for this tunnel")
+
+// tunnelOptions holds per-connection settings requested at registration
+type tunnelOptions struct {
+	AsyncAck   int
+	Gzip       bool
+	ReadLimit  int64      // Max decompressed message size
+	Token      *AuthToken // Token the client registered with
+	ChunkSize  int        // Client reassembles chunked bodies of this size (0 = unsupported)
+	BodyLimit  int64      // Max reassembled response body
+	SendBuffer int        // Messages queued for the client before webhooks are refused (default 256)
+
+	PingInterval time.Duration // WebSocket ping interval
+	PongTimeout  time.Duration // Max wait for a pong
+}
 
-	// Always generate full UUID server-side for security
-	// Client-requested IDs are ignored to prevent ID guessing attacks
+// Register registers a tunnel on a client session. An empty requestedID gets
+// a server-generated UUID; callers decide whether to honor client-requested
+// IDs. In multi-client mode, clients registering the same ID share the tunnel.
+func (r *TunnelRegistry) Register(sess *session, requestedID string) (*Tunnel, error) {
+	// Generate full UUID server-side by default to prevent ID guessing attacks.
+	// Prefix-scoped tokens get their prefix prepended.
 	tunnelID := uuid.New().String()
+	if sess.token != nil {
+		tunnelID = sess.token.TunnelPrefix + tunnelID
+	}
+	if requestedID != "" {
+		if !validTunnelID(requestedID) {
+			return nil, fmt.Errorf("invalid tunnel ID %q", requestedID)
+		}
+		tunnelID = requestedID
+	}
 
 	tunnel := &Tunnel{
-		ID:      tunnelID,
-		conn:    conn,
-		send:    make(chan []byte, 256),
-		pending: make(map[string]chan *protocol.HTTPResponse),
-		done:    make(chan struct{}),
+		session:     sess,
+		ID:          tunnelID,
+		ConnectedAt: time.Now(),
+	}
+
+	r.mu.Lock()
+	group, exists := r.tunnels[tunnelID]
+	if exists && !r.multiClient {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("tunnel ID %q is already connected", tunnelID)
+	}
+	if exists {
+		for _, t := range group.conns {
+			if t.session == sess {
+				r.mu.Unlock()
+				return nil, fmt.Errorf("tunnel ID %q requested twice", tunnelID)
+			}
+		}
+		// Only the token that opened a shared tunnel may join it
+		if sess.token != group.token {
+			r.mu.Unlock()
+			return nil, fmt.Errorf("%w (%s)", ErrTunnelForbidden, tunnelID)
+		}
+	}
+	// Joining a shared tunnel doesn't count against the limit
+	if !exists && r.maxTunnels > 0 && len(r.tunnels) >= r.maxTunnels {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w (max %d)", ErrTooManyTunnels, r.maxTunnels)
+	}
+	if !exists {
+		group = &tunnelGroup{}
+		if r.offline != nil {
+			group.resumeToken = uuid.New().String()
+		}
+		group.token = sess.token
+		if sess.token != nil {
+			group.label = sess.token.Label
+		}
+		if r.rateLimit > 0 {
+			group.limiter = newTokenBucket(r.rateLimit, r.rateBurst)
+		}
+		r.tunnels[tunnelID] = group
+	}
+	tunnel.resumeTok = group.resumeToken
+	group.conns = append(group.conns, tunnel)
+	r.mu.Unlock()
+
+	sess.tunnels = append(sess.tunnels, tunnel)
+
+	if !exists {
+		r.notify(r.onOpen, tunnelID)
 	}
-	r.tunnels[tunnelID] = tunnel
 	return tunnel, nil
 }
 
-// Unregister removes a tunnel from the registry
-func (r *TunnelRegistry) Unregister(tunnelID string) {
+// validTunnelID reports whether a client-requested ID is acceptable
+func validTunnelID(id string) bool {
+	if len(id) < 8 || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Unregister removes a tunnel's client connection from the registry. The
+// tunnel itself closes when its last connection is removed.
+func (r *TunnelRegistry) Unregister(tunnel *Tunnel) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	closed := false
+	if group, ok := r.tunnels[tunnel.ID]; ok {
+		for i, t := range group.conns {
+			if t == tunnel {
+				group.conns = append(group.conns[:i:i], group.conns[i+1:]...)
+				if i == 0 && r.balance == BalanceFailover && len(group.conns) > 0 {
+					log.Printf("tunnel %s: primary conn=%s gone, promoting conn=%s", tunnel.ShortID(), tunnel.ConnID, group.conns[0].ConnID)
+				}
+				break
+			}
+		}
+		if len(group.conns) == 0 {
+			delete(r.tunnels, tunnel.ID)
+			closed = true
+			if r.offline != nil {
+				r.offline.Remember(tunnel.ID, group.resumeToken)
+			}
+		}
+	}
+	r.mu.Unlock()
 
-	if tunnel, ok := r.tunnels[tunnelID]; ok {
-		tunnel.Close() // Signal shutdown via done channel
-		delete(r.tunnels, tunnelID)
-		// Note: send channel is NOT closed here to avoid panics
-		// WritePump will exit when done is closed and drain remaining messages
+	if closed {
+		r.notify(r.onClose, tunnel.ID)
+	}
+}
+
+// Allow applies a tunnel's webhook rate limit. When the limit is exceeded it
+// returns false, the wait until the next slot and the total rejected so far.
+// Tunnels without a connected client are not limited.
+func (r *TunnelRegistry) Allow(tunnelID string) (bool, time.Duration, uint64) {
+	r.mu.RLock()
+	group, ok := r.tunnels[tunnelID]
+	r.mu.RUnlock()
+	if !ok || group.limiter == nil || group.limiter.Allow() {
+		return true, 0, 0
 	}
+	return false, group.limiter.RetryAfter(), group.limited.Add(1)
 }
 
-// Get retrieves a tunnel by ID
+// Label returns the token label a connected tunnel was opened with
+func (r *TunnelRegistry) Label(tunnelID string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if group, ok := r.tunnels[tunnelID]; ok {
+		return group.label
+	}
+	return ""
+}
+
+// notify invokes a lifecycle callback, recovering from panics
+func (r *TunnelRegistry) notify(fn func(tunnelID string), tunnelID string) {
+	if fn == nil {
+		return
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("tunnel %s: lifecycle callback panicked: %v", tunnelID, p)
+		}
+	}()
+	fn(tunnelID)
+}
+
+// Get retrieves a tunnel by ID, picking one of its connections according to
+// the balancing strategy when several clients share it
 func (r *TunnelRegistry) Get(tunnelID string) (*Tunnel, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	t, ok := r.tunnels[tunnelID]
-	return t, ok
+
+	group, ok := r.tunnels[tunnelID]
+	if !ok || len(group.conns) == 0 {
+		return nil, false
+	}
+	conns := liveConns(group.conns)
+	if len(conns) == 1 {
+		return conns[0], true
+	}
+
+	switch r.balance {
+	case BalanceFailover:
+		// The primary takes everything while its connection is up
+		return conns[0], true
+	case BalanceLeastInFlight:
+		best := conns[0]
+		for _, t := range conns[1:] {
+			if t.inFlight.Load() < best.inFlight.Load() {
+				best = t
+			}
+		}
+		return best, true
+	default:
+		n := group.next.Add(1) - 1
+		return conns[n%uint64(len(conns))], true
+	}
+}
+
+// liveConns leaves out connections that are closing but not unregistered
+// yet, unless that would leave none
+func liveConns(conns []*Tunnel) []*Tunnel {
+	closing := 0
+	for _, t := range conns {
+		if t.session.closing() {
+			closing++
+		}
+	}
+	if closing == 0 || closing == len(conns) {
+		return conns
+	}
+	live := make([]*Tunnel, 0, len(conns)-closing)
+	for _, t := range conns {
+		if !t.session.closing() {
+			live = append(live, t)
+		}
+	}
+	return live
+}
+
+// TunnelInfo summarizes an active tunnel for the admin API
+type TunnelInfo struct {
+	ID           string    `json:"id"`
+	ShortID      string    `json:"short_id"`
+	PublicURL    string    `json:"public_url"`
+	ConnectedAt  time.Time `json:"connected_at"`
+	Clients      int       `json:"clients"`
+	RequestCount int64     `json:"request_count"`
+	Bytes        int64     `json:"bytes"`
+	ParseErrors  int64     `json:"parse_errors"`
+	Label        string    `json:"label,omitempty"` // Label of the token the tunnel was opened with
+
+	SendQueue     int   `json:"send_queue"`      // Messages waiting to be written to its clients
+	SendQueueSize int   `json:"send_queue_size"` // Capacity of its clients' send buffers
+	Busy          int64 `json:"busy"`            // Webhooks refused with 503 because a send buffer was full
+
+	LastActivity *time.Time `json:"last_activity,omitempty"` // Last webhook forwarded by any of its clients
+}
+
+// List returns info for all active tunnels, oldest first. Counters are
+// summed across a tunnel's clients; ConnectedAt is the earliest connection.
+func (r *TunnelRegistry) List() []TunnelInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]TunnelInfo, 0, len(r.tunnels))
+	for id, group := range r.tunnels {
+		if len(group.conns) == 0 {
+			continue
+		}
+		info := TunnelInfo{
+			ID:          id,
+			ShortID:     group.conns[0].ShortID(),
+			ConnectedAt: group.conns[0].ConnectedAt,
+			Clients:     len(group.conns),
+			Label:       group.label,
+		}
+		var lastActive int64
+		for _, t := range group.conns {
+			info.RequestCount += t.requests.Load()
+			info.Bytes += t.bytes.Load()
+			info.ParseErrors += t.parseErrors.Load()
+			info.SendQueue += len(t.send)
+			info.SendQueueSize += cap(t.send)
+			info.Busy += t.busy.Load()
+			lastActive = max(lastActive, t.lastActive.Load())
+		}
+		if lastActive > 0 {
+			at := time.Unix(0, lastActive)
+			info.LastActivity = &at
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ConnectedAt.Before(result[j].ConnectedAt)
+	})
+	return result
+}
+
+// InFlight returns the number of webhooks awaiting a client response across
+// all tunnels
+func (r *TunnelRegistry) InFlight() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// A session carrying several tunnels is counted once
+	seen := make(map[*session]bool)
+	var n int64
+	for _, group := range r.tunnels {
+		for _, t := range group.conns {
+			if !seen[t.session] {
+				seen[t.session] = true
+				n += t.inFlight.Load()
+			}
+		}
+	}
+	return n
+}
+
+// Disconnect closes every client connection of a tunnel, giving code (a
+// protocol.ErrCode) as the reason. Each connection's ReadPump then
+// unregisters it.
+func (r *TunnelRegistry) Disconnect(tunnelID, code string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if group, ok := r.tunnels[tunnelID]; ok {
+		for _, t := range group.conns {
+			t.session.closeWith(websocket.ClosePolicyViolation, code)
+		}
+	}
 }
 
 // CloseAll gracefully closes all active tunnels
 func (r *TunnelRegistry) CloseAll() {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	for id, tunnel := range r.tunnels {
-		log.Printf("closing tunnel: %s", tunnel.ShortID())
-		tunnel.Close()
-		tunnel.conn.Close()
+	closed := make([]string, 0, len(r.tunnels))
+	for id, group := range r.tunnels {
+		for _, tunnel := range group.conns {
+			log.Printf("closing tunnel: %s (conn=%s)", tunnel.ShortID(), tunnel.ConnID)
+			tunnel.Close()
+			tunnel.session.closeWith(websocket.CloseGoingAway, protocol.ErrCodeShutdown)
+		}
 		delete(r.tunnels, id)
+		closed = append(closed, id)
 	}
+	r.mu.Unlock()
+
+	for _, id := range closed {
+		r.notify(r.onClose, id)
+	}
+}
+
+// errNotSent is returned by ForwardRequest when the connection closed before
+// taking the request, so the client never saw it
+var errNotSent = errors.New("tunnel closed")
+
+// errSendBufferFull is returned by ForwardRequest when the connection's send
+// buffer is nearly full, so the client isn't keeping up. The request isn't
+// sent.
+var errSendBufferFull = errors.New("tunnel send buffer full")
+
+// saturated reports whether the send buffer has reached its high-water mark
+func (s *session) saturated() bool {
+	return len(s.send) >= s.busyAt
 }
 
-// ForwardRequest sends a request through the tunnel and waits for response
-func (t *Tunnel) ForwardRequest(ctx context.Context, req *protocol.HTTPRequest) (*protocol.HTTPResponse, error) {
+// ForwardRequest sends a request through the connection and waits for response
+func (s *session) ForwardRequest(ctx context.Context, req *protocol.HTTPRequest) (*protocol.HTTPResponse, error) {
+	// Refuse now rather than queue behind messages the client isn't reading
+	if s.saturated() {
+		return nil, errSendBufferFull
+	}
+
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
 	respChan := make(chan *protocol.HTTPResponse, 1)
 
-	t.pendingMu.Lock()
-	t.pending[req.ID] = respChan
-	t.pendingMu.Unlock()
+	s.pendingMu.Lock()
+	s.pending[req.ID] = pendingResponse{ch: respChan, sent: time.Now()}
+	s.pendingMu.Unlock()
 
 	defer func() {
-		t.pendingMu.Lock()
-		delete(t.pending, req.ID)
-		t.pendingMu.Unlock()
+		s.pendingMu.Lock()
+		delete(s.pending, req.ID)
+		s.pendingMu.Unlock()
 	}()
 
-	msg, err := protocol.NewMessage(protocol.TypeRequest, req)
+	// Compress large bodies on the wire; the stored request keeps the original
+	wire := req
+	if s.gzip {
+		if body, enc := protocol.CompressBody(req.Body); enc != "" {
+			c := *req
+			c.Body, c.BodyEncoding = body, enc
+			wire = &c
+		}
+	}
+
+	// Large bodies follow the request in chunks
+	var chunks []protocol.BodyChunk
+	if s.chunkSize > 0 && len(wire.Body) > s.chunkSize {
+		c := *wire
+		chunks = protocol.SplitBody(req.ID, c.Body, s.chunkSize)
+		c.Body, c.Chunked = nil, true
+		wire = &c
+	}
+
+	msg, err := protocol.NewMessage(protocol.TypeRequest, wire)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create message: %w", err)
 	}
@@ -140,11 +596,26 @@ func (t *Tunnel) ForwardRequest(ctx context.Context, req *protocol.HTTPRequest)
 	}
 
 	select {
-	case t.send <- data:
+	case s.send <- data:
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case <-t.done:
-		return nil, fmt.Errorf("tunnel closed")
+	case <-s.done:
+		return nil, errNotSent
+	}
+
+	// Once started, a chunk sequence is always finished so the client isn't
+	// left holding a partial body
+	for i := range chunks {
+		chunkMsg, _ := protocol.NewMessage(protocol.TypeChunk, &chunks[i])
+		data, err := json.Marshal(chunkMsg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal chunk: %w", err)
+		}
+		select {
+		case s.send <- data:
+		case <-s.done:
+			return nil, fmt.Errorf("tunnel closed")
+		}
 	}
 
 	select {
@@ -152,80 +623,137 @@ func (t *Tunnel) ForwardRequest(ctx context.Context, req *protocol.HTTPRequest)
 		return resp, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
-	case <-t.done:
+	case <-s.done:
 		return nil, fmt.Errorf("tunnel closed")
 	}
 }
 
-// HandleResponse processes an incoming response from the client
-func (t *Tunnel) HandleResponse(resp *protocol.HTTPResponse) {
-	t.pendingMu.Lock()
-	ch, ok := t.pending[resp.RequestID]
-	t.pendingMu.Unlock()
+// pendingResponse is a forwarded request waiting for its response
+type pendingResponse struct {
+	ch   chan *protocol.HTTPResponse
+	sent time.Time
+}
 
-	if ok {
-		select {
-		case ch <- resp:
-		default:
-		}
+// HandleResponse processes an incoming response from the client. It returns
+// how long the response took, or 0 if nothing was waiting for it (e.g., it
+// arrived after the forward timed out).
+func (s *session) HandleResponse(resp *protocol.HTTPResponse) time.Duration {
+	s.pendingMu.Lock()
+	p, ok := s.pending[resp.RequestID]
+	s.pendingMu.Unlock()
+
+	if !ok {
+		return 0
 	}
+	select {
+	case p.ch <- resp:
+	default:
+	}
+	return time.Since(p.sent)
+}
+
+// errMessageTooLarge is returned by readMessage for oversized messages
+var errMessageTooLarge = errors.New("message exceeds size limit")
+
+// readMessage reads the next data message, capping its size after
+// decompression. The connection's own read limit only covers the compressed
+// frames, so a small permessage-deflate frame could otherwise inflate
+// without bound.
+func readMessage(conn *websocket.Conn, limit int64) ([]byte, error) {
+	_, r, err := conn.NextReader()
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	message, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(message)) > limit {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseMessageTooBig, ""), time.Now().Add(writeWait))
+		return nil, errMessageTooLarge
+	}
+	return message, nil
 }
 
 // WritePump pumps messages from the send channel to the WebSocket connection
-func (t *Tunnel) WritePump() {
-	ticker := time.NewTicker(pingPeriod)
+func (s *session) WritePump() {
+	ticker := time.NewTicker(s.pingInterval)
 	defer func() {
 		ticker.Stop()
-		t.conn.Close()
+		s.conn.Close()
 	}()
 
 	for {
 		select {
-		case message, ok := <-t.send:
-			t.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		case message, ok := <-s.send:
+			s.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
-				t.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				s.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			if err := t.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			// Small frames aren't worth deflating
+			s.conn.EnableWriteCompression(len(message) >= protocol.CompressThreshold)
+			if err := s.conn.WriteMessage(websocket.TextMessage, message); err != nil {
 				return
 			}
 		case <-ticker.C:
-			t.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := t.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := s.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
-		case <-t.done:
+		case data := <-s.final:
+			s.writeFinal(data)
+			return
+		case <-s.done:
 			return
 		}
 	}
 }
 
-// ReadPump pumps messages from the WebSocket connection
-func (t *Tunnel) ReadPump(registry *TunnelRegistry) {
+// writeFinal sends a last message and a close frame before the connection
+// is closed
+func (s *session) writeFinal(data []byte) {
+	s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	s.conn.WriteMessage(websocket.TextMessage, data)
+	s.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeWait))
+}
+
+// ReadPump pumps messages from the WebSocket connection. When the
+// connection ends, all of its tunnels are unregistered.
+func (s *session) ReadPump(registry *TunnelRegistry) {
 	defer func() {
-		registry.Unregister(t.ID)
-		t.conn.Close()
+		s.Close() // Signal shutdown via done channel
+		// Note: send channel is NOT closed here to avoid panics
+		// WritePump will exit when done is closed and drain remaining messages
+		for _, tunnel := range s.tunnels {
+			registry.Unregister(tunnel)
+		}
+		s.conn.Close()
 	}()
 
-	t.conn.SetReadDeadline(time.Now().Add(pongWait))
-	t.conn.SetPongHandler(func(string) error {
-		t.conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.conn.SetReadDeadline(time.Now().Add(s.pongTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		s.conn.SetReadDeadline(time.Now().Add(s.pongTimeout))
 		return nil
 	})
 
 	for {
-		_, message, err := t.conn.ReadMessage()
+		message, err := readMessage(s.conn, s.readLimit)
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("tunnel %s read error: %v", t.ShortID(), err)
+			if errors.Is(err, errMessageTooLarge) || websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("tunnel %s (conn=%s) read error: %v", s.shortIDs(), s.ConnID, err)
 			}
 			return
 		}
 
 		var msg protocol.Message
 		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("tunnel %s: failed to parse message: %v", t.ShortID(), err)
+			s.logParseError(registry, "message", message, err)
 			continue
 		}
 
@@ -233,15 +761,112 @@ func (t *Tunnel) ReadPump(registry *TunnelRegistry) {
 		case protocol.TypeResponse:
 			var resp protocol.HTTPResponse
 			if err := msg.ParsePayload(&resp); err != nil {
-				log.Printf("tunnel %s: failed to parse response: %v", t.ShortID(), err)
+				s.logParseError(registry, "response", message, err)
 				continue
 			}
-			t.HandleResponse(&resp)
-			registry.store.StoreResponse(&resp)
+			if resp.Chunked {
+				if err := s.responses.Expect(resp.RequestID, &resp); err != nil {
+					s.failResponse(registry, &resp, err)
+				}
+				continue
+			}
+			s.deliverResponse(registry, &resp)
+		case protocol.TypeChunk:
+			var chunk protocol.BodyChunk
+			if err := msg.ParsePayload(&chunk); err != nil {
+				s.logParseError(registry, "chunk", message, err)
+				continue
+			}
+			resp, body, done, err := s.responses.Add(&chunk)
+			switch {
+			case err != nil && resp == nil:
+				// The request already finished (e.g., timed out)
+				log.Printf("[%s] tunnel %s (conn=%s): dropped chunk: %v", chunk.ID, s.shortIDs(), s.ConnID, err)
+			case err != nil:
+				s.failResponse(registry, resp, err)
+			case done:
+				resp.Body, resp.Chunked = body, false
+				s.deliverResponse(registry, resp)
+			}
+		case protocol.TypePing:
+			// Client heartbeat - answer so it can detect dead connections
+			pongMsg, _ := protocol.NewMessage(protocol.TypePong, nil)
+			data, _ := json.Marshal(pongMsg)
+			select {
+			case s.send <- data:
+			case <-s.done:
+				return
+			}
 		case protocol.TypePong:
 			// Client responded to ping, connection is alive
 		default:
-			log.Printf("tunnel %s: unknown message type: %s", t.ShortID(), msg.Type)
+			log.Printf("tunnel %s (conn=%s): unknown message type: %s", s.shortIDs(), s.ConnID, msg.Type)
+		}
+	}
+}
+
+// deliverResponse completes a response to a forwarded request. It is only
+// recorded in history for requests to one of this session's tunnels, so a
+// client can't overwrite or pile up responses for IDs it doesn't own.
+func (s *session) deliverResponse(registry *TunnelRegistry, resp *protocol.HTTPResponse) {
+	if resp.BodyEncoding != "" {
+		s.decompressResponse(registry, resp)
+	}
+	duration := s.HandleResponse(resp)
+	if req, ok := registry.store.Get(resp.RequestID); ok && s.owns(req.TunnelID) {
+		registry.store.StoreResponse(resp, duration)
+	}
+}
+
+// owns reports whether tunnelID is one of the session's tunnels
+func (s *session) owns(tunnelID string) bool {
+	for _, t := range s.tunnels {
+		if t.ID == tunnelID {
+			return true
 		}
 	}
+	return false
+}
+
+// failResponse answers a request whose chunked response body couldn't be
+// reassembled with a 502
+func (s *session) failResponse(registry *TunnelRegistry, resp *protocol.HTTPResponse, err error) {
+	log.Printf("[%s] tunnel %s (conn=%s): bad chunked response: %v", resp.RequestID, s.shortIDs(), s.ConnID, err)
+	resp.StatusCode = http.StatusBadGateway
+	resp.Headers = protocol.Headers{"Content-Type": {"text/plain"}}
+	resp.Body = []byte(fmt.Sprintf("invalid response body from client: %v", err))
+	resp.BodyEncoding, resp.Chunked = "", false
+	s.deliverResponse(registry, resp)
+}
+
+// decompressResponse restores a compressed response body in place. Bodies
+// past the size limit are cut short and marked with TruncatedHeader; bodies
+// that cannot be decoded become a 502.
+func (s *session) decompressResponse(registry *TunnelRegistry, resp *protocol.HTTPResponse) {
+	body, err := protocol.DecompressBody(resp.Body, resp.BodyEncoding, registry.maxDecompressed)
+	resp.BodyEncoding = ""
+	if errors.Is(err, protocol.ErrDecompressedTooLarge) {
+		log.Printf("[%s] tunnel %s (conn=%s): response body exceeds %d bytes decompressed, truncated", resp.RequestID, s.shortIDs(), s.ConnID, len(body))
+		resp.Headers = protocol.MarkTruncated(resp.Headers, int64(len(body)))
+		resp.Body = body
+		return
+	}
+	if err != nil {
+		log.Printf("[%s] tunnel %s (conn=%s): bad response body: %v", resp.RequestID, s.shortIDs(), s.ConnID, err)
+		resp.StatusCode = http.StatusBadGateway
+		resp.Headers = protocol.Headers{"Content-Type": {"text/plain"}}
+		resp.Body = []byte(fmt.Sprintf("invalid response body from client: %v", err))
+		return
+	}
+	resp.Body = body
+}
+
+// logParseError counts and logs a malformed frame from the client
+func (s *session) logParseError(registry *TunnelRegistry, what string, frame []byte, err error) {
+	n := s.parseErrors.Add(1)
+	log.Printf("tunnel %s (conn=%s): failed to parse %s (%d bytes, %d total errors): %v",
+		s.shortIDs(), s.ConnID, what, len(frame), n, err)
+	if registry.debug {
+		log.Printf("[debug] tunnel %s (conn=%s): frame: %s", s.shortIDs(), s.ConnID, protocol.DumpFrame(frame, 256))
+	}
 }
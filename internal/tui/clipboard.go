@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type copyResultMsg struct {
+	success bool
+	message string
+}
+
+// copyToClipboard copies text to the system clipboard. Over SSH, or when no
+// clipboard tool is available, it falls back to an OSC52 escape sequence so
+// the local terminal sets its clipboard instead.
+func copyToClipboard(text, what string) tea.Cmd {
+	return func() tea.Msg {
+		size := fmt.Sprintf("%d bytes", len(text))
+		if !isSSH() && !clipboard.Unsupported {
+			if err := clipboard.WriteAll(text); err == nil {
+				return copyResultMsg{success: true, message: fmt.Sprintf("Copied %s (%s)", what, size)}
+			}
+		}
+
+		seq := osc52.New(text)
+		if os.Getenv("TMUX") != "" {
+			seq = seq.Tmux()
+		} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+			seq = seq.Screen()
+		}
+		// Bubble Tea renders to stdout; stderr reaches the same terminal
+		if _, err := seq.WriteTo(os.Stderr); err != nil {
+			return copyResultMsg{success: false, message: "Copy failed: " + err.Error()}
+		}
+		return copyResultMsg{success: true, message: fmt.Sprintf("Copied %s via terminal (%s)", what, size)}
+	}
+}
+
+func isSSH() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+// plainDetail renders a request/response pair as unstyled text for copying
+func plainDetail(req RequestItem) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s\n", req.Method, req.Path)
+	writePlainHeaders(&b, req.ReqHeaders)
+	if len(req.ReqBody) > 0 {
+		b.WriteString("\n")
+		b.Write(req.ReqBody)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	switch {
+	case req.Error != "":
+		fmt.Fprintf(&b, "Error: %s\n", req.Error)
+	case req.StatusCode > 0:
+		fmt.Fprintf(&b, "Response: %d (%s, %s)\n", req.StatusCode, formatDuration(req.Duration), formatBytes(len(req.ResBody)))
+		writePlainHeaders(&b, req.ResHeaders)
+		if len(req.ResBody) > 0 {
+			b.WriteString("\n")
+			b.Write(req.ResBody)
+			b.WriteString("\n")
+		}
+	default:
+		b.WriteString("Pending\n")
+	}
+
+	return b.String()
+}
+
+func writePlainHeaders(b *strings.Builder, headers http.Header) {
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, v := range headers[k] {
+			fmt.Fprintf(b, "%s: %s\n", k, v)
+		}
+	}
+}
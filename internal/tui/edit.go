@@ -0,0 +1,74 @@
+package tui
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type editDoneMsg struct {
+	requestID string
+	original  []byte
+	file      string
+	err       error
+}
+
+// editBody opens a request's body in $VISUAL or $EDITOR (default vi). The
+// TUI is suspended while the editor runs.
+func editBody(req RequestItem) tea.Cmd {
+	f, err := os.CreateTemp("", "hookshot-"+req.ID+"-*"+bodyExt(req.ReqHeaders.Get("Content-Type")))
+	if err != nil {
+		return func() tea.Msg { return editDoneMsg{requestID: req.ID, err: err} }
+	}
+	_, err = f.Write(req.ReqBody)
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return func() tea.Msg { return editDoneMsg{requestID: req.ID, err: err} }
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Allow editors with arguments, e.g., "code --wait"
+	args := append(strings.Fields(editor), f.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editDoneMsg{requestID: req.ID, original: req.ReqBody, file: f.Name(), err: err}
+	})
+}
+
+// readEdit returns the edited body and whether it changed, removing the file
+func (msg editDoneMsg) readEdit() ([]byte, bool, error) {
+	if msg.file == "" {
+		return nil, false, msg.err
+	}
+	defer os.Remove(msg.file)
+	if msg.err != nil {
+		return nil, false, msg.err
+	}
+	body, err := os.ReadFile(msg.file)
+	if err != nil {
+		return nil, false, err
+	}
+	return body, !bytes.Equal(body, msg.original), nil
+}
+
+// bodyExt picks a file extension so editors highlight the body
+func bodyExt(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return ".json"
+	case strings.Contains(contentType, "xml"):
+		return ".xml"
+	default:
+		return ".txt"
+	}
+}
@@ -0,0 +1,162 @@
+package tui
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// requestFilter is a parsed filter input. Whitespace-separated terms are
+// ANDed together:
+//
+//	method:post         method (comma-separated for any of several)
+//	status:404          exact status
+//	status:4xx          status class
+//	status:>=500        comparison (>, >=, <, <=, !=)
+//	status:err          forwarding failed
+//	hash:abc            body hash prefix
+//	re:^/hooks/(a|b)    path matches a regular expression (case-sensitive)
+//	!re:^/health        path doesn't match
+//	anything else       substring of path, method or ID
+type requestFilter []func(RequestItem) bool
+
+// parseFilter turns filter input into predicates. Qualifiers with an empty
+// value (e.g., "status:" while still typing) are ignored. A regular
+// expression that doesn't compile matches as a plain substring of the path
+// instead, and is reported in the returned error.
+func parseFilter(input string) (requestFilter, error) {
+	var f requestFilter
+	var badRegexp error
+	for _, raw := range strings.Fields(input) {
+		term := strings.ToLower(raw)
+		qualifier, value, ok := strings.Cut(term, ":")
+		if !ok {
+			f = append(f, matchText(term))
+			continue
+		}
+		switch qualifier {
+		case "re", "!re":
+			// Patterns keep their case; the rest of the input is folded
+			_, pattern, _ := strings.Cut(raw, ":")
+			if pattern == "" {
+				continue
+			}
+			pred, err := matchPathRegexp(pattern)
+			if err != nil && badRegexp == nil {
+				badRegexp = errors.New("bad regexp, matching as text")
+			}
+			if qualifier == "!re" {
+				f = append(f, func(req RequestItem) bool { return !pred(req) })
+			} else {
+				f = append(f, pred)
+			}
+		case "method":
+			if value != "" {
+				f = append(f, matchMethod(value))
+			}
+		case "status":
+			if value != "" {
+				f = append(f, matchStatus(value))
+			}
+		case "hash":
+			if value != "" {
+				f = append(f, func(req RequestItem) bool {
+					return req.BodyHash != "" && strings.HasPrefix(req.BodyHash, value)
+				})
+			}
+		default:
+			// Not a qualifier (e.g., a path like /webhooks:v2)
+			f = append(f, matchText(term))
+		}
+	}
+	return f, badRegexp
+}
+
+// match reports whether req satisfies every term
+func (f requestFilter) match(req RequestItem) bool {
+	for _, pred := range f {
+		if !pred(req) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchText(text string) func(RequestItem) bool {
+	return func(req RequestItem) bool {
+		return strings.Contains(strings.ToLower(req.Path), text) ||
+			strings.Contains(strings.ToLower(req.Method), text) ||
+			strings.Contains(req.ID, text)
+	}
+}
+
+// matchPathRegexp matches paths against a pattern, or contain it as text
+// when it doesn't compile (e.g., while a group is still being typed)
+func matchPathRegexp(pattern string) (func(RequestItem) bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return func(req RequestItem) bool { return strings.Contains(req.Path, pattern) }, err
+	}
+	return func(req RequestItem) bool { return re.MatchString(req.Path) }, nil
+}
+
+func matchMethod(value string) func(RequestItem) bool {
+	methods := strings.Split(strings.ToUpper(value), ",")
+	return func(req RequestItem) bool {
+		for _, m := range methods {
+			if req.Method == m {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// matchStatus parses a status term. Terms that don't parse match nothing,
+// so a typo shows an empty list rather than silently matching everything.
+func matchStatus(value string) func(RequestItem) bool {
+	if value == "err" {
+		return func(req RequestItem) bool { return req.Error != "" }
+	}
+
+	// Class: 4xx
+	if len(value) == 3 && value[1:] == "xx" && value[0] >= '1' && value[0] <= '5' {
+		class := int(value[0] - '0')
+		return func(req RequestItem) bool { return req.StatusCode/100 == class }
+	}
+
+	op := ""
+	for _, candidate := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(value, candidate) {
+			op = candidate
+			value = value[len(candidate):]
+			break
+		}
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return func(RequestItem) bool { return false }
+	}
+
+	return func(req RequestItem) bool {
+		// Pending and failed requests have no status to compare
+		if req.StatusCode == 0 {
+			return false
+		}
+		switch op {
+		case ">=":
+			return req.StatusCode >= n
+		case "<=":
+			return req.StatusCode <= n
+		case "!=":
+			return req.StatusCode != n
+		case ">":
+			return req.StatusCode > n
+		case "<":
+			return req.StatusCode < n
+		default:
+			return req.StatusCode == n
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// historyQueueSize caps requests waiting to be written; further requests
+// are dropped so a slow disk never stalls the TUI
+const historyQueueSize = 100
+
+// maxHistoryLine bounds one entry when loading; bodies are base64, so a
+// 10MB body takes about 14MB
+const maxHistoryLine = 64 * 1024 * 1024
+
+// History keeps the TUI's requests in a JSONL file, one RequestItem per
+// line with bodies base64, so they can be reviewed after a restart
+type History struct {
+	file  *os.File
+	queue chan RequestItem
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	dropped atomic.Uint64
+}
+
+// OpenHistory opens path for appending, creating it if needed
+func OpenHistory(path string) (*History, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	h := &History{
+		file:  f,
+		queue: make(chan RequestItem, historyQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go h.run()
+	return h, nil
+}
+
+// Load returns the last n requests in the file, newest first. Lines that
+// don't parse, such as one cut short by a crash, are skipped.
+func (h *History) Load(n int) ([]RequestItem, error) {
+	if _, err := h.file.Seek(0, 0); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	scanner := bufio.NewScanner(h.file)
+	scanner.Buffer(make([]byte, 64*1024), maxHistoryLine)
+
+	var items []RequestItem
+	for scanner.Scan() {
+		var item RequestItem
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil || item.ID == "" {
+			continue
+		}
+		items = append(items, item)
+		if len(items) > n {
+			items = items[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return items, nil
+}
+
+// Append queues a request to be written, dropping it if the queue is full
+func (h *History) Append(item RequestItem) {
+	select {
+	case h.queue <- item:
+	default:
+		if n := h.dropped.Add(1); n == 1 || n%100 == 0 {
+			log.Printf("[%s] history queue full, dropped entry (%d dropped total)", item.ID, n)
+		}
+	}
+}
+
+// run writes queued requests until Close, then writes what is still queued
+func (h *History) run() {
+	defer close(h.done)
+	for {
+		select {
+		case item := <-h.queue:
+			h.write(item)
+		case <-h.stop:
+			for {
+				select {
+				case item := <-h.queue:
+					h.write(item)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write appends one request as a single line
+func (h *History) write(item RequestItem) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return
+	}
+	h.file.Write(append(data, '\n'))
+}
+
+// Close writes the queued requests and closes the file
+func (h *History) Close() error {
+	h.stopOnce.Do(func() { close(h.stop) })
+	<-h.done
+	return h.file.Close()
+}
@@ -6,8 +6,19 @@ import "github.com/charmbracelet/bubbles/key"
 type KeyMap struct {
 	Up      key.Binding
 	Down    key.Binding
+	PageUp  key.Binding
+	PageDn  key.Binding
+	LineUp  key.Binding
+	LineDn  key.Binding
 	Replay  key.Binding
+	Edit    key.Binding
+	Copy    key.Binding
+	CopyAll key.Binding
+	SaveHAR key.Binding
+	SaveRaw key.Binding
 	Filter  key.Binding
+	Stats   key.Binding
+	Pause   key.Binding
 	Clear   key.Binding
 	Quit    key.Binding
 	Help    key.Binding
@@ -25,14 +36,58 @@ var DefaultKeyMap = KeyMap{
 		key.WithKeys("down", "j"),
 		key.WithHelp("↓/j", "down"),
 	),
+	PageUp: key.NewBinding(
+		key.WithKeys("pgup", "ctrl+u"),
+		key.WithHelp("pgup/ctrl+u", "scroll detail up"),
+	),
+	PageDn: key.NewBinding(
+		key.WithKeys("pgdown", "ctrl+d"),
+		key.WithHelp("pgdn/ctrl+d", "scroll detail down"),
+	),
+	LineUp: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "detail line up"),
+	),
+	LineDn: key.NewBinding(
+		key.WithKeys("J"),
+		key.WithHelp("J", "detail line down"),
+	),
 	Replay: key.NewBinding(
 		key.WithKeys("r"),
 		key.WithHelp("r", "replay"),
 	),
+	Edit: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "edit body and replay"),
+	),
+	Copy: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "copy body"),
+	),
+	CopyAll: key.NewBinding(
+		key.WithKeys("Y"),
+		key.WithHelp("Y", "copy detail"),
+	),
+	SaveHAR: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "save as HAR"),
+	),
+	SaveRaw: key.NewBinding(
+		key.WithKeys("W"),
+		key.WithHelp("W", "save as .http"),
+	),
 	Filter: key.NewBinding(
 		key.WithKeys("/"),
 		key.WithHelp("/", "filter"),
 	),
+	Stats: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "stats"),
+	),
+	Pause: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "pause/resume list"),
+	),
 	Clear: key.NewBinding(
 		key.WithKeys("esc"),
 		key.WithHelp("esc", "clear"),
@@ -64,7 +119,9 @@ func (k KeyMap) ShortHelp() []key.Binding {
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.Enter},
-		{k.Replay, k.Filter, k.Clear},
+		{k.PageUp, k.PageDn, k.LineUp, k.LineDn},
+		{k.Replay, k.Edit, k.Copy, k.CopyAll, k.SaveHAR, k.SaveRaw},
+		{k.Filter, k.Clear, k.Stats, k.Pause},
 		{k.Quit, k.Help},
 	}
 }
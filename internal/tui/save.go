@@ -0,0 +1,138 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/lance0/hookshot/internal/protocol"
+)
+
+// Save formats
+const (
+	saveHAR  = ".har"
+	saveHTTP = ".http"
+)
+
+type saveResultMsg struct {
+	success bool
+	message string
+}
+
+// saveRequest writes a request and its response to a timestamped file in
+// the working directory, as a one-entry HAR archive or a .http file
+func saveRequest(req RequestItem, format string) tea.Cmd {
+	return func() tea.Msg {
+		var data []byte
+		switch format {
+		case saveHAR:
+			har := protocol.NewHAR("")
+			har.Log.Entries = append(har.Log.Entries, harEntry(req))
+			var err error
+			if data, err = json.MarshalIndent(har, "", "  "); err != nil {
+				return saveResultMsg{success: false, message: "Save failed: " + err.Error()}
+			}
+		default:
+			data = []byte(httpFile(req))
+		}
+
+		name := fmt.Sprintf("hookshot-%s-%s%s", req.ID, time.Now().Format("20060102-150405"), format)
+		// Bodies may carry secrets; keep the file private
+		if err := os.WriteFile(name, data, 0o600); err != nil {
+			return saveResultMsg{success: false, message: "Save failed: " + err.Error()}
+		}
+		return saveResultMsg{success: true, message: fmt.Sprintf("Saved %s to ./%s", req.ID, name)}
+	}
+}
+
+// targetBase is the target the request was forwarded to, as a base URL
+func targetBase(req RequestItem) string {
+	if !strings.HasPrefix(req.Target, "http://") && !strings.HasPrefix(req.Target, "https://") {
+		return "http://localhost" // Mocked, or the target is unknown
+	}
+	return strings.TrimSuffix(req.Target, "/")
+}
+
+// harEntry converts a request to a HAR entry via the relay's HAR export
+func harEntry(req RequestItem) protocol.HAREntry {
+	httpReq := &protocol.HTTPRequest{
+		ID:        req.ID,
+		Method:    req.Method,
+		Path:      req.Path,
+		Headers:   protocol.HeadersFromHTTP(req.ReqHeaders),
+		Body:      req.ReqBody,
+		Timestamp: req.Timestamp,
+	}
+	var httpResp *protocol.HTTPResponse
+	if req.StatusCode > 0 {
+		httpResp = &protocol.HTTPResponse{
+			RequestID:  req.ID,
+			StatusCode: req.StatusCode,
+			StatusText: req.StatusText,
+			Headers:    protocol.HeadersFromHTTP(req.ResHeaders),
+			Body:       req.ResBody,
+		}
+	}
+
+	entry := protocol.NewHAREntry(targetBase(req), httpReq, httpResp)
+	ms := float64(req.Duration) / float64(time.Millisecond)
+	entry.Time = ms
+	entry.Timings.Wait = ms
+	if req.Error != "" {
+		entry.Comment += ": " + req.Error
+	}
+	return entry
+}
+
+// httpFile renders a request in the .http format of editor REST clients, so
+// it can be sent again as is. The response follows as comments.
+func httpFile(req RequestItem) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "### hookshot request %s, %s\n", req.ID, req.Timestamp.Format(time.RFC3339))
+	fmt.Fprintf(&b, "%s %s%s\n", req.Method, targetBase(req), req.Path)
+	writePlainHeaders(&b, req.ReqHeaders)
+	if len(req.ReqBody) > 0 {
+		b.WriteString("\n")
+		if utf8.Valid(req.ReqBody) {
+			b.Write(req.ReqBody)
+			b.WriteString("\n")
+		} else {
+			fmt.Fprintf(&b, "# <binary body, %d bytes, not included>\n", len(req.ReqBody))
+		}
+	}
+
+	b.WriteString("\n")
+	switch {
+	case req.Error != "":
+		fmt.Fprintf(&b, "# Error: %s\n", req.Error)
+	case req.StatusCode > 0:
+		fmt.Fprintf(&b, "# Response: %s (%s)\n", protocol.StatusLine(req.StatusCode, req.StatusText), formatDuration(req.Duration))
+		var headers strings.Builder
+		writePlainHeaders(&headers, req.ResHeaders)
+		commentLines(&b, headers.String())
+		if len(req.ResBody) > 0 {
+			b.WriteString("#\n")
+			if utf8.Valid(req.ResBody) {
+				commentLines(&b, string(req.ResBody))
+			} else {
+				fmt.Fprintf(&b, "# <binary body, %d bytes>\n", len(req.ResBody))
+			}
+		}
+	default:
+		b.WriteString("# Response: pending\n")
+	}
+
+	return b.String()
+}
+
+// commentLines writes text with each line prefixed by "# "
+func commentLines(b *strings.Builder, text string) {
+	for line := range strings.SplitSeq(strings.TrimSuffix(text, "\n"), "\n") {
+		b.WriteString(strings.TrimSpace("# "+line) + "\n")
+	}
+}
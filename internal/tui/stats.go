@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// statsHeight is the number of lines the stats panel adds to the layout
+const statsHeight = 4
+
+// requestStats summarizes the loaded requests
+type requestStats struct {
+	Total     int
+	Classes   [6]int // Count per status class, indexed by StatusCode/100
+	Errors    int    // Forwarding failed
+	Pending   int    // No response yet
+	Avg       time.Duration
+	P95       time.Duration
+	PerMinute int // Requests received in the last minute
+}
+
+func computeStats(reqs []RequestItem, now time.Time) requestStats {
+	s := requestStats{Total: len(reqs)}
+	var durations []time.Duration
+	var sum time.Duration
+
+	for _, req := range reqs {
+		switch {
+		case req.Error != "":
+			s.Errors++
+		case req.StatusCode == 0:
+			s.Pending++
+		case req.StatusCode/100 < len(s.Classes):
+			s.Classes[req.StatusCode/100]++
+		}
+		if req.Duration > 0 {
+			durations = append(durations, req.Duration)
+			sum += req.Duration
+		}
+		if now.Sub(req.Timestamp) < time.Minute {
+			s.PerMinute++
+		}
+	}
+
+	if len(durations) > 0 {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		s.Avg = sum / time.Duration(len(durations))
+		// Nearest-rank percentile
+		rank := (len(durations)*95 + 99) / 100
+		s.P95 = durations[rank-1]
+	}
+	return s
+}
+
+func (m Model) renderStats() string {
+	s := computeStats(m.requests, time.Now())
+
+	header := SectionStyle.Render("STATS") + " " + DimStyle.Render(fmt.Sprintf("(last %d)", s.Total))
+	rightSide := DimStyle.Render("[s]hide")
+	headerLine := header + strings.Repeat(" ", max(0, m.width-lipgloss.Width(header)-lipgloss.Width(rightSide)-6)) + rightSide
+
+	label := func(l string) string { return DimStyle.Render(l + " ") }
+
+	counts := label("2xx") + StatusStyle(200).Render(fmt.Sprint(s.Classes[2])) + "  " +
+		label("3xx") + StatusStyle(300).Render(fmt.Sprint(s.Classes[3])) + "  " +
+		label("4xx") + StatusStyle(400).Render(fmt.Sprint(s.Classes[4])) + "  " +
+		label("5xx") + StatusStyle(500).Render(fmt.Sprint(s.Classes[5])) + "  " +
+		label("err") + ErrorStyle.Render(fmt.Sprint(s.Errors)) + "  " +
+		label("pending") + DimStyle.Render(fmt.Sprint(s.Pending))
+
+	timing := label("avg") + formatDuration(s.Avg) + "  " +
+		label("p95") + formatDuration(s.P95) + "  " +
+		label("rate") + fmt.Sprintf("%d/min", s.PerMinute)
+
+	rows := []string{
+		headerLine,
+		DimStyle.Render(strings.Repeat("─", m.width-6)),
+		"  " + counts,
+		"  " + timing,
+	}
+	return ListBoxStyle.Width(m.width - 2).Render(strings.Join(rows, "\n"))
+}
@@ -134,6 +134,23 @@ var (
 	// Emoji/icon style
 	IconStyle = lipgloss.NewStyle().
 			Foreground(Mauve)
+
+	// Status outside expect_status
+	UnexpectedStyle = lipgloss.NewStyle().
+			Foreground(Crust).
+			Background(Maroon).
+			Bold(true)
+
+	// Duplicate body badge
+	DupBadgeStyle = lipgloss.NewStyle().
+			Foreground(Peach).
+			Bold(true)
+
+	// Paused list banner
+	PausedStyle = lipgloss.NewStyle().
+			Foreground(Crust).
+			Background(Yellow).
+			Bold(true)
 )
 
 // MethodStyle returns the style for a given HTTP method
@@ -1,8 +1,10 @@
 package tui
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -11,21 +13,28 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/lance0/hookshot/internal/protocol"
 )
 
-// RequestItem represents a webhook request/response pair
+// RequestItem represents a webhook request/response pair. The JSON form is
+// a line of the history file.
 type RequestItem struct {
-	ID         string
-	Method     string
-	Path       string
-	StatusCode int
-	Duration   time.Duration
-	Timestamp  time.Time
-	ReqHeaders map[string]string
-	ReqBody    []byte
-	ResHeaders map[string]string
-	ResBody    []byte
-	Error      string
+	ID         string        `json:"id"`
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	StatusCode int           `json:"status,omitempty"`
+	StatusText string        `json:"status_text,omitempty"` // Reason phrase the target sent
+	Duration   time.Duration `json:"duration_ns,omitempty"`
+	Timestamp  time.Time     `json:"timestamp"`
+	ReqHeaders http.Header   `json:"request_headers,omitempty"`
+	ReqBody    []byte        `json:"request_body,omitempty"` // base64 in the JSON
+	ResHeaders http.Header   `json:"response_headers,omitempty"`
+	ResBody    []byte        `json:"response_body,omitempty"` // base64 in the JSON
+	Error      string        `json:"error,omitempty"`
+	BodyHash   string        `json:"body_hash,omitempty"`  // Short sha256 of ReqBody (empty if no body)
+	Target     string        `json:"target,omitempty"`     // Local target the request was forwarded to
+	Unexpected bool          `json:"unexpected,omitempty"` // Status fell outside the client's expect_status
+	Verified   bool          `json:"verified,omitempty"`   // Server verified the webhook signature
 }
 
 // ConnectionInfo holds tunnel connection details
@@ -38,6 +47,9 @@ type ConnectionInfo struct {
 	Connected bool
 }
 
+// maxRequests is how many requests the list keeps
+const maxRequests = 100
+
 // Model is the main TUI model
 type Model struct {
 	requests      []RequestItem
@@ -47,11 +59,17 @@ type Model struct {
 	height        int
 	viewport      viewport.Model
 	viewportReady bool
+	detailID      string // Request shown in the viewport, to reset scroll on change
 	connection    ConnectionInfo
 	ready         bool
 	quitting      bool
 	statusMsg     string
 	statusTime    time.Time
+	showStats     bool
+
+	// While paused, new requests wait in queued (oldest first)
+	paused bool
+	queued []RequestItem
 
 	// Filter mode
 	filterMode  bool
@@ -60,6 +78,8 @@ type Model struct {
 	// Channels for communication
 	requestCh chan RequestItem
 	connCh    chan ConnectionInfo
+
+	history *History // Optional: requests are also appended here
 }
 
 // NewModel creates a new TUI model
@@ -73,6 +93,22 @@ func NewModel() Model {
 	}
 }
 
+// UseHistory fills the list with the newest requests in h and appends new
+// requests to it
+func (m *Model) UseHistory(h *History) error {
+	items, err := h.Load(maxRequests)
+	if err != nil {
+		return err
+	}
+	m.requests = items
+	m.history = h
+	if len(items) > 0 {
+		m.statusMsg = fmt.Sprintf("Loaded %d requests from history", len(items))
+		m.statusTime = time.Now()
+	}
+	return nil
+}
+
 // RequestChannel returns the channel for sending requests to the TUI
 func (m *Model) RequestChannel() chan<- RequestItem {
 	return m.requestCh
@@ -120,7 +156,9 @@ func (m Model) tick() tea.Cmd {
 	})
 }
 
-func (m Model) replayRequest(requestID string) tea.Cmd {
+// replayRequest replays a stored request, with body replacing its body
+// when non-nil
+func (m Model) replayRequest(requestID string, body []byte) tea.Cmd {
 	return func() tea.Msg {
 		if m.connection.ServerURL == "" || m.connection.TunnelID == "" {
 			return replayResultMsg{success: false, requestID: requestID, message: "Not connected"}
@@ -129,7 +167,12 @@ func (m Model) replayRequest(requestID string) tea.Cmd {
 		url := fmt.Sprintf("%s/api/tunnels/%s/requests/%s/replay",
 			m.connection.ServerURL, m.connection.TunnelID, requestID)
 
-		req, err := http.NewRequest("POST", url, nil)
+		var edit io.Reader
+		if body != nil {
+			data, _ := json.Marshal(map[string]string{"body": string(body)})
+			edit = bytes.NewReader(data)
+		}
+		req, err := http.NewRequest("POST", url, edit)
 		if err != nil {
 			return replayResultMsg{success: false, requestID: requestID, message: err.Error()}
 		}
@@ -165,12 +208,10 @@ func (m Model) filteredRequests() []RequestItem {
 	if m.filterInput == "" {
 		return m.requests
 	}
-	filter := strings.ToLower(m.filterInput)
+	filter, _ := parseFilter(m.filterInput)
 	var filtered []RequestItem
 	for _, req := range m.requests {
-		if strings.Contains(strings.ToLower(req.Path), filter) ||
-			strings.Contains(strings.ToLower(req.Method), filter) ||
-			strings.Contains(req.ID, filter) {
+		if filter.match(req) {
 			filtered = append(filtered, req)
 		}
 	}
@@ -222,9 +263,34 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selected++
 			}
 
+		case key.Matches(msg, m.keys.PageUp):
+			m.viewport.HalfPageUp()
+
+		case key.Matches(msg, m.keys.PageDn):
+			m.viewport.HalfPageDown()
+
+		case key.Matches(msg, m.keys.LineUp):
+			m.viewport.ScrollUp(1)
+
+		case key.Matches(msg, m.keys.LineDn):
+			m.viewport.ScrollDown(1)
+
 		case key.Matches(msg, m.keys.Filter):
 			m.filterMode = true
 
+		case key.Matches(msg, m.keys.Pause):
+			if m.paused {
+				m.resume()
+			} else {
+				m.paused = true
+			}
+
+		case key.Matches(msg, m.keys.Stats):
+			m.showStats = !m.showStats
+			if m.ready {
+				m.resizeViewport()
+			}
+
 		case key.Matches(msg, m.keys.Clear):
 			m.filterInput = ""
 			m.selected = 0
@@ -235,7 +301,41 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				req := filtered[m.selected]
 				m.statusMsg = fmt.Sprintf("Replaying %s...", req.ID)
 				m.statusTime = time.Now()
-				cmds = append(cmds, m.replayRequest(req.ID))
+				cmds = append(cmds, m.replayRequest(req.ID, nil))
+			}
+
+		case key.Matches(msg, m.keys.Edit):
+			filtered := m.filteredRequests()
+			if len(filtered) > 0 && m.selected < len(filtered) {
+				cmds = append(cmds, editBody(filtered[m.selected]))
+			}
+
+		case key.Matches(msg, m.keys.Copy):
+			filtered := m.filteredRequests()
+			if len(filtered) > 0 && m.selected < len(filtered) {
+				req := filtered[m.selected]
+				if len(req.ReqBody) == 0 {
+					m.statusMsg = DimStyle.Render("Request has no body")
+					m.statusTime = time.Now()
+				} else {
+					cmds = append(cmds, copyToClipboard(string(req.ReqBody), "request body"))
+				}
+			}
+
+		case key.Matches(msg, m.keys.CopyAll):
+			filtered := m.filteredRequests()
+			if len(filtered) > 0 && m.selected < len(filtered) {
+				cmds = append(cmds, copyToClipboard(plainDetail(filtered[m.selected]), "request detail"))
+			}
+
+		case key.Matches(msg, m.keys.SaveHAR), key.Matches(msg, m.keys.SaveRaw):
+			filtered := m.filteredRequests()
+			if len(filtered) > 0 && m.selected < len(filtered) {
+				format := saveHAR
+				if key.Matches(msg, m.keys.SaveRaw) {
+					format = saveHTTP
+				}
+				cmds = append(cmds, saveRequest(filtered[m.selected], format))
 			}
 		}
 
@@ -244,26 +344,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		m.ready = true
 
-		// Update viewport size
-		headerHeight := 6
-		listHeight := min(10, m.height/3)
-		detailHeight := m.height - headerHeight - listHeight - 4
-
-		if !m.viewportReady {
-			m.viewport = viewport.New(m.width-4, detailHeight)
-			m.viewport.YPosition = 0
-			m.viewportReady = true
-		} else {
-			m.viewport.Width = m.width - 4
-			m.viewport.Height = detailHeight
-		}
+		m.resizeViewport()
 
 	case requestMsg:
-		// Prepend new request (newest first)
-		m.requests = append([]RequestItem{RequestItem(msg)}, m.requests...)
-		// Keep max 100 requests
-		if len(m.requests) > 100 {
-			m.requests = m.requests[:100]
+		if m.history != nil {
+			m.history.Append(RequestItem(msg))
+		}
+		if m.paused {
+			m.queued = append(m.queued, RequestItem(msg))
+			// Only the newest would survive the flush anyway
+			if len(m.queued) > maxRequests {
+				m.queued = m.queued[len(m.queued)-maxRequests:]
+			}
+		} else {
+			// Prepend new request (newest first)
+			m.requests = append([]RequestItem{RequestItem(msg)}, m.requests...)
+			// Keep at most maxRequests
+			if len(m.requests) > maxRequests {
+				m.requests = m.requests[:maxRequests]
+			}
 		}
 		cmds = append(cmds, m.waitForRequest())
 
@@ -286,17 +385,72 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.statusMsg = ErrorStyle.Render("✗ ") + msg.message
 		}
 		m.statusTime = time.Now()
+
+	case editDoneMsg:
+		body, changed, err := msg.readEdit()
+		switch {
+		case err != nil:
+			m.statusMsg = ErrorStyle.Render("✗ ") + "Edit failed: " + err.Error()
+		case !changed:
+			m.statusMsg = DimStyle.Render("Body unchanged, not replayed")
+		default:
+			m.statusMsg = fmt.Sprintf("Replaying edited %s...", msg.requestID)
+			cmds = append(cmds, m.replayRequest(msg.requestID, body))
+		}
+		m.statusTime = time.Now()
+
+	case copyResultMsg:
+		if msg.success {
+			m.statusMsg = SuccessStyle.Render("✓ ") + msg.message
+		} else {
+			m.statusMsg = ErrorStyle.Render("✗ ") + msg.message
+		}
+		m.statusTime = time.Now()
+
+	case saveResultMsg:
+		if msg.success {
+			m.statusMsg = SuccessStyle.Render("✓ ") + msg.message
+		} else {
+			m.statusMsg = ErrorStyle.Render("✗ ") + msg.message
+		}
+		m.statusTime = time.Now()
 	}
 
 	// Update viewport content
 	filtered := m.filteredRequests()
 	if len(filtered) > 0 && m.selected < len(filtered) {
-		m.viewport.SetContent(m.renderDetail(filtered[m.selected]))
+		req := filtered[m.selected]
+		m.viewport.SetContent(m.renderDetail(req))
+		if req.ID != m.detailID {
+			m.detailID = req.ID
+			m.viewport.GotoTop()
+		}
 	}
 
 	return m, tea.Batch(cmds...)
 }
 
+// resizeViewport fits the detail viewport into the space left by the other
+// panels
+func (m *Model) resizeViewport() {
+	headerHeight := 6
+	listHeight := min(10, m.height/3)
+	detailHeight := m.height - headerHeight - listHeight - 4
+	if m.showStats {
+		detailHeight -= statsHeight
+	}
+	detailHeight = max(1, detailHeight)
+
+	if !m.viewportReady {
+		m.viewport = viewport.New(m.width-4, detailHeight)
+		m.viewport.YPosition = 0
+		m.viewportReady = true
+	} else {
+		m.viewport.Width = m.width - 4
+		m.viewport.Height = detailHeight
+	}
+}
+
 // View implements tea.Model
 func (m Model) View() string {
 	if m.quitting {
@@ -313,6 +467,12 @@ func (m Model) View() string {
 	b.WriteString(m.renderHeader())
 	b.WriteString("\n")
 
+	// Stats panel
+	if m.showStats {
+		b.WriteString(m.renderStats())
+		b.WriteString("\n")
+	}
+
 	// Request list
 	b.WriteString(m.renderList())
 	b.WriteString("\n")
@@ -375,16 +535,33 @@ func (m Model) renderHeader() string {
 }
 
 func (m Model) renderList() string {
-	header := SectionStyle.Render("REQUESTS")
+	filtered := m.filteredRequests()
+
+	// Counts: total, or matched/total while filtering, plus selected position
+	count := fmt.Sprintf("(%d)", len(m.requests))
+	if m.filterInput != "" {
+		count = fmt.Sprintf("(%d/%d)", len(filtered), len(m.requests))
+	}
+	header := SectionStyle.Render("REQUESTS") + " " + DimStyle.Render(count)
+	if len(filtered) > 0 && m.selected < len(filtered) {
+		header += "  " + DimStyle.Render(fmt.Sprintf("%d/%d", m.selected+1, len(filtered)))
+	}
+	if m.paused {
+		header += "  " + PausedStyle.Render(fmt.Sprintf(" PAUSED (%d queued) ", len(m.queued)))
+	}
 
 	// Show filter or replay hint
 	var rightSide string
+	var filterHint string
+	if _, err := parseFilter(m.filterInput); err != nil {
+		filterHint = "  " + ErrorStyle.Render(err.Error())
+	}
 	if m.filterMode {
-		rightSide = DimStyle.Render("filter: ") + lipgloss.NewStyle().Foreground(Sky).Render(m.filterInput) + lipgloss.NewStyle().Foreground(Sky).Blink(true).Render("▎")
+		rightSide = DimStyle.Render("filter: ") + lipgloss.NewStyle().Foreground(Sky).Render(m.filterInput) + lipgloss.NewStyle().Foreground(Sky).Blink(true).Render("▎") + filterHint
 	} else if m.filterInput != "" {
-		rightSide = DimStyle.Render("filter: ") + lipgloss.NewStyle().Foreground(Sky).Render(m.filterInput) + "  " + DimStyle.Render("[esc]clear")
+		rightSide = DimStyle.Render("filter: ") + lipgloss.NewStyle().Foreground(Sky).Render(m.filterInput) + filterHint + "  " + DimStyle.Render("[esc]clear")
 	} else {
-		rightSide = DimStyle.Render("[r]eplay [/]filter")
+		rightSide = DimStyle.Render("[r]eplay [y]ank [/]filter [s]tats")
 	}
 	headerLine := header + strings.Repeat(" ", max(0, m.width-lipgloss.Width(header)-lipgloss.Width(rightSide)-6)) + rightSide
 
@@ -392,16 +569,16 @@ func (m Model) renderList() string {
 	rows = append(rows, headerLine)
 	rows = append(rows, DimStyle.Render(strings.Repeat("─", m.width-6)))
 
-	filtered := m.filteredRequests()
 	if len(m.requests) == 0 {
 		rows = append(rows, DimStyle.Render("  Waiting for requests..."))
 	} else if len(filtered) == 0 {
 		rows = append(rows, DimStyle.Render("  No matching requests"))
 	} else {
 		// Show up to 8 requests
+		hashCounts := m.bodyHashCounts()
 		maxRows := min(8, len(filtered))
 		for i := 0; i < maxRows; i++ {
-			rows = append(rows, m.renderRequestRow(i, filtered[i]))
+			rows = append(rows, m.renderRequestRow(i, filtered[i], hashCounts[filtered[i].BodyHash]))
 		}
 		if len(filtered) > maxRows {
 			rows = append(rows, DimStyle.Render(fmt.Sprintf("  ... and %d more", len(filtered)-maxRows)))
@@ -412,7 +589,18 @@ func (m Model) renderList() string {
 	return ListBoxStyle.Width(m.width - 2).Render(content)
 }
 
-func (m Model) renderRequestRow(index int, req RequestItem) string {
+// bodyHashCounts counts how many loaded requests share each body hash
+func (m Model) bodyHashCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, req := range m.requests {
+		if req.BodyHash != "" {
+			counts[req.BodyHash]++
+		}
+	}
+	return counts
+}
+
+func (m Model) renderRequestRow(index int, req RequestItem, dupCount int) string {
 	// Selection indicator
 	indicator := "  "
 	if index == m.selected {
@@ -423,7 +611,7 @@ func (m Model) renderRequestRow(index int, req RequestItem) string {
 	method := MethodStyle(req.Method).Width(7).Render(req.Method)
 
 	// Path (truncate if needed)
-	maxPathLen := m.width - 50
+	maxPathLen := m.width - 63
 	path := req.Path
 	if len(path) > maxPathLen {
 		path = path[:maxPathLen-3] + "..."
@@ -431,7 +619,9 @@ func (m Model) renderRequestRow(index int, req RequestItem) string {
 
 	// Status
 	var status string
-	if req.StatusCode > 0 {
+	if req.StatusCode > 0 && req.Unexpected {
+		status = UnexpectedStyle.Width(4).Render(fmt.Sprintf("%d!", req.StatusCode))
+	} else if req.StatusCode > 0 {
 		status = StatusStyle(req.StatusCode).Width(4).Render(fmt.Sprintf("%d", req.StatusCode))
 	} else if req.Error != "" {
 		status = ErrorStyle.Width(4).Render("ERR")
@@ -442,16 +632,25 @@ func (m Model) renderRequestRow(index int, req RequestItem) string {
 	// Duration
 	duration := DimStyle.Width(6).Render(formatDuration(req.Duration))
 
+	// Request and response body sizes
+	sizes := DimStyle.Width(12).Render(formatSizes(req))
+
 	// Relative time
 	relTime := DimStyle.Width(10).Render(relativeTime(req.Timestamp))
 
-	// ID
+	// ID (with duplicate-body badge)
 	id := DimStyle.Render(req.ID)
+	if req.Verified {
+		id += " " + SuccessStyle.Render("✓")
+	}
+	if dupCount > 1 {
+		id += " " + DupBadgeStyle.Render(fmt.Sprintf("×%d", dupCount))
+	}
 
-	row := fmt.Sprintf("%s%s %s %s %s %s %s",
+	row := fmt.Sprintf("%s%s %s %s %s %s %s %s",
 		indicator, method, path,
 		strings.Repeat(" ", max(0, maxPathLen-len(req.Path))),
-		status, duration, relTime+" "+id)
+		status, duration, sizes, relTime+" "+id)
 
 	if index == m.selected {
 		return SelectedStyle.Width(m.width - 6).Render(row)
@@ -467,16 +666,28 @@ func (m Model) renderDetail(req RequestItem) string {
 	b.WriteString(" ")
 	b.WriteString(lipgloss.NewStyle().Foreground(Text).Render(req.Path))
 	b.WriteString("\n")
+	if req.Target != "" {
+		b.WriteString(DimStyle.Render("Forwarded to: "))
+		b.WriteString(lipgloss.NewStyle().Foreground(Green).Render(req.Target))
+		b.WriteString("\n")
+	}
+	if req.Verified {
+		b.WriteString(DimStyle.Render("Signature: "))
+		b.WriteString(SuccessStyle.Render("verified"))
+		b.WriteString("\n")
+	}
 
 	// Request headers
 	if len(req.ReqHeaders) > 0 {
 		b.WriteString(DimStyle.Render(strings.Repeat("─", 40)))
 		b.WriteString("\n")
-		for k, v := range req.ReqHeaders {
+		for k, values := range req.ReqHeaders {
 			if k == "Content-Type" || k == "User-Agent" || k == "X-Request-Id" {
-				b.WriteString(DimStyle.Render(k+": "))
-				b.WriteString(lipgloss.NewStyle().Foreground(Subtext0).Render(v))
-				b.WriteString("\n")
+				for _, v := range values {
+					b.WriteString(DimStyle.Render(k+": "))
+					b.WriteString(lipgloss.NewStyle().Foreground(Subtext0).Render(v))
+					b.WriteString("\n")
+				}
 			}
 		}
 	}
@@ -485,8 +696,12 @@ func (m Model) renderDetail(req RequestItem) string {
 	if len(req.ReqBody) > 0 {
 		b.WriteString(DimStyle.Render(strings.Repeat("─", 40)))
 		b.WriteString("\n")
-		body := truncateBody(req.ReqBody, 500)
-		b.WriteString(lipgloss.NewStyle().Foreground(Text).Render(body))
+		b.WriteString(DimStyle.Render("Body: " + formatBytes(len(req.ReqBody))))
+		if req.BodyHash != "" {
+			b.WriteString(DimStyle.Render("  hash: " + req.BodyHash))
+		}
+		b.WriteString("\n")
+		b.WriteString(m.renderBody(req.ReqBody, req.ReqHeaders, Text))
 		b.WriteString("\n")
 	}
 
@@ -498,13 +713,15 @@ func (m Model) renderDetail(req RequestItem) string {
 		b.WriteString(ErrorStyle.Render("Error: " + req.Error))
 	} else if req.StatusCode > 0 {
 		b.WriteString(DimStyle.Render("Response: "))
-		b.WriteString(StatusStyle(req.StatusCode).Render(fmt.Sprintf("%d", req.StatusCode)))
-		b.WriteString(DimStyle.Render(fmt.Sprintf(" (%s)", formatDuration(req.Duration))))
+		b.WriteString(StatusStyle(req.StatusCode).Render(protocol.StatusLine(req.StatusCode, req.StatusText)))
+		b.WriteString(DimStyle.Render(fmt.Sprintf(" (%s, %s)", formatDuration(req.Duration), formatBytes(len(req.ResBody)))))
+		if req.Unexpected {
+			b.WriteString(" " + UnexpectedStyle.Render("unexpected status"))
+		}
 		b.WriteString("\n")
 
 		if len(req.ResBody) > 0 {
-			body := truncateBody(req.ResBody, 500)
-			b.WriteString(lipgloss.NewStyle().Foreground(Subtext0).Render(body))
+			b.WriteString(m.renderBody(req.ResBody, req.ResHeaders, Subtext0))
 		}
 	} else {
 		b.WriteString(DimStyle.Render("Pending..."))
@@ -517,6 +734,12 @@ func (m Model) renderDetailBox() string {
 	header := SectionStyle.Render("REQUEST DETAIL")
 	headerLine := header
 
+	// Scroll position when the detail doesn't fit
+	if m.viewportReady && m.viewport.TotalLineCount() > m.viewport.Height {
+		pos := DimStyle.Render(fmt.Sprintf("%3.0f%%  [J/K]scroll", m.viewport.ScrollPercent()*100))
+		headerLine = header + strings.Repeat(" ", max(0, m.width-lipgloss.Width(header)-lipgloss.Width(pos)-6)) + pos
+	}
+
 	filtered := m.filteredRequests()
 	var content string
 	if len(filtered) > 0 && m.selected < len(filtered) {
@@ -533,12 +756,45 @@ func (m Model) renderHelp() string {
 		return "  " + m.statusMsg
 	}
 	if m.filterMode {
-		return "  " + DimStyle.Render("Type to filter • Enter to confirm • Esc to cancel")
+		return "  " + DimStyle.Render("Type to filter (method:post status:4xx re:^/hooks/) • Enter to confirm • Esc to cancel")
 	}
-	help := "  " + DimStyle.Render("↑↓ navigate  r replay  / filter  q quit")
+	help := "  " + DimStyle.Render("↑↓ navigate  J/K scroll  r replay  e edit  y/Y copy  w/W save  / filter  s stats  space pause  q quit")
 	return help
 }
 
+// resume leaves paused mode, adding the queued requests to the list while
+// keeping the selected request selected
+func (m *Model) resume() {
+	m.paused = false
+	if len(m.queued) == 0 {
+		return
+	}
+
+	var selectedID string
+	if filtered := m.filteredRequests(); m.selected < len(filtered) {
+		selectedID = filtered[m.selected].ID
+	}
+
+	merged := make([]RequestItem, 0, len(m.queued)+len(m.requests))
+	for i := len(m.queued) - 1; i >= 0; i-- {
+		merged = append(merged, m.queued[i])
+	}
+	merged = append(merged, m.requests...)
+	if len(merged) > maxRequests {
+		merged = merged[:maxRequests]
+	}
+	m.requests = merged
+	m.queued = nil
+
+	m.selected = 0
+	for i, req := range m.filteredRequests() {
+		if req.ID == selectedID {
+			m.selected = i
+			break
+		}
+	}
+}
+
 // Helper functions
 
 func formatDuration(d time.Duration) string {
@@ -554,6 +810,27 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%.1fs", d.Seconds())
 }
 
+// formatBytes formats a body size for display (e.g., 1.2KB)
+func formatBytes(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%dB", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1fKB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	}
+}
+
+// formatSizes shows request→response body sizes, with "-" while pending
+func formatSizes(req RequestItem) string {
+	res := "-"
+	if req.StatusCode > 0 {
+		res = formatBytes(len(req.ResBody))
+	}
+	return formatBytes(len(req.ReqBody)) + "→" + res
+}
+
 func relativeTime(t time.Time) string {
 	if t.IsZero() {
 		return "-"
@@ -571,15 +848,22 @@ func relativeTime(t time.Time) string {
 	return fmt.Sprintf("%dh ago", int(d.Hours()))
 }
 
-func truncateBody(body []byte, maxLen int) string {
-	s := string(body)
-	// Replace newlines for compact display
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	s = strings.ReplaceAll(s, "\r", "")
-	if len(s) > maxLen {
-		return s[:maxLen] + "..."
+// renderBody renders a full body for the detail viewport, wrapped to its
+// width. Binary bodies are summarized; a HAR export (w) keeps them.
+func (m Model) renderBody(body []byte, headers http.Header, fg lipgloss.Color) string {
+	contentType := headers.Get("Content-Type")
+	if !protocol.IsTextBody(body, contentType, headers.Get("Content-Encoding")) {
+		if contentType == "" {
+			contentType = "unknown type"
+		}
+		return DimStyle.Render(fmt.Sprintf("<binary body, %s (%s)>", formatBytes(len(body)), contentType))
+	}
+	s := strings.ReplaceAll(string(body), "\r", "")
+	style := lipgloss.NewStyle().Foreground(fg)
+	if m.viewport.Width > 0 {
+		style = style.Width(m.viewport.Width)
 	}
-	return s
+	return style.Render(s)
 }
 
 func min(a, b int) int {